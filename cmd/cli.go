@@ -92,43 +92,140 @@ func contains(ss []string, s string) bool {
 
 // RunCLI is the entry point for --cli mode.
 func RunCLI(version string) {
-	// Parse CLI-specific flags using a separate FlagSet.
-	fs := flag.NewFlagSet("cli", flag.ExitOnError)
-	argsFlag := fs.String("args", "", "JSON arguments for tool invocation")
-	outputFlag := fs.String("output", "", "Output format: json or text")
-	helpFlag := fs.Bool("help", false, "Show tool parameter help")
-
 	// Find the positional command (first non-flag arg after --cli).
 	// os.Args has been filtered by init() to remove --cli and preceding flags.
 	cliArgs := cliArgsToParse()
 	if len(cliArgs) == 0 {
 		fmt.Fprintln(os.Stderr, "Usage: forgejo-mcp --cli <command> [options]")
-		fmt.Fprintln(os.Stderr, "Commands: list, <tool-name>")
-		fmt.Fprintln(os.Stderr, "Options: --args '{json}', --output=json|text, --help")
+		fmt.Fprintln(os.Stderr, "Commands: list, doctor, resources, read <uri>, run <plan.yaml>, shell, completion bash|zsh|fish [--repos], <tool-name>")
+		fmt.Fprintln(os.Stderr, "Options: --<argument> <value>, --args '{json}', --output=json|text|table|yaml|csv|ndjson, --query <expr>, --columns <a,b>, --help")
 		os.Exit(1)
 	}
 
 	command := cliArgs[0]
-	_ = fs.Parse(cliArgs[1:])
 
 	// Build the MCPServer and register tools with domain tracking.
 	flagPkg.Version = version
 	mcpSrv := server.NewMCPServer("Forgejo MCP Server", version, server.WithLogging())
 	registerToolsWithDomains(mcpSrv)
+	operation.RegisterCoreResources(mcpSrv)
+
+	// Parse CLI-specific flags using a separate FlagSet. Tool commands also
+	// get one flag per argument in the tool's input schema.
+	fs := flag.NewFlagSet("cli", flag.ExitOnError)
+	argsFlag := fs.String("args", "", "JSON arguments for tool invocation")
+	outputFlag := fs.String("output", "", "Output format: json, text, table, yaml, csv or ndjson")
+	queryFlag := fs.String("query", "", "jq-style expression applied to the result")
+	columnsFlag := fs.String("columns", "", "Comma-separated columns for table and csv output")
+	helpFlag := fs.Bool("help", false, "Show tool parameter help")
 
 	switch command {
+	case completeCommand:
+		runComplete(mcpSrv, cliArgs[1:])
+	case "completion":
+		shell, repos := "", false
+		for _, arg := range cliArgs[1:] {
+			if arg == "--repos" {
+				repos = true
+			} else if shell == "" {
+				shell = arg
+			}
+		}
+		if err := cliCompletion(os.Stdout, shell, repos); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "shell":
+		if err := newCLIShell(mcpSrv, os.Stdout).run(os.Stdin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	case "list":
+		_ = fs.Parse(cliArgs[1:])
 		outputMode := *outputFlag
 		if outputMode == "" {
 			outputMode = "text"
 		}
-		if err := cliList(mcpSrv, outputMode); err != nil {
+		if err := cliList(os.Stdout, mcpSrv, outputMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "resources":
+		_ = fs.Parse(cliArgs[1:])
+		outputMode := *outputFlag
+		if outputMode == "" {
+			outputMode = "text"
+		}
+		if err := cliResources(os.Stdout, mcpSrv, outputMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "read":
+		uri, ok := parseWithOperand(fs, cliArgs[1:])
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Usage: forgejo-mcp --cli read <uri> [--output=...] [--query <expr>]")
+			os.Exit(2)
+		}
+		outputMode := *outputFlag
+		if outputMode == "" {
+			outputMode = "json"
+		}
+		output, err := newCLIOutput(outputMode, *queryFlag, *columnsFlag)
+		if err == nil {
+			err = cliRead(os.Stdout, mcpSrv, uri, output)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "doctor":
+		_ = fs.Parse(cliArgs[1:])
+		outputMode := *outputFlag
+		if outputMode == "" {
+			outputMode = "text"
+		}
+		var report doctorReport
+		if doctorConfig(&report) {
+			initConfig()
+			runDoctor(context.Background(), &report)
+		}
+		if err := writeDoctorReport(os.Stdout, report, outputMode); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if report.failed() {
+			os.Exit(1)
+		}
+	case "run":
+		dryRun := fs.Bool("dry-run", false, "Print the resolved steps without calling any tool")
+		vars := planVars{}
+		fs.Var(vars, "var", "Set a plan variable (name=value, repeatable)")
+		path, ok := parseWithOperand(fs, cliArgs[1:])
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Usage: forgejo-mcp --cli run <plan.yaml|-> [--dry-run] [--var name=value ...]")
+			os.Exit(2)
+		}
+		if err := cliRun(mcpSrv, os.Stdout, path, vars, *dryRun); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	default:
+		st := mcpSrv.GetTool(command)
+		if st == nil {
+			fmt.Fprintf(os.Stderr, "Error: unknown tool: %s\nRun 'forgejo-mcp --cli list' to see available tools\n", command)
+			os.Exit(1)
+		}
+		flagValues := map[string]any{}
+		defineToolFlags(fs, st.Tool, flagValues)
+		fs.Usage = func() { _ = cliHelp(os.Stdout, mcpSrv, command) }
+		_ = fs.Parse(cliArgs[1:])
+		if fs.NArg() > 0 {
+			fmt.Fprintf(os.Stderr, "Error: unexpected argument %q\n", fs.Arg(0))
+			os.Exit(2)
+		}
+
 		if *helpFlag {
-			if err := cliHelp(mcpSrv, command); err != nil {
+			if err := cliHelp(os.Stdout, mcpSrv, command); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
@@ -139,20 +236,47 @@ func RunCLI(version string) {
 		if outputMode == "" {
 			outputMode = "json"
 		}
+		output, err := newCLIOutput(outputMode, *queryFlag, *columnsFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(2)
+		}
 
 		argsJSON, err := resolveArgs(*argsFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading arguments: %v\n", err)
 			os.Exit(1)
 		}
+		args, err := mergeArgs(argsJSON, flagValues)
+		if err == nil {
+			err = checkRequired(st.Tool, args)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
-		if err := cliExec(mcpSrv, command, argsJSON, outputMode); err != nil {
+		if err := cliExec(mcpSrv, command, args, output); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	}
 }
 
+// parseWithOperand parses args that hold exactly one operand, such as a URI
+// or file name, before or after the flags.
+func parseWithOperand(fs *flag.FlagSet, args []string) (string, bool) {
+	operand := ""
+	if len(args) > 0 && (args[0] == "-" || !strings.HasPrefix(args[0], "-")) {
+		operand, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if operand == "" && fs.NArg() == 1 {
+		return fs.Arg(0), true
+	}
+	return operand, operand != "" && fs.NArg() == 0
+}
+
 // cliArgsToParse extracts the args after --cli from os.Args.
 func cliArgsToParse() []string {
 	for i, arg := range os.Args {
@@ -188,11 +312,11 @@ func resolveArgs(argsFlag string) (string, error) {
 	return "{}", nil
 }
 
-// cliList prints all registered tools.
-func cliList(s *server.MCPServer, outputMode string) error {
+// cliList prints all registered tools to w.
+func cliList(w io.Writer, s *server.MCPServer, outputMode string) error {
 	tools := s.ListTools()
 	if tools == nil {
-		fmt.Println("No tools registered.")
+		fmt.Fprintln(w, "No tools registered.")
 		return nil
 	}
 
@@ -223,7 +347,7 @@ func cliList(s *server.MCPServer, outputMode string) error {
 	})
 
 	if outputMode == "json" {
-		enc := json.NewEncoder(os.Stdout)
+		enc := json.NewEncoder(w)
 		enc.SetIndent("", "  ")
 		return enc.Encode(infos)
 	}
@@ -239,86 +363,65 @@ func cliList(s *server.MCPServer, outputMode string) error {
 	}
 
 	for _, domain := range domainOrder {
-		fmt.Printf("\n%s:\n", strings.ToUpper(domain))
+		fmt.Fprintf(w, "\n%s:\n", strings.ToUpper(domain))
 		for _, info := range grouped[domain] {
-			fmt.Printf("  %-40s %s\n", info.Name, info.Description)
+			fmt.Fprintf(w, "  %-40s %s\n", info.Name, info.Description)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	return nil
 }
 
-// cliHelp prints the parameter schema for a tool.
-func cliHelp(s *server.MCPServer, toolName string) error {
+// cliHelp prints the parameter schema for a tool to w.
+func cliHelp(w io.Writer, s *server.MCPServer, toolName string) error {
 	st := s.GetTool(toolName)
 	if st == nil {
 		return fmt.Errorf("unknown tool: %s", toolName)
 	}
 
-	fmt.Printf("Tool: %s\n", st.Tool.Name)
+	fmt.Fprintf(w, "Tool: %s\n", st.Tool.Name)
 	if st.Tool.Description != "" {
-		fmt.Printf("Description: %s\n", st.Tool.Description)
+		fmt.Fprintf(w, "Description: %s\n", st.Tool.Description)
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	props := st.Tool.InputSchema.Properties
-	required := st.Tool.InputSchema.Required
-
 	if len(props) == 0 {
-		fmt.Println("No parameters.")
+		fmt.Fprintln(w, "No parameters.")
 		return nil
 	}
 
-	fmt.Println("Parameters:")
-	// Sort parameter names for consistent output.
-	names := make([]string, 0, len(props))
-	for name := range props {
-		names = append(names, name)
-	}
-	sort.Strings(names)
-
-	requiredSet := map[string]bool{}
-	for _, r := range required {
-		requiredSet[r] = true
-	}
-
-	for _, name := range names {
-		prop := props[name]
+	fmt.Fprintf(w, "Usage: forgejo-mcp --cli %s [flags] [--args '{json}']\n\n", st.Tool.Name)
+	fmt.Fprintln(w, "Flags:")
+	for _, p := range toolParams(st.Tool) {
 		reqStr := "optional"
-		if requiredSet[name] {
+		if p.Required {
 			reqStr = "required"
 		}
-
-		// Property is stored as map[string]any.
-		propMap, ok := prop.(map[string]any)
-		if !ok {
-			fmt.Printf("  %-20s (%s)\n", name, reqStr)
-			continue
+		name := "--" + p.FlagName()
+		if placeholder := flagPlaceholder(p); placeholder != "" {
+			name += " " + placeholder
 		}
-
-		typStr, _ := propMap["type"].(string)
-		desc, _ := propMap["description"].(string)
-
-		fmt.Printf("  %-20s %-10s %-10s %s\n", name, typStr, reqStr, desc)
+		if reservedCLIFlags[p.Name] {
+			name = p.Name + " (via --args)"
+		}
+		fmt.Fprintf(w, "  %-32s %-10s %s\n", name, reqStr, p.Description)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "JSON from --args or stdin is merged with the flags; flags win.")
+	fmt.Fprintln(w, "--output=table|yaml|csv|ndjson and --query <expr> format the unwrapped result.")
 
 	return nil
 }
 
 // cliExec invokes a tool handler and prints the result.
-func cliExec(s *server.MCPServer, toolName, argsJSON, outputMode string) error {
+func cliExec(s *server.MCPServer, toolName string, args map[string]any, output cliOutput) error {
 	st := s.GetTool(toolName)
 	if st == nil {
 		return fmt.Errorf("unknown tool: %s\nRun 'forgejo-mcp --cli list' to see available tools", toolName)
 	}
 
-	// Parse JSON arguments.
-	var args map[string]any
-	if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
-		return fmt.Errorf("invalid JSON arguments: %w", err)
-	}
-
 	// Construct CallToolRequest.
 	req := mcp.CallToolRequest{
 		Params: mcp.CallToolParams{
@@ -335,7 +438,7 @@ func cliExec(s *server.MCPServer, toolName, argsJSON, outputMode string) error {
 
 	// Check IsError flag.
 	if result.IsError {
-		if outputMode == "json" {
+		if output.Format == "json" {
 			enc := json.NewEncoder(os.Stderr)
 			enc.SetIndent("", "  ")
 			_ = enc.Encode(result.Content)
@@ -350,7 +453,10 @@ func cliExec(s *server.MCPServer, toolName, argsJSON, outputMode string) error {
 	}
 
 	// Output result.
-	if outputMode == "json" {
+	if !output.raw() {
+		return output.write(os.Stdout, resultValue(result))
+	}
+	if output.Format == "json" {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		return enc.Encode(result.Content)
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// reservedCLIFlags are the flags every tool command accepts; a tool argument
+// with one of these names can only be passed through --args.
+var reservedCLIFlags = map[string]bool{
+	"args": true, "output": true, "query": true, "columns": true, "help": true,
+}
+
+// toolParam is one input-schema property exposed as a command-line flag.
+type toolParam struct {
+	Name        string
+	Type        string
+	ItemType    string
+	Description string
+	Required    bool
+	Enum        []string
+}
+
+// FlagName is the flag spelling shown in help; snake_case arguments are
+// also accepted in their kebab-case form.
+func (p toolParam) FlagName() string {
+	return strings.ReplaceAll(p.Name, "_", "-")
+}
+
+// toolParams lists a tool's input-schema properties sorted by name.
+func toolParams(tool mcp.Tool) []toolParam {
+	params := make([]toolParam, 0, len(tool.InputSchema.Properties))
+	for name, raw := range tool.InputSchema.Properties {
+		p := toolParam{Name: name, Required: slices.Contains(tool.InputSchema.Required, name)}
+		if prop, ok := raw.(map[string]any); ok {
+			p.Type, _ = prop["type"].(string)
+			p.Description, _ = prop["description"].(string)
+			if items, ok := prop["items"].(map[string]any); ok {
+				p.ItemType, _ = items["type"].(string)
+			}
+			switch enum := prop["enum"].(type) {
+			case []string:
+				p.Enum = enum
+			case []any:
+				for _, v := range enum {
+					p.Enum = append(p.Enum, fmt.Sprint(v))
+				}
+			}
+		}
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool { return params[i].Name < params[j].Name })
+	return params
+}
+
+// coerce converts a flag value to the JSON type the schema declares.
+func coerce(typ, value string) (any, error) {
+	switch typ {
+	case "number", "integer":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		if typ == "integer" && n != float64(int64(n)) {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", value)
+		}
+		return b, nil
+	case "object":
+		var v map[string]any
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return nil, fmt.Errorf("invalid JSON object: %w", err)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// defineToolFlags adds one flag per tool argument to fs. Parsed values are
+// type-coerced and stored in values under the argument's schema name.
+func defineToolFlags(fs *flag.FlagSet, tool mcp.Tool, values map[string]any) {
+	for _, p := range toolParams(tool) {
+		if reservedCLIFlags[p.Name] {
+			continue
+		}
+		checkEnum := func(value string) error {
+			if len(p.Enum) > 0 && !slices.Contains(p.Enum, value) {
+				return fmt.Errorf("must be one of %s", strings.Join(p.Enum, ", "))
+			}
+			return nil
+		}
+		set := func(value string) error {
+			if p.Type == "array" {
+				// Repeat the flag or comma-separate to pass several items.
+				items, _ := values[p.Name].([]any)
+				for _, item := range strings.Split(value, ",") {
+					item = strings.TrimSpace(item)
+					if err := checkEnum(item); err != nil {
+						return err
+					}
+					v, err := coerce(p.ItemType, item)
+					if err != nil {
+						return err
+					}
+					items = append(items, v)
+				}
+				values[p.Name] = items
+				return nil
+			}
+			if err := checkEnum(value); err != nil {
+				return err
+			}
+			v, err := coerce(p.Type, value)
+			if err != nil {
+				return err
+			}
+			values[p.Name] = v
+			return nil
+		}
+		names := []string{p.Name}
+		if alias := p.FlagName(); alias != p.Name {
+			names = append(names, alias)
+		}
+		for _, name := range names {
+			if p.Type == "boolean" {
+				fs.BoolFunc(name, p.Description, set)
+			} else {
+				fs.Func(name, p.Description, set)
+			}
+		}
+	}
+}
+
+// mergeArgs overlays flag values on the JSON arguments; flags win.
+func mergeArgs(argsJSON string, flagValues map[string]any) (map[string]any, error) {
+	args := map[string]any{}
+	if strings.TrimSpace(argsJSON) != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, fmt.Errorf("invalid JSON arguments: %w", err)
+		}
+		if args == nil {
+			args = map[string]any{}
+		}
+	}
+	for name, v := range flagValues {
+		args[name] = v
+	}
+	return args, nil
+}
+
+// checkRequired reports the required arguments args lacks, as flags.
+func checkRequired(tool mcp.Tool, args map[string]any) error {
+	var missing []string
+	for _, p := range toolParams(tool) {
+		if _, ok := args[p.Name]; p.Required && !ok {
+			missing = append(missing, "--"+p.FlagName())
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required arguments for %s: %s", tool.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// flagPlaceholder is the value hint shown after a flag in help output.
+func flagPlaceholder(p toolParam) string {
+	switch {
+	case p.Type == "boolean":
+		return ""
+	case len(p.Enum) > 0:
+		return "<" + strings.Join(p.Enum, "|") + ">"
+	case p.Type == "array":
+		item := p.ItemType
+		if item == "" {
+			item = "value"
+		}
+		return "<" + item + ",...>"
+	case p.Type == "object":
+		return "<json>"
+	case p.Type == "":
+		return "<value>"
+	default:
+		return "<" + p.Type + ">"
+	}
+}
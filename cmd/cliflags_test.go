@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var flagTestTool = mcp.NewTool("list_things",
+	mcp.WithString("owner", mcp.Required(), mcp.Description("Repository owner")),
+	mcp.WithString("repo", mcp.Required()),
+	mcp.WithString("state", mcp.Enum("open", "closed")),
+	mcp.WithNumber("page"),
+	mcp.WithBoolean("include_org_labels"),
+	mcp.WithArray("ids", mcp.WithNumberItems()),
+	mcp.WithString("output"),
+)
+
+func parseToolFlags(t *testing.T, args ...string) (map[string]any, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := map[string]any{}
+	defineToolFlags(fs, flagTestTool, values)
+	return values, fs.Parse(args)
+}
+
+func TestDefineToolFlags_CoercesTypes(t *testing.T) {
+	got, err := parseToolFlags(t,
+		"--owner", "goern", "--page", "2", "--include-org-labels",
+		"--ids", "1,2", "--ids=3", "--state", "closed",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"owner":              "goern",
+		"page":               2.0,
+		"include_org_labels": true,
+		"ids":                []any{1.0, 2.0, 3.0},
+		"state":              "closed",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("values = %#v, want %#v", got, want)
+	}
+}
+
+func TestDefineToolFlags_RejectsBadValues(t *testing.T) {
+	for _, args := range [][]string{
+		{"--page", "two"},
+		{"--state", "merged"},
+		{"--include_org_labels=maybe"},
+		{"--output", "x"}, // reserved for the CLI itself
+	} {
+		if _, err := parseToolFlags(t, args...); err == nil {
+			t.Errorf("%v: expected a parse error", args)
+		}
+	}
+}
+
+func TestMergeArgsAndCheckRequired(t *testing.T) {
+	args, err := mergeArgs(`{"owner":"a","repo":"b","page":1}`, map[string]any{"page": 3.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args["page"] != 3.0 || args["repo"] != "b" {
+		t.Fatalf("flags should override JSON: %v", args)
+	}
+	if err := checkRequired(flagTestTool, args); err != nil {
+		t.Fatal(err)
+	}
+
+	err = checkRequired(flagTestTool, map[string]any{"owner": "a"})
+	if err == nil || !strings.Contains(err.Error(), "--repo") {
+		t.Fatalf("err = %v, want missing --repo", err)
+	}
+	if _, err := mergeArgs("{not json", nil); err == nil {
+		t.Fatal("invalid JSON accepted")
+	}
+}
+
+func TestDefineToolFlags_PassesServerDefinedValues(t *testing.T) {
+	// Completion suggests merge styles, but the server decides which it
+	// accepts; the CLI must not reject one it has no list entry for.
+	tool := completionServer().GetTool("merge_pull_request").Tool
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := map[string]any{}
+	defineToolFlags(fs, tool, values)
+	if err := fs.Parse([]string{"--style", "fast-forward-only"}); err != nil || values["style"] != "fast-forward-only" {
+		t.Fatalf("values = %v, err = %v", values, err)
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"strings"
 
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/cassette"
 	flagPkg "git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
 )
 
@@ -20,8 +24,15 @@ var (
 	httpPort  int
 	token     string
 	userAgent string
+	sudoAllow string
+	recordDir string
+	replayDir string
+	listen    listenSpecs
 
-	debug bool
+	transportSet bool
+	detectRepo   bool
+	probeWrites  bool
+	debug        bool
 )
 
 // isVersionRequest returns true for both the "version" subcommand and the
@@ -44,13 +55,18 @@ func initFlags() {
 		&transport,
 		"t",
 		"stdio",
-		"Transport type (stdio, sse, or http)",
+		"Transport type (stdio, sse, or http; comma-separate to combine, e.g. stdio,http)",
 	)
 	fs.StringVar(
 		&transport,
 		"transport",
 		"stdio",
-		"Transport type (stdio, sse, or http)",
+		"Transport type (stdio, sse, or http; comma-separate to combine, e.g. stdio,http)",
+	)
+	fs.Var(
+		&listen,
+		"listen",
+		"Extra MCP listener, repeatable: http://host:port, sse://host:port or http+unix:///path.sock, with optional ?auth=optional|required|none&mode=0660",
 	)
 	fs.StringVar(
 		&urlFlag,
@@ -82,6 +98,36 @@ func initFlags() {
 		"",
 		"User agent for HTTP requests (default: forgejo-mcp/<version>)",
 	)
+	fs.StringVar(
+		&sudoAllow,
+		"sudo-allowlist",
+		"",
+		"Comma-separated logins an admin token may impersonate via act_as (\"*\" for any; empty disables)",
+	)
+	fs.StringVar(
+		&recordDir,
+		"record",
+		"",
+		"Record every upstream Forgejo request/response (tokens redacted) into this directory",
+	)
+	fs.StringVar(
+		&replayDir,
+		"replay",
+		"",
+		"Serve upstream Forgejo responses from a cassette recorded with --record instead of the network",
+	)
+	fs.BoolVar(
+		&detectRepo,
+		"detect-repo",
+		false,
+		"Default owner/repo to the git checkout in the working directory when its remote is on --url",
+	)
+	fs.BoolVar(
+		&probeWrites,
+		"probe-write-scopes",
+		false,
+		"Detect write token scopes with write requests to an owner that cannot exist, so tools the token cannot use are hidden",
+	)
 	fs.BoolVar(
 		&debug,
 		"d",
@@ -97,6 +143,11 @@ func initFlags() {
 
 	// ExitOnError: Parse exits the process on error, so the return is moot.
 	_ = fs.Parse(os.Args[1:])
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "t" || f.Name == "transport" {
+			transportSet = true
+		}
+	})
 
 	flagPkg.URL = urlFlag
 	flagPkg.UserAgent = userAgent
@@ -169,6 +220,33 @@ func initConfig() {
 		}
 	}
 
+	if sudoAllow == "" {
+		sudoAllow = os.Getenv("FORGEJO_MCP_SUDO_ALLOWLIST")
+	}
+	flagPkg.SudoAllowlist = splitList(sudoAllow)
+
+	flagPkg.RecordDir = recordDir
+	if flagPkg.RecordDir == "" {
+		flagPkg.RecordDir = os.Getenv("FORGEJO_MCP_RECORD_DIR")
+	}
+	flagPkg.ReplayDir = replayDir
+	if flagPkg.ReplayDir == "" {
+		flagPkg.ReplayDir = os.Getenv("FORGEJO_MCP_REPLAY_DIR")
+	}
+
+	flagPkg.DetectRepo = detectRepo || os.Getenv("FORGEJO_MCP_DETECT_REPO") == "true"
+	flagPkg.ProbeWriteScopes = probeWrites || os.Getenv("FORGEJO_MCP_PROBE_WRITE_SCOPES") == "true"
+
+	flagPkg.Listeners = listen
+	if len(flagPkg.Listeners) == 0 {
+		flagPkg.Listeners = splitList(os.Getenv("FORGEJO_MCP_LISTEN"))
+	}
+	// Listeners alone replace the stdio default: a sidecar has no stdin, and
+	// its EOF would stop the whole server.
+	if len(flagPkg.Listeners) > 0 && !transportSet {
+		transport = ""
+	}
+
 	if debug {
 		flagPkg.Debug = debug
 		log.Debug("Debug mode enabled via flag")
@@ -192,6 +270,51 @@ func initConfig() {
 	}
 }
 
+// splitList splits a comma-separated list, dropping blanks.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// listenSpecs collects repeated --listen flags.
+type listenSpecs []string
+
+func (l *listenSpecs) String() string { return strings.Join(*l, ",") }
+
+func (l *listenSpecs) Set(spec string) error {
+	*l = append(*l, spec)
+	return nil
+}
+
+// configureCassette installs a recording or replaying transport when
+// --record or --replay is set. Replay needs no token, so it runs fully offline.
+func configureCassette() error {
+	switch {
+	case flagPkg.RecordDir != "" && flagPkg.ReplayDir != "":
+		return errors.New("--record and --replay are mutually exclusive")
+	case flagPkg.RecordDir != "":
+		recorder, err := cassette.NewRecorder(flagPkg.RecordDir, http.DefaultTransport, flagPkg.Token)
+		if err != nil {
+			return err
+		}
+		forgejo.SetTransport(recorder)
+		log.Info("Recording upstream traffic", log.StringField("dir", flagPkg.RecordDir))
+	case flagPkg.ReplayDir != "":
+		replayer, err := cassette.NewReplayer(flagPkg.ReplayDir)
+		if err != nil {
+			return err
+		}
+		forgejo.SetTransport(replayer)
+		log.Info("Replaying upstream traffic", log.StringField("dir", flagPkg.ReplayDir))
+	}
+	return nil
+}
+
 func validateURL(urlStr string) error {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -219,7 +342,9 @@ func Execute(version string) {
 	// has its own args (tool name, --args, --output) that would confuse it.
 	cliMode = hasCLIFlag()
 	if cliMode {
-		initConfig()
+		if cliNeedsConfig() {
+			initConfig()
+		}
 	} else {
 		initFlags()
 	}
@@ -232,6 +357,10 @@ func Execute(version string) {
 		)
 	}
 
+	if err := configureCassette(); err != nil {
+		log.Fatal("Invalid cassette configuration", log.ErrorField(err))
+	}
+
 	// Sync flushes buffered logs at exit; its error (e.g. syncing stderr) is
 	// not actionable here.
 	defer func() { _ = log.Default().Sync() }()
@@ -246,6 +375,7 @@ func Execute(version string) {
 		log.SanitizedURLField("url", flagPkg.URL),
 		log.StringField("transport", transport),
 		log.IntField("sse-port", flagPkg.SSEPort),
+		log.StringField("listen", strings.Join(flagPkg.Listeners, ",")),
 		log.BoolField("debug", flagPkg.Debug),
 		log.BoolField("token_configured", flagPkg.Token != ""),
 		log.StringField("user_agent", flagPkg.UserAgent),
@@ -0,0 +1,387 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// cliCommands are the --cli commands that are not tool names.
+var cliCommands = map[string]string{
+	"list":       "List available tools",
+	"doctor":     "Check connectivity, authentication and configuration",
+	"resources":  "List resources and resource templates",
+	"read":       "Read a resource URI",
+	"run":        "Run the steps of a plan file",
+	"shell":      "Start an interactive shell",
+	"completion": "Print a shell completion script (bash, zsh or fish)",
+}
+
+// suggestedValues are completion candidates for free-form tool parameters,
+// by tool and parameter name. Unlike schema enums they are not enforced:
+// the server decides what it accepts, and a value missing here still
+// passes through to it.
+var suggestedValues = map[string]map[string][]string{
+	"list_repo_issues": {
+		"state": {"open", "closed", "all"},
+		"type":  {"issues", "pulls"},
+		"sort":  {"relevance", "latest", "oldest", "recentupdate", "leastupdate", "mostcomment", "leastcomment", "nearduedate", "farduedate"},
+	},
+	"search_issues": {
+		"state": {"open", "closed", "all"},
+		"type":  {"issues", "pulls"},
+	},
+	"issue_state_change":   {"state": {"open", "closed"}},
+	"list_repo_milestones": {"state": {"open", "closed", "all"}},
+	"list_repo_pull_requests": {
+		"state": {"open", "closed", "all"},
+		"sort":  {"oldest", "recentupdate", "leastupdate", "mostcomment", "leastcomment", "priority"},
+	},
+	"merge_pull_request": {"style": {"merge", "rebase", "rebase-merge", "squash", "fast-forward-only"}},
+}
+
+// completeCommand is the hidden command the completion scripts call back
+// into; it prints one candidate per line for the words typed so far.
+const completeCommand = "__complete"
+
+// cliNeedsConfig reports whether the --cli command talks to Forgejo.
+// Completion must work before FORGEJO_URL is configured, and doctor
+// reports a missing URL instead of exiting on it.
+func cliNeedsConfig() bool {
+	args := cliArgsToParse()
+	return len(args) == 0 || (args[0] != "completion" && args[0] != completeCommand && args[0] != "doctor")
+}
+
+// candidate is one completion suggestion.
+type candidate struct {
+	Value       string
+	Description string
+}
+
+// completeWords returns the candidates for the last of words, the arguments
+// typed after --cli. The last word is the one being completed and may be
+// empty. repos, when non-nil, lists "owner/name" for --owner and --repo.
+func completeWords(s *server.MCPServer, words []string, repos func() []string) []candidate {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+
+	if len(prev) == 0 {
+		var out []candidate
+		for name, desc := range cliCommands {
+			out = append(out, candidate{name, desc})
+		}
+		for name, st := range s.ListTools() {
+			out = append(out, candidate{name, summarize(st.Tool.Description)})
+		}
+		return filterPrefix(out, cur)
+	}
+
+	command := prev[0]
+	var params []toolParam
+	switch command {
+	case "completion":
+		if strings.HasPrefix(cur, "-") {
+			return filterPrefix([]candidate{{"--repos", "Complete --owner/--repo from list_my_repos"}}, cur)
+		}
+		if len(prev) == 1 {
+			return filterPrefix([]candidate{{"bash", ""}, {"zsh", ""}, {"fish", ""}}, cur)
+		}
+		return nil
+	case "list":
+	default:
+		st := s.GetTool(command)
+		if st == nil {
+			return nil
+		}
+		params = toolParams(st.Tool)
+	}
+
+	values := func(flagName, prefix string) []candidate {
+		return filterPrefix(flagValues(command, flagName, params, prev, repos), prefix)
+	}
+
+	// --flag=value in one word (zsh and fish keep it together).
+	if name, value, ok := strings.Cut(cur, "="); ok && strings.HasPrefix(name, "--") {
+		var out []candidate
+		for _, c := range values(strings.TrimPrefix(name, "--"), value) {
+			out = append(out, candidate{name + "=" + c.Value, c.Description})
+		}
+		return out
+	}
+	if strings.HasPrefix(cur, "-") {
+		out := []candidate{
+			{"--args", "JSON arguments for tool invocation"},
+			{"--output", "Output format"},
+			{"--query", "jq-style expression applied to the result"},
+			{"--columns", "Columns for table and csv output"},
+			{"--help", "Show tool parameter help"},
+		}
+		for _, p := range params {
+			if !reservedCLIFlags[p.Name] {
+				out = append(out, candidate{"--" + p.FlagName(), summarize(p.Description)})
+			}
+		}
+		return filterPrefix(out, cur)
+	}
+
+	// Value for the preceding flag. Bash splits "--flag=value" into
+	// "--flag", "=", "value".
+	last := prev[len(prev)-1]
+	if last == "=" && len(prev) >= 2 {
+		last = prev[len(prev)-2]
+	}
+	if strings.HasPrefix(last, "--") && !strings.Contains(last, "=") {
+		return values(strings.TrimPrefix(last, "--"), cur)
+	}
+	return nil
+}
+
+// flagValues lists the known values of a flag of command: output formats,
+// schema enums or suggested values and, when enabled, the caller's
+// repositories.
+func flagValues(command, flagName string, params []toolParam, prev []string, repos func() []string) []candidate {
+	if flagName == "output" {
+		var out []candidate
+		for _, format := range outputFormats {
+			out = append(out, candidate{format, ""})
+		}
+		return out
+	}
+	var param *toolParam
+	for i := range params {
+		if params[i].Name == flagName || params[i].FlagName() == flagName {
+			param = &params[i]
+		}
+	}
+	if param == nil {
+		return nil
+	}
+	var out []candidate
+	known := param.Enum
+	if len(known) == 0 {
+		known = suggestedValues[command][param.Name]
+	}
+	for _, v := range known {
+		out = append(out, candidate{v, ""})
+	}
+	if len(out) > 0 || repos == nil || (param.Name != "owner" && param.Name != "repo") {
+		return out
+	}
+
+	owner := typedFlag(prev, "owner")
+	seen := map[string]bool{}
+	for _, full := range repos() {
+		o, name, ok := strings.Cut(full, "/")
+		if !ok {
+			continue
+		}
+		value := o
+		if param.Name == "repo" {
+			if owner != "" && !strings.EqualFold(owner, o) {
+				continue
+			}
+			value = name
+		}
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, candidate{value, ""})
+		}
+	}
+	return out
+}
+
+// typedFlag returns the value already given for --name in words, if any.
+func typedFlag(words []string, name string) string {
+	for i, w := range words {
+		if v, ok := strings.CutPrefix(w, "--"+name+"="); ok {
+			return v
+		}
+		if w == "--"+name && i+1 < len(words) {
+			if words[i+1] == "=" && i+2 < len(words) {
+				return words[i+2]
+			}
+			return words[i+1]
+		}
+	}
+	return ""
+}
+
+func filterPrefix(cands []candidate, prefix string) []candidate {
+	var out []candidate
+	for _, c := range cands {
+		if strings.HasPrefix(c.Value, prefix) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Value < out[j].Value })
+	return out
+}
+
+// summarize shortens a description to its first line for completion menus.
+func summarize(desc string) string {
+	desc, _, _ = strings.Cut(desc, "\n")
+	if len(desc) > 80 {
+		desc = desc[:77] + "..."
+	}
+	return desc
+}
+
+// writeCandidates prints candidates in the format the shell's script reads.
+func writeCandidates(w io.Writer, shell string, cands []candidate) {
+	for _, c := range cands {
+		switch {
+		case shell == "fish" && c.Description != "":
+			fmt.Fprintf(w, "%s\t%s\n", c.Value, c.Description)
+		case shell == "zsh":
+			value := strings.ReplaceAll(c.Value, ":", `\:`)
+			if c.Description != "" {
+				fmt.Fprintf(w, "%s:%s\n", value, c.Description)
+			} else {
+				fmt.Fprintln(w, value)
+			}
+		default:
+			fmt.Fprintln(w, c.Value)
+		}
+	}
+}
+
+// myRepos lists the caller's repositories as "owner/name" through the
+// list_my_repos tool, or nothing if the call fails.
+func myRepos(s *server.MCPServer) []string {
+	st := s.GetTool("list_my_repos")
+	if st == nil {
+		return nil
+	}
+	result, err := st.Handler(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: "list_my_repos", Arguments: map[string]any{"page": 1.0, "limit": 100.0}},
+	})
+	if err != nil || result == nil || result.IsError || len(result.Content) == 0 {
+		return nil
+	}
+	text, ok := result.Content[0].(mcp.TextContent)
+	if !ok {
+		return nil
+	}
+	var repos struct {
+		Result []struct {
+			FullName string `json:"full_name"`
+		}
+	}
+	if err := json.Unmarshal([]byte(text.Text), &repos); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(repos.Result))
+	for _, r := range repos.Result {
+		names = append(names, r.FullName)
+	}
+	return names
+}
+
+// runComplete implements `--cli __complete <shell> [--repos] -- <words...>`.
+func runComplete(s *server.MCPServer, args []string) {
+	if len(args) == 0 {
+		return
+	}
+	shell, args := args[0], args[1:]
+	var repos func() []string
+	for len(args) > 0 && args[0] != "--" {
+		if args[0] == "--repos" {
+			repos = func() []string {
+				// Completion runs without configuration; only look up
+				// repositories when Forgejo is configured, never block on it.
+				if os.Getenv("FORGEJO_URL") == "" && os.Getenv("GITEA_HOST") == "" {
+					return nil
+				}
+				initConfig()
+				return myRepos(s)
+			}
+		}
+		args = args[1:]
+	}
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	writeCandidates(os.Stdout, shell, completeWords(s, args, repos))
+}
+
+// cliCompletion prints the completion script for shell. The scripts call
+// back into the binary, so they always match the installed tool set.
+func cliCompletion(w io.Writer, shell string, repos bool) error {
+	extra := ""
+	if repos {
+		extra = " --repos"
+	}
+	switch shell {
+	case "bash":
+		fmt.Fprintf(w, bashCompletion, extra)
+	case "zsh":
+		fmt.Fprintf(w, zshCompletion, extra)
+	case "fish":
+		fmt.Fprintf(w, fishCompletion, extra)
+	default:
+		return fmt.Errorf("unsupported shell %q: use bash, zsh or fish", shell)
+	}
+	return nil
+}
+
+const bashCompletion = `# bash completion for forgejo-mcp --cli
+# Install: source <(forgejo-mcp --cli completion bash)
+_forgejo_mcp() {
+    local i start=0
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        if [[ ${COMP_WORDS[i]} == --cli ]]; then
+            start=$((i + 1))
+            break
+        fi
+    done
+    if ((start == 0)); then
+        [[ ${COMP_WORDS[COMP_CWORD]} == -* ]] && COMPREPLY=($(compgen -W "--cli" -- "${COMP_WORDS[COMP_CWORD]}"))
+        return
+    fi
+    local IFS=$'\n'
+    COMPREPLY=($("${COMP_WORDS[0]}" --cli __complete bash%[1]s -- "${COMP_WORDS[@]:start:COMP_CWORD-start+1}" 2>/dev/null))
+}
+complete -F _forgejo_mcp forgejo-mcp
+`
+
+const zshCompletion = `#compdef forgejo-mcp
+# zsh completion for forgejo-mcp --cli
+# Install: forgejo-mcp --cli completion zsh > "${fpath[1]}/_forgejo-mcp"
+_forgejo_mcp() {
+    local start=${words[(i)--cli]}
+    if (( start >= CURRENT )); then
+        compadd -- --cli
+        return
+    fi
+    local -a candidates
+    candidates=(${(f)"$(${words[1]} --cli __complete zsh%[1]s -- "${(@)words[start+1,CURRENT]}" 2>/dev/null)"})
+    _describe -t values 'forgejo-mcp' candidates
+}
+compdef _forgejo_mcp forgejo-mcp
+`
+
+const fishCompletion = `# fish completion for forgejo-mcp --cli
+# Install: forgejo-mcp --cli completion fish > ~/.config/fish/completions/forgejo-mcp.fish
+function __forgejo_mcp_complete
+    set -l tokens (commandline -opc)
+    set -l cli (contains -i -- --cli $tokens)
+    or return
+    set -l args
+    if test $cli -lt (count $tokens)
+        set args $tokens[(math $cli + 1)..-1]
+    end
+    set -l cur (commandline -ct)
+    $tokens[1] --cli __complete fish%[1]s -- $args "$cur" 2>/dev/null
+end
+complete -c forgejo-mcp -l cli -d 'Invoke a tool from the command line'
+complete -c forgejo-mcp -f -n 'contains -- --cli (commandline -opc)' -a '(__forgejo_mcp_complete)'
+`
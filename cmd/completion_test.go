@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func completionServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "")
+	registerToolsWithDomains(s)
+	return s
+}
+
+func values(cands []candidate) []string {
+	out := make([]string, 0, len(cands))
+	for _, c := range cands {
+		out = append(out, c.Value)
+	}
+	return out
+}
+
+func TestCompleteWords(t *testing.T) {
+	s := completionServer()
+	repos := func() []string { return []string{"goern/forgejo-mcp", "goern/dotfiles", "b4mad/site"} }
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{"tool names", []string{"merge_pull"}, []string{"merge_pull_request"}},
+		{"commands", []string{"compl"}, []string{"completion"}},
+		{"shells", []string{"completion", ""}, []string{"bash", "fish", "zsh"}},
+		{"flags", []string{"merge_pull_request", "--st"}, []string{"--style"}},
+		{"kebab flags", []string{"list_repo_labels", "--include"}, []string{"--include-org-labels"}},
+		{"enum after flag", []string{"merge_pull_request", "--style", "rebase"}, []string{"rebase", "rebase-merge"}},
+		{"enum in one word", []string{"merge_pull_request", "--style=sq"}, []string{"--style=squash"}},
+		{"suggested values", []string{"merge_pull_request", "--style", "f"}, []string{"fast-forward-only"}},
+		{"schema enum", []string{"get_issue_dependency_graph", "--format", "m"}, []string{"mermaid"}},
+		{"bash split on =", []string{"list_repo_issues", "--state", "=", "c"}, []string{"closed"}},
+		{"output formats", []string{"list_repo_issues", "--output", ""}, []string{"csv", "json", "ndjson", "table", "text", "yaml"}},
+		{"output flags", []string{"list_repo_issues", "--q"}, []string{"--query"}},
+		{"owners", []string{"list_repo_issues", "--owner", ""}, []string{"b4mad", "goern"}},
+		{"repos of owner", []string{"list_repo_issues", "--owner", "goern", "--repo", ""}, []string{"dotfiles", "forgejo-mcp"}},
+		{"unknown tool", []string{"no_such_tool", "--"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := values(completeWords(s, tt.words, repos))
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("completeWords(%q) = %q, want %q", tt.words, got, tt.want)
+			}
+		})
+	}
+
+	if got := completeWords(s, []string{"list_repo_issues", "--owner", ""}, nil); len(got) != 0 {
+		t.Fatalf("owner completion without --repos = %v", got)
+	}
+}
+
+func TestWriteCandidates(t *testing.T) {
+	cands := []candidate{{"a:b", "desc"}, {"c", ""}}
+	for shell, want := range map[string]string{
+		"bash": "a:b\nc\n",
+		"zsh":  "a\\:b:desc\nc\n",
+		"fish": "a:b\tdesc\nc\n",
+	} {
+		var buf bytes.Buffer
+		writeCandidates(&buf, shell, cands)
+		if buf.String() != want {
+			t.Errorf("%s: got %q, want %q", shell, buf.String(), want)
+		}
+	}
+}
+
+func TestCLICompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := cliCompletion(&buf, shell, true); err != nil {
+			t.Fatal(err)
+		}
+		script := buf.String()
+		if !strings.Contains(script, "__complete "+shell+" --repos --") || strings.Contains(script, "%!") {
+			t.Errorf("%s script does not call back correctly:\n%s", shell, script)
+		}
+	}
+	if err := cliCompletion(&bytes.Buffer{}, "tcsh", false); err == nil {
+		t.Fatal("unsupported shell accepted")
+	}
+}
+
+func TestMyRepos(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	t.Setenv("FORGEJO_URL", srv.URL)
+	t.Setenv("FORGEJO_ACCESS_TOKEN", srv.Token)
+	srv.Use(t)
+	login := srv.User().UserName
+	srv.CreateRepo(login, "demo")
+
+	got := myRepos(completionServer())
+	if !slices.Contains(got, login+"/demo") {
+		t.Fatalf("myRepos = %v, want %s/demo", got, login)
+	}
+}
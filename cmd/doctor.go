@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+
+	flagPkg "git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/upload"
+)
+
+// Doctor check outcomes.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// certExpiryWarning is how close to expiry a TLS certificate draws a warning.
+const certExpiryWarning = 14 * 24 * time.Hour
+
+// doctorCheck is one line of the `--cli doctor` report.
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// doctorReport collects checks in the order they ran.
+type doctorReport []doctorCheck
+
+func (r *doctorReport) add(name, status, format string, args ...any) {
+	*r = append(*r, doctorCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r doctorReport) failed() bool {
+	for _, c := range r {
+		if c.Status == checkFail {
+			return true
+		}
+	}
+	return false
+}
+
+// doctorConfig checks the URL configuration without initConfig's fatal
+// exits, so a missing or malformed URL shows up in the report.
+func doctorConfig(r *doctorReport) bool {
+	raw := os.Getenv("FORGEJO_URL")
+	if raw == "" {
+		raw = os.Getenv("GITEA_HOST")
+	}
+	if raw == "" {
+		r.add("url", checkFail, "not configured: set FORGEJO_URL")
+		return false
+	}
+	if err := validateURL(raw); err != nil {
+		r.add("url", checkFail, "%v", err)
+		return false
+	}
+	return true
+}
+
+// runDoctor checks the configured instance. Checks that need the server
+// are skipped once it proves unreachable.
+func runDoctor(ctx context.Context, r *doctorReport) {
+	u, _ := url.Parse(flagPkg.URL)
+	if u.Scheme == "http" && !isLoopback(u.Hostname()) {
+		r.add("url", checkWarn, "%s uses plain HTTP; the token is sent unencrypted", sanitizeURL(u))
+	} else {
+		r.add("url", checkPass, "%s", sanitizeURL(u))
+	}
+
+	raw, ok := doctorReachability(ctx, r, u)
+	if !ok {
+		return
+	}
+	doctorVersion(ctx, r, raw)
+	user := doctorToken(ctx, r)
+	doctorScopes(ctx, r)
+	if len(flagPkg.SudoAllowlist) > 0 {
+		switch {
+		case user == nil:
+			r.add("sudo", checkWarn, "allowlist set; admin status unknown without a working token")
+		case user.IsAdmin:
+			r.add("sudo", checkPass, "admin token may act as %s", strings.Join(flagPkg.SudoAllowlist, ", "))
+		default:
+			r.add("sudo", checkFail, "allowlist set but %s is not an admin; Sudo requests will be rejected", user.UserName)
+		}
+	}
+	doctorSettings(ctx, r)
+}
+
+// doctorReachability fetches /api/v1/version directly so TLS and HTTP
+// failures can be told apart. It returns the reported version.
+func doctorReachability(ctx context.Context, r *doctorReport, u *url.URL) (string, bool) {
+	endpoint := strings.TrimRight(flagPkg.URL, "/") + "/api/v1/version"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		r.add("reachability", checkFail, "%v", err)
+		return "", false
+	}
+	req.Header.Set("User-Agent", flagPkg.UserAgent)
+	client := &http.Client{Timeout: 15 * time.Second}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var dnsErr *net.DNSError
+		switch {
+		case errors.As(err, &certErr), errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr):
+			r.add("tls", checkFail, "certificate not trusted: %v", unwrapURLError(err))
+		case errors.As(err, &dnsErr):
+			r.add("reachability", checkFail, "cannot resolve %s", u.Hostname())
+		default:
+			r.add("reachability", checkFail, "%v", unwrapURLError(err))
+		}
+		return "", false
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var body struct {
+		Version string `json:"version"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&body) != nil || body.Version == "" {
+		r.add("reachability", checkFail, "GET /api/v1/version returned %s; is %s the root of a Forgejo instance?", resp.Status, sanitizeURL(u))
+		return "", false
+	}
+	r.add("reachability", checkPass, "API answered in %s", elapsed)
+
+	if resp.TLS == nil {
+		if u.Scheme == "http" {
+			r.add("tls", checkWarn, "not using TLS")
+		}
+		return body.Version, true
+	}
+	cert := resp.TLS.PeerCertificates[0]
+	left := time.Until(cert.NotAfter)
+	status := checkPass
+	if left < certExpiryWarning {
+		status = checkWarn
+	}
+	r.add("tls", status, "%s, certificate valid until %s (%d days)",
+		tls.VersionName(resp.TLS.Version), cert.NotAfter.Format("2006-01-02"), int(left.Hours()/24))
+	return body.Version, true
+}
+
+// doctorVersion compares the server version with the version-gated tools.
+func doctorVersion(ctx context.Context, r *doctorReport, raw string) {
+	info := forgejo.ParseServerVersion(raw)
+	switch info.Flavor {
+	case forgejo.FlavorForgejo:
+		r.add("version", checkPass, "Forgejo %s", info.Version)
+	case forgejo.FlavorGitea:
+		r.add("version", checkWarn, "Gitea %s; Forgejo-only features are unavailable", info.Version)
+	default:
+		r.add("version", checkWarn, "unrecognised version %q; assuming every feature is available", raw)
+	}
+	_, statuses := forgejo.Capabilities(ctx)
+	for _, c := range statuses {
+		if !c.Enabled {
+			r.add("feature "+string(c.Name), checkWarn, "%s", c.Reason)
+		}
+	}
+}
+
+// doctorToken resolves the identity behind the token.
+func doctorToken(ctx context.Context, r *doctorReport) *forgejo_sdk.User {
+	if flagPkg.Token == "" {
+		r.add("token", checkWarn, "no token: set FORGEJO_ACCESS_TOKEN; only public data is readable")
+		return nil
+	}
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		r.add("token", checkFail, "%v", err)
+		return nil
+	}
+	user, resp, err := client.GetMyUserInfo()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			r.add("token", checkFail, "rejected by the server (401): expired, revoked or for another instance")
+		} else {
+			r.add("token", checkFail, "GET /user: %v", err)
+		}
+		return nil
+	}
+	role := ""
+	if user.IsAdmin {
+		role = " (admin)"
+	}
+	r.add("token", checkPass, "authenticated as %s%s", user.UserName, role)
+	return user
+}
+
+// doctorScopeCategories are the scope categories tools are filtered by.
+var doctorScopeCategories = []string{
+	forgejo.ScopeRepository, forgejo.ScopeIssue, forgejo.ScopeUser,
+	forgejo.ScopeNotification, forgejo.ScopeOrganization,
+}
+
+// doctorScopes lists the token's scopes and the ones whose tools are hidden.
+func doctorScopes(ctx context.Context, r *doctorReport) {
+	if flagPkg.Token == "" {
+		return
+	}
+	scopes := forgejo.ScopesFor(ctx)
+	if !scopes.Known {
+		r.add("scopes", checkWarn, "could not be determined; every tool stays listed")
+		return
+	}
+	var missing []string
+	for _, category := range doctorScopeCategories {
+		switch {
+		case !scopes.Allows(category, false):
+			missing = append(missing, "read:"+category)
+		case !scopes.Allows(category, true):
+			missing = append(missing, "write:"+category)
+		}
+	}
+	granted := strings.Join(scopes.Names(), ", ")
+	if !scopes.WritesProbed {
+		granted += " (write scopes not probed; see --probe-write-scopes)"
+	}
+	if len(missing) > 0 {
+		r.add("scopes", checkWarn, "%s; tools needing %s are hidden", granted, strings.Join(missing, ", "))
+		return
+	}
+	r.add("scopes", checkPass, "%s", granted)
+}
+
+// doctorSettings reports the server limits tools adapt to and the local
+// upload configuration.
+func doctorSettings(ctx context.Context, r *doctorReport) {
+	if max, ok := forgejo.MaxResponseItems(ctx); ok {
+		r.add("max response items", checkPass, "%d per page", max)
+	} else {
+		r.add("max response items", checkWarn, "unknown (GET /settings/api failed); list tools use their default page sizes")
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err == nil {
+		settings, _, err := client.GetGlobalAttachmentSettings()
+		switch {
+		case err != nil:
+			r.add("attachments", checkWarn, "GET /settings/attachment: %v", err)
+		case !settings.Enabled:
+			r.add("attachments", checkWarn, "disabled on the server; attachment uploads will fail")
+		default:
+			r.add("attachments", checkPass, "up to %d files of %s; types %s",
+				settings.MaxFiles, formatBytes(settings.MaxSize), settings.AllowedTypes)
+		}
+	}
+
+	enabled, root, err := upload.FilePathSettings()
+	switch {
+	case err != nil && enabled:
+		r.add("file path uploads", checkFail, "%v", err)
+	case err != nil:
+		// Nothing is uploaded from disk yet, but enabling uploads would fail.
+		r.add("file path uploads", checkWarn, "disabled; %v", err)
+	case !enabled:
+		r.add("file path uploads", checkPass, "disabled (set %s=1 to allow file_path)", upload.AllowFilePathEnv)
+	case root == "":
+		r.add("file path uploads", checkWarn, "enabled for any readable file; set %s to confine them", upload.UploadRootEnv)
+	default:
+		r.add("file path uploads", checkPass, "enabled, confined to %s", root)
+	}
+}
+
+// writeDoctorReport prints the checks and a summary line.
+func writeDoctorReport(w io.Writer, r doctorReport, outputMode string) error {
+	if outputMode == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	counts := map[string]int{}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range r {
+		counts[c.Status]++
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", strings.ToUpper(c.Status), c.Name, c.Detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n", counts[checkPass], counts[checkWarn], counts[checkFail])
+	return err
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// sanitizeURL drops credentials and query strings.
+func sanitizeURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	return clean.String()
+}
+
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	flagPkg "git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/upload"
+)
+
+func checkStatuses(r doctorReport) map[string]string {
+	out := map[string]string{}
+	for _, c := range r {
+		out[c.Name] = c.Status + ": " + c.Detail
+	}
+	return out
+}
+
+func TestDoctor(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Version = "15.0.1+gitea-1.22.0"
+	srv.Use(t)
+	t.Setenv(upload.AllowFilePathEnv, "1")
+	t.Setenv(upload.UploadRootEnv, "")
+
+	var report doctorReport
+	runDoctor(context.Background(), &report)
+	got := checkStatuses(report)
+	for name, want := range map[string]string{
+		"url":                     "pass: " + srv.URL,
+		"reachability":            "pass: API answered",
+		"version":                 "pass: Forgejo 15.0.1",
+		"feature action_job_logs": "warn: requires Forgejo v16+",
+		"token":                   "pass: authenticated as " + srv.User().UserName,
+		"scopes":                  "pass: read:issue",
+		"max response items":      "pass: 50 per page",
+		"attachments":             "pass: up to 5 files of 2.0 MiB",
+		"file path uploads":       "warn: enabled for any readable file",
+	} {
+		if !strings.HasPrefix(got[name], want) {
+			t.Errorf("%s = %q, want %q", name, got[name], want)
+		}
+	}
+	if report.failed() {
+		t.Errorf("healthy server reported failures: %v", report)
+	}
+
+	var out bytes.Buffer
+	if err := writeDoctorReport(&out, report, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "[PASS]  url") || !strings.Contains(out.String(), "0 failed") {
+		t.Errorf("report:\n%s", out.String())
+	}
+}
+
+func TestDoctor_UploadRoot(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	t.Setenv(upload.UploadRootEnv, t.TempDir()+"/missing")
+
+	for allow, want := range map[string]string{"": "warn: disabled; ", "1": "fail: "} {
+		t.Setenv(upload.AllowFilePathEnv, allow)
+		var report doctorReport
+		doctorSettings(context.Background(), &report)
+		if got := checkStatuses(report)["file path uploads"]; !strings.HasPrefix(got, want) || !strings.Contains(got, "missing") {
+			t.Errorf("%s=%q: file path uploads = %q, want %q...", upload.AllowFilePathEnv, allow, got, want)
+		}
+	}
+}
+
+func TestDoctor_BadTokenAndTLS(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	flagPkg.Token = "wrong"
+
+	var report doctorReport
+	runDoctor(context.Background(), &report)
+	if got := checkStatuses(report)["token"]; !strings.HasPrefix(got, "fail: rejected by the server (401)") {
+		t.Errorf("token = %q", got)
+	}
+	if !report.failed() {
+		t.Error("rejected token not reported as a failure")
+	}
+
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsSrv.Close)
+	flagPkg.URL = tlsSrv.URL
+	report = nil
+	runDoctor(context.Background(), &report)
+	if got := checkStatuses(report)["tls"]; !strings.HasPrefix(got, "fail: certificate not trusted") {
+		t.Errorf("tls = %q", got)
+	}
+}
+
+func TestDoctorConfig(t *testing.T) {
+	for url, ok := range map[string]bool{"": false, "ftp://example.com": false, "https://example.com": true} {
+		t.Setenv("FORGEJO_URL", url)
+		t.Setenv("GITEA_HOST", "")
+		var report doctorReport
+		if got := doctorConfig(&report); got != ok || report.failed() == ok {
+			t.Errorf("doctorConfig(%q) = %v, report %v", url, got, report)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// completer returns candidates for the word ending at the cursor and the
+// byte offset in line where that word starts.
+type completer func(line string) (start int, candidates []string)
+
+// lineEditor is a minimal Emacs-style line editor for the CLI shell:
+// cursor movement, history and tab completion. It reads keys from in, which
+// the caller has put in raw mode, and echoes to out.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete completer
+}
+
+func newLineEditor(in io.Reader, out io.Writer, complete completer) *lineEditor {
+	return &lineEditor{in: bufio.NewReader(in), out: out, complete: complete}
+}
+
+// addHistory appends line unless it is blank or repeats the last entry.
+func (e *lineEditor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if n := len(e.history); n > 0 && e.history[n-1] == line {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+// readLine edits one line after prompt. It returns io.EOF on Ctrl-D at an
+// empty line and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	histIdx := len(e.history)
+	draft := ""
+
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+		redraw()
+	}
+	insert := func(s string) {
+		r := []rune(s)
+		buf = append(buf[:pos], append(r, buf[pos:]...)...)
+		pos += len(r)
+		redraw()
+	}
+
+	fmt.Fprint(e.out, prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				redraw()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				redraw()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			redraw()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			redraw()
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+				redraw()
+			}
+		case 6: // Ctrl-F
+			if pos < len(buf) {
+				pos++
+				redraw()
+			}
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+			redraw()
+		case 21: // Ctrl-U
+			buf = buf[pos:]
+			pos = 0
+			redraw()
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+			redraw()
+		case 12: // Ctrl-L
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+			redraw()
+		case '\t':
+			if e.complete == nil {
+				continue
+			}
+			if text, list := e.tabComplete(string(buf[:pos])); list != nil {
+				fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(list, "  "))
+				redraw()
+			} else if text != "" {
+				insert(text)
+			}
+		case 16, 14: // Ctrl-P, Ctrl-N
+			histIdx, draft = e.moveHistory(r == 16, histIdx, draft, string(buf), setLine)
+		case 27: // Escape sequence
+			key := e.readEscape()
+			switch key {
+			case "A", "B":
+				histIdx, draft = e.moveHistory(key == "A", histIdx, draft, string(buf), setLine)
+			case "C":
+				if pos < len(buf) {
+					pos++
+					redraw()
+				}
+			case "D":
+				if pos > 0 {
+					pos--
+					redraw()
+				}
+			case "H", "1~":
+				pos = 0
+				redraw()
+			case "F", "4~":
+				pos = len(buf)
+				redraw()
+			case "3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					redraw()
+				}
+			}
+		default:
+			if r >= ' ' && r != utf8.RuneError {
+				insert(string(r))
+			}
+		}
+	}
+}
+
+// readEscape consumes a CSI or SS3 sequence and returns its final part,
+// e.g. "A" for the up arrow or "3~" for Delete.
+func (e *lineEditor) readEscape() string {
+	b, err := e.in.ReadByte()
+	if err != nil || (b != '[' && b != 'O') {
+		return ""
+	}
+	var seq strings.Builder
+	for {
+		c, err := e.in.ReadByte()
+		if err != nil {
+			return ""
+		}
+		seq.WriteByte(c)
+		if c >= 0x40 && c <= 0x7e {
+			return seq.String()
+		}
+	}
+}
+
+// moveHistory steps through history, keeping the line being typed as the
+// entry below the newest one.
+func (e *lineEditor) moveHistory(up bool, idx int, draft, current string, set func(string)) (int, string) {
+	if idx == len(e.history) {
+		draft = current
+	}
+	switch {
+	case up && idx > 0:
+		idx--
+		set(e.history[idx])
+	case !up && idx < len(e.history)-1:
+		idx++
+		set(e.history[idx])
+	case !up && idx == len(e.history)-1:
+		idx++
+		set(draft)
+	}
+	return idx, draft
+}
+
+// tabComplete returns the text to insert for a unique completion (or a
+// longer common prefix), or the candidate list to show when ambiguous.
+func (e *lineEditor) tabComplete(before string) (string, []string) {
+	start, cands := e.complete(before)
+	word := before[start:]
+	switch len(cands) {
+	case 0:
+		return "", nil
+	case 1:
+		text := strings.TrimPrefix(cands[0], word)
+		if !strings.HasSuffix(cands[0], "=") {
+			text += " "
+		}
+		return text, nil
+	}
+	prefix := cands[0]
+	for _, c := range cands[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(word) {
+		return strings.TrimPrefix(prefix, word), nil
+	}
+	return "", cands
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.yaml.in/yaml/v3"
+)
+
+// outputFormats are the values --output accepts. json without --query keeps
+// printing the raw MCP content array, as it always has.
+var outputFormats = []string{"json", "text", "table", "yaml", "csv", "ndjson"}
+
+// entityColumns are the default table/csv columns per entity type, matched
+// by the first rule whose marker keys are all present. Dotted columns
+// select nested fields; a list of objects renders as their joined values.
+var entityColumns = []struct {
+	markers []string
+	columns []string
+}{
+	{[]string{"number", "head", "base"}, []string{"number", "state", "title", "user.login", "head.ref", "base.ref", "merged"}},
+	{[]string{"number", "title"}, []string{"number", "state", "title", "user.login", "labels.name", "updated_at"}},
+	{[]string{"index", "title", "author"}, []string{"index", "state", "title", "author", "labels", "updated_at"}},
+	{[]string{"index_in_repo", "status"}, []string{"id", "index_in_repo", "status", "event", "prettyref", "title"}},
+	{[]string{"run_id", "status"}, []string{"id", "run_id", "name", "status"}},
+	{[]string{"tag_name"}, []string{"tag_name", "name", "draft", "prerelease", "published_at"}},
+	{[]string{"full_name", "owner"}, []string{"full_name", "private", "fork", "stars_count", "open_issues_count", "updated_at"}},
+	{[]string{"name", "color"}, []string{"id", "name", "color", "description"}},
+	{[]string{"body", "user", "id"}, []string{"id", "user.login", "created_at", "body"}},
+	{[]string{"login"}, []string{"login", "full_name", "email"}},
+}
+
+// maxDefaultColumns caps the fallback columns for unknown entities.
+const maxDefaultColumns = 8
+
+// cliOutput is how --cli prints a tool result: the --output format, an
+// optional --query applied to the unwrapped Result, and --columns for
+// table and csv.
+type cliOutput struct {
+	Format  string
+	Query   string
+	Columns []string
+}
+
+// newCLIOutput validates the output flags before any tool is called.
+func newCLIOutput(format, query, columns string) (cliOutput, error) {
+	o := cliOutput{Format: format, Query: query, Columns: splitList(columns)}
+	if !slices.Contains(outputFormats, format) {
+		return o, fmt.Errorf("unknown output format %q: use %s", format, strings.Join(outputFormats, ", "))
+	}
+	if query != "" {
+		if _, err := compileQuery(query); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
+
+// raw reports whether the result is printed as the tool returned it:
+// the MCP content array for json, its text for text.
+func (o cliOutput) raw() bool {
+	return o.Query == "" && (o.Format == "json" || o.Format == "text")
+}
+
+// write prints a decoded result, applying the query first.
+func (o cliOutput) write(w io.Writer, v any) error {
+	if o.Query != "" {
+		var err error
+		if v, err = applyQuery(o.Query, v); err != nil {
+			return err
+		}
+	}
+	return writeValue(w, v, o.Format, o.Columns)
+}
+
+// resultValue decodes the first text content of a tool result, unwrapping
+// the {"Result": ...} envelope tool handlers return. Non-JSON text is
+// returned as a string.
+func resultValue(result *mcp.CallToolResult) any {
+	text := resultText(result)
+	if v, ok := decodeResult(text); ok {
+		return v
+	}
+	return text
+}
+
+// resultText joins the text parts of a tool result.
+func resultText(result *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		if tc, ok := c.(mcp.TextContent); ok {
+			parts = append(parts, tc.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// decodeResult parses a JSON tool result, unwrapping the {"Result": ...}
+// envelope.
+func decodeResult(text string) (any, bool) {
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, false
+	}
+	if m, ok := value.(map[string]any); ok && len(m) == 1 {
+		if inner, ok := m["Result"]; ok {
+			value = inner
+		}
+	}
+	return value, true
+}
+
+// writeValue prints a decoded result in the given format. columns
+// overrides the default table/csv columns.
+func writeValue(w io.Writer, v any, format string, columns []string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "text":
+		if s, ok := v.(string); ok {
+			_, err := fmt.Fprintln(w, s)
+			return err
+		}
+		return json.NewEncoder(w).Encode(v)
+	case "ndjson":
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		enc := json.NewEncoder(w)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "table", "csv":
+		rows := tableRows(v)
+		if len(columns) == 0 {
+			columns = defaultColumns(rows)
+		}
+		if format == "csv" {
+			return writeCSV(w, rows, columns)
+		}
+		return writeTable(w, rows, columns)
+	}
+	return fmt.Errorf("unknown output format %q: use %s", format, strings.Join(outputFormats, ", "))
+}
+
+// tableRows turns a result into rows: an array's elements, the single array
+// inside a wrapper object such as {"total_count": 2, "workflow_runs": [...]},
+// or the value itself.
+func tableRows(v any) []any {
+	switch v := v.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		var inner []any
+		found := 0
+		for _, field := range v {
+			if items, ok := field.([]any); ok {
+				inner = items
+				found++
+			}
+		}
+		if found == 1 && len(inner) > 0 {
+			if _, ok := inner[0].(map[string]any); ok {
+				return inner
+			}
+		}
+	}
+	return []any{v}
+}
+
+// defaultColumns picks the entity columns for the first row, falling back
+// to its scalar fields.
+func defaultColumns(rows []any) []string {
+	if len(rows) == 0 {
+		return nil
+	}
+	first, ok := rows[0].(map[string]any)
+	if !ok {
+		return []string{"value"}
+	}
+	for _, entity := range entityColumns {
+		matched := true
+		for _, key := range entity.markers {
+			if _, ok := first[key]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return entity.columns
+		}
+	}
+	var columns []string
+	for _, key := range sortedKeys(first) {
+		switch first[key].(type) {
+		case map[string]any, []any:
+			continue
+		}
+		columns = append(columns, key)
+	}
+	// Identifying fields lead.
+	sort.SliceStable(columns, func(i, j int) bool {
+		return columnRank(columns[i]) < columnRank(columns[j])
+	})
+	if len(columns) > maxDefaultColumns {
+		columns = columns[:maxDefaultColumns]
+	}
+	return columns
+}
+
+func columnRank(name string) int {
+	switch name {
+	case "id":
+		return 0
+	case "number", "index":
+		return 1
+	case "name", "title", "login":
+		return 2
+	}
+	return 3
+}
+
+// cell extracts a dotted column from a row.
+func cell(row any, column string) string {
+	if column == "value" {
+		if _, ok := row.(map[string]any); !ok {
+			return cellString(row)
+		}
+	}
+	return cellString(lookupPath(row, strings.Split(column, ".")))
+}
+
+// lookupPath follows keys through objects; through an array it collects
+// the path from each element.
+func lookupPath(v any, keys []string) any {
+	for i, key := range keys {
+		switch x := v.(type) {
+		case map[string]any:
+			v = x[key]
+		case []any:
+			out := make([]any, 0, len(x))
+			for _, item := range x {
+				out = append(out, lookupPath(item, keys[i:]))
+			}
+			return out
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// cellString renders a value for a table cell or CSV field.
+func cellString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, cellString(item))
+		}
+		return strings.Join(parts, ",")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func writeTable(w io.Writer, rows []any, columns []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = strings.ToUpper(strings.ReplaceAll(c, ".", "_"))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			// Keep one row per line and the columns aligned.
+			cells[i] = strings.NewReplacer("\n", " ", "\r", "", "\t", " ").Replace(cell(row, c))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, rows []any, columns []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i] = cell(row, c)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeJSON(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestWriteValue(t *testing.T) {
+	issues := decodeJSON(t, `[
+		{"number": 1, "title": "Bug", "state": "open", "user": {"login": "alice"}, "labels": [{"name": "bug"}, {"name": "p1"}], "updated_at": "2026-01-02T00:00:00Z", "body": "line\nbreak"},
+		{"number": 2, "title": "Say \"hi\", please", "state": "closed", "user": {"login": "bob"}, "labels": [], "updated_at": "2026-01-03T00:00:00Z"}
+	]`)
+
+	tests := []struct {
+		format  string
+		columns []string
+		want    string
+	}{
+		{"table", nil, "" +
+			"NUMBER  STATE   TITLE             USER_LOGIN  LABELS_NAME  UPDATED_AT\n" +
+			"1       open    Bug               alice       bug,p1       2026-01-02T00:00:00Z\n" +
+			"2       closed  Say \"hi\", please  bob                      2026-01-03T00:00:00Z\n"},
+		{"table", []string{"number", "body"}, "" +
+			"NUMBER  BODY\n" +
+			"1       line break\n" +
+			"2       \n"},
+		{"csv", []string{"number", "title"}, "number,title\n1,Bug\n2,\"Say \"\"hi\"\", please\"\n"},
+		{"ndjson", []string{}, ""},
+		{"yaml", nil, ""},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writeValue(&out, issues, tt.format, tt.columns); err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if tt.want != "" && out.String() != tt.want {
+			t.Errorf("%s %v:\n%s\nwant:\n%s", tt.format, tt.columns, out.String(), tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	_ = writeValue(&out, issues, "ndjson", nil)
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[0], `{"body":`) {
+		t.Errorf("ndjson = %q", out.String())
+	}
+	out.Reset()
+	_ = writeValue(&out, issues, "yaml", nil)
+	if !strings.HasPrefix(out.String(), "- body: |-\n    line\n    break\n") || !strings.Contains(out.String(), "\n  user:\n    login: bob\n") {
+		t.Errorf("yaml =\n%s", out.String())
+	}
+	if err := writeValue(&out, issues, "xml", nil); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"pull requests", `[{"number": 1, "title": "t", "head": {}, "base": {}}]`, "number,state,title,user.login,head.ref,base.ref,merged"},
+		{"action runs wrapper", `{"total_count": 1, "workflow_runs": [{"id": 9, "index_in_repo": 3, "status": "success"}]}`, "id,index_in_repo,status,event,prettyref,title"},
+		{"repositories", `[{"full_name": "a/b", "owner": {}}]`, "full_name,private,fork,stars_count,open_issues_count,updated_at"},
+		{"unknown entity", `{"zeta": 1, "name": "n", "id": 2, "nested": {}}`, "id,name,zeta"},
+		{"scalars", `["a", "b"]`, "value"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(defaultColumns(tableRows(decodeJSON(t, tt.value))), ",")
+		if got != tt.want {
+			t.Errorf("%s: columns = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCLIOutput(t *testing.T) {
+	if _, err := newCLIOutput("xml", "", ""); err == nil {
+		t.Error("unknown format accepted")
+	}
+	if _, err := newCLIOutput("json", ".[", ""); err == nil {
+		t.Error("bad query accepted")
+	}
+
+	o, err := newCLIOutput("csv", "map(select(.state == \"open\"))", "number, title")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.raw() {
+		t.Error("csv output is not raw")
+	}
+	var out bytes.Buffer
+	issues := decodeJSON(t, `[{"number": 1, "title": "A", "state": "open"}, {"number": 2, "title": "B", "state": "closed"}]`)
+	if err := o.write(&out, issues); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "number,title\n1,A\n" {
+		t.Errorf("csv with query = %q", out.String())
+	}
+
+	out.Reset()
+	o, _ = newCLIOutput("text", ".[1].title", "")
+	if err := o.write(&out, issues); err != nil || out.String() != "B\n" {
+		t.Errorf("text with query = %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,485 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.yaml.in/yaml/v3"
+)
+
+// Defaults for steps that poll with `until`.
+const (
+	defaultPlanRetries  = 10
+	defaultPlanInterval = 10 * time.Second
+)
+
+// plan is a `--cli run` file: variables and the steps to run in order.
+type plan struct {
+	Vars  map[string]any `yaml:"vars"`
+	Steps []planStep     `yaml:"steps"`
+}
+
+// planStep calls one tool, or reads one resource URI. Strings in args, read
+// and for_each may contain ${expr} references; if and until are
+// expressions. Expressions use the --query language over the run context
+// {vars, env, steps, <as>}, so ${steps.pr.Result.number} is the number in
+// the result of the step with id "pr".
+type planStep struct {
+	ID              string         `yaml:"id"`
+	Tool            string         `yaml:"tool"`
+	Read            string         `yaml:"read"`
+	Args            map[string]any `yaml:"args"`
+	If              string         `yaml:"if"`
+	ForEach         any            `yaml:"for_each"`
+	As              string         `yaml:"as"`
+	Until           string         `yaml:"until"`
+	Retries         int            `yaml:"retries"`
+	Interval        string         `yaml:"interval"`
+	ContinueOnError bool           `yaml:"continue_on_error"`
+
+	interval time.Duration
+}
+
+var (
+	planRef    = regexp.MustCompile(`\$\{([^}]*)\}`)
+	planStepID = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	planIdent  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+)
+
+// loadPlan reads and validates a plan; "-" reads standard input.
+func loadPlan(s *server.MCPServer, path string) (*plan, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parsePlan(s, data)
+}
+
+// parsePlan decodes a plan and checks it before anything runs: unknown
+// keys and tools, duplicate ids and malformed expressions are errors.
+func parsePlan(s *server.MCPServer, data []byte) (*plan, error) {
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	var p plan
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	if len(p.Steps) == 0 {
+		return nil, errors.New("plan has no steps")
+	}
+	// YAML integers and maps become the float64s and map[string]any tool
+	// handlers get from JSON.
+	if err := normalizeJSON(&p.Vars); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.ID == "" {
+			step.ID = fmt.Sprintf("step%d", i+1)
+		}
+		where := fmt.Sprintf("step %d (%s)", i+1, step.ID)
+		switch {
+		case !planStepID.MatchString(step.ID):
+			return nil, fmt.Errorf("%s: id must be letters, digits and underscores", where)
+		case seen[step.ID]:
+			return nil, fmt.Errorf("%s: duplicate id", where)
+		case (step.Tool == "") == (step.Read == ""):
+			return nil, fmt.Errorf("%s: set exactly one of tool and read", where)
+		case step.Tool != "" && s.GetTool(step.Tool) == nil:
+			return nil, fmt.Errorf("%s: unknown tool %q", where, step.Tool)
+		case step.Read != "" && len(step.Args) > 0:
+			return nil, fmt.Errorf("%s: args apply to tool steps only", where)
+		}
+		seen[step.ID] = true
+
+		if err := normalizeJSON(&step.Args); err != nil {
+			return nil, err
+		}
+		if err := normalizeJSON(&step.ForEach); err != nil {
+			return nil, err
+		}
+		if step.As == "" {
+			step.As = "item"
+		}
+		if slices.Contains(planRoots, step.As) || queryKeywords[step.As] || !planStepID.MatchString(step.As) {
+			return nil, fmt.Errorf("%s: invalid loop variable %q", where, step.As)
+		}
+		roots := map[string]bool{step.As: true}
+		for _, root := range planRoots {
+			roots[root] = true
+		}
+		for _, expr := range []string{step.If, step.Until} {
+			if expr == "" {
+				continue
+			}
+			if _, err := compileQuery(planExpr(expr, roots)); err != nil {
+				return nil, fmt.Errorf("%s: %w", where, err)
+			}
+		}
+		if step.Until == "" && (step.Retries != 0 || step.Interval != "") {
+			return nil, fmt.Errorf("%s: retries and interval need until", where)
+		}
+		if step.Until != "" {
+			if step.Retries == 0 {
+				step.Retries = defaultPlanRetries
+			}
+			step.interval = defaultPlanInterval
+			if step.Interval != "" {
+				d, err := time.ParseDuration(step.Interval)
+				if err != nil {
+					return nil, fmt.Errorf("%s: interval: %w", where, err)
+				}
+				step.interval = d
+			}
+		}
+	}
+	return &p, nil
+}
+
+// normalizeJSON round-trips v through JSON.
+func normalizeJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// planRoots are the context names every plan expression can start with.
+var planRoots = []string{"vars", "env", "steps"}
+
+// planExpr turns a condition or ${...} body into a query: the enclosing
+// ${ } is optional and the leading dot of a path into one of roots may be
+// left out. Anything else, such as true or length > 0, is a query as
+// written.
+func planExpr(expr string, roots map[string]bool) string {
+	expr = strings.TrimSpace(expr)
+	if m := planRef.FindStringSubmatch(expr); m != nil && m[0] == expr {
+		expr = strings.TrimSpace(m[1])
+	}
+	if roots[planIdent.FindString(expr)] {
+		expr = "." + expr
+	}
+	return expr
+}
+
+// planRunner runs a plan's steps against the tools and resources of s.
+type planRunner struct {
+	s      *server.MCPServer
+	out    io.Writer
+	dryRun bool
+	ctx    map[string]any
+	sleep  func(time.Duration)
+}
+
+func newPlanRunner(s *server.MCPServer, out io.Writer, vars map[string]any, dryRun bool) *planRunner {
+	env := map[string]any{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return &planRunner{
+		s:      s,
+		out:    out,
+		dryRun: dryRun,
+		ctx:    map[string]any{"vars": vars, "env": env, "steps": map[string]any{}},
+		sleep:  time.Sleep,
+	}
+}
+
+// run runs every step in order and stops at the first failure not covered
+// by continue_on_error.
+func (r *planRunner) run(p *plan) error {
+	for _, step := range p.Steps {
+		result, err := r.runStep(step)
+		if err != nil {
+			if !step.ContinueOnError {
+				return fmt.Errorf("step %s: %w", step.ID, err)
+			}
+			fmt.Fprintf(r.out, "    error (continuing): %v\n", err)
+			result = map[string]any{"error": err.Error()}
+		}
+		r.ctx["steps"].(map[string]any)[step.ID] = result
+	}
+	return nil
+}
+
+// runStep runs one step, once or per for_each item. A loop's result is the
+// list of its iterations' results.
+func (r *planRunner) runStep(step planStep) (any, error) {
+	what := step.Tool
+	if step.Read != "" {
+		what = "read " + step.Read
+	}
+	fmt.Fprintf(r.out, "==> %s: %s\n", step.ID, what)
+	if step.ForEach == nil {
+		return r.runOnce(step)
+	}
+
+	list, err := r.interpolate(step.ForEach)
+	if err != nil {
+		return nil, fmt.Errorf("for_each: %w", err)
+	}
+	items, ok := list.([]any)
+	if !ok {
+		if r.dryRun {
+			fmt.Fprintf(r.out, "    for each %s in %s\n", step.As, formatValue(list))
+			items = []any{fmt.Sprintf("${%s}", step.As)}
+		} else {
+			return nil, fmt.Errorf("for_each: expected a list, got %s", typeName(list))
+		}
+	}
+	defer delete(r.ctx, step.As)
+	results := make([]any, 0, len(items))
+	for _, item := range items {
+		r.ctx[step.As] = item
+		result, err := r.runOnce(step)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", step.As, formatValue(item), err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runOnce checks the step's condition and calls it, polling while until is
+// not yet true.
+func (r *planRunner) runOnce(step planStep) (any, error) {
+	if step.If != "" {
+		if r.dryRun {
+			fmt.Fprintf(r.out, "    if %s\n", step.If)
+		} else if ok, err := r.condition(step.If); err != nil {
+			return nil, fmt.Errorf("if: %w", err)
+		} else if !ok {
+			fmt.Fprintln(r.out, "    skipped")
+			return nil, nil
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := r.call(step)
+		if step.Until == "" || r.dryRun {
+			if r.dryRun && step.Until != "" {
+				fmt.Fprintf(r.out, "    until %s (%d tries, every %s)\n", step.Until, step.Retries+1, step.interval)
+			}
+			return result, err
+		}
+		// A failed call counts as the condition not being met yet: what is
+		// polled for may not exist, or the server may be briefly down.
+		if err == nil {
+			// until sees the attempt's result as the step's own.
+			steps := r.ctx["steps"].(map[string]any)
+			steps[step.ID] = result
+			done, condErr := r.condition(step.Until)
+			delete(steps, step.ID)
+			if condErr != nil {
+				return nil, fmt.Errorf("until: %w", condErr)
+			}
+			if done {
+				return result, nil
+			}
+		}
+		if attempt > step.Retries {
+			if err != nil {
+				return nil, fmt.Errorf("until %s: not met after %d tries: %w", step.Until, attempt, err)
+			}
+			return nil, fmt.Errorf("until %s: not met after %d tries", step.Until, attempt)
+		}
+		if err != nil {
+			fmt.Fprintf(r.out, "    try %d failed: %v\n", attempt, err)
+		}
+		fmt.Fprintf(r.out, "    waiting %s for %s\n", step.interval, step.Until)
+		r.sleep(step.interval)
+	}
+}
+
+// call invokes the tool or reads the resource. Results are decoded JSON,
+// without unwrapping the tool envelope, or the raw text.
+func (r *planRunner) call(step planStep) (any, error) {
+	if step.Read != "" {
+		uri, err := r.interpolate(step.Read)
+		if err != nil {
+			return nil, err
+		}
+		uriText, ok := uri.(string)
+		if !ok {
+			return nil, fmt.Errorf("read: expected a URI, got %s", typeName(uri))
+		}
+		if r.dryRun {
+			fmt.Fprintf(r.out, "    read %s\n", uriText)
+			return nil, nil
+		}
+		contents, err := readResource(r.s, uriText)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range contents {
+			if tc, ok := c.(mcp.TextResourceContents); ok {
+				return decodePlanResult(tc.Text), nil
+			}
+		}
+		return nil, nil
+	}
+
+	st := r.s.GetTool(step.Tool)
+	resolved, err := r.interpolate(step.Args)
+	if err != nil {
+		return nil, err
+	}
+	args, _ := resolved.(map[string]any)
+	if args == nil {
+		args = map[string]any{}
+	}
+	if err := checkRequired(st.Tool, args); err != nil {
+		return nil, err
+	}
+	if r.dryRun {
+		data, _ := json.Marshal(args)
+		fmt.Fprintf(r.out, "    args %s\n", data)
+		return nil, nil
+	}
+	result, err := st.Handler(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: step.Tool, Arguments: args},
+	})
+	if err != nil {
+		return nil, err
+	}
+	text := resultText(result)
+	if result.IsError {
+		return nil, errors.New(text)
+	}
+	return decodePlanResult(text), nil
+}
+
+func decodePlanResult(text string) any {
+	var v any
+	if err := json.Unmarshal([]byte(text), &v); err != nil {
+		return text
+	}
+	return v
+}
+
+// condition evaluates an if or until expression.
+// roots names what expressions can refer to right now: vars, env, steps
+// and the loop variable of a running for_each.
+func (r *planRunner) roots() map[string]bool {
+	roots := make(map[string]bool, len(r.ctx))
+	for name := range r.ctx {
+		roots[name] = true
+	}
+	return roots
+}
+
+func (r *planRunner) condition(expr string) (bool, error) {
+	v, err := applyQuery(planExpr(expr, r.roots()), r.ctx)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// interpolate resolves ${...} references in strings, recursively. A string
+// that is a single reference takes the referenced value and type; embedded
+// references are formatted as text. In a dry run, references that do not
+// resolve yet are left as written.
+func (r *planRunner) interpolate(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if m := planRef.FindStringSubmatch(v); m != nil && m[0] == v {
+			return r.lookup(m[0], m[1])
+		}
+		var lookupErr error
+		out := planRef.ReplaceAllStringFunc(v, func(ref string) string {
+			value, err := r.lookup(ref, planRef.FindStringSubmatch(ref)[1])
+			if err != nil && lookupErr == nil {
+				lookupErr = err
+			}
+			return formatValue(value)
+		})
+		return out, lookupErr
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			resolved, err := r.interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			resolved, err := r.interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (r *planRunner) lookup(ref, expr string) (any, error) {
+	v, err := applyQuery(planExpr(expr, r.roots()), r.ctx)
+	if err == nil && v == nil {
+		err = fmt.Errorf("%s is null", ref)
+	}
+	if err != nil {
+		if r.dryRun {
+			return ref, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// planVars collects repeated --var name=value flags.
+type planVars map[string]any
+
+func (v planVars) String() string { return "" }
+
+func (v planVars) Set(kv string) error {
+	name, value, ok := strings.Cut(kv, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", kv)
+	}
+	v[name] = value
+	return nil
+}
+
+// cliRun loads and runs a plan file. vars override the plan's own.
+func cliRun(s *server.MCPServer, out io.Writer, path string, vars planVars, dryRun bool) error {
+	p, err := loadPlan(s, path)
+	if err != nil {
+		return err
+	}
+	merged := map[string]any{}
+	for k, v := range p.Vars {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return newPlanRunner(s, out, merged, dryRun).run(p)
+}
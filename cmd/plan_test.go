@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+)
+
+func TestParsePlan_Errors(t *testing.T) {
+	s := completionServer()
+	tests := map[string]string{
+		"no steps":       "vars: {a: 1}\n",
+		"unknown key":    "steps:\n- tool: get_my_user_info\n  arg: {}\n",
+		"unknown tool":   "steps:\n- tool: no_such_tool\n",
+		"tool and read":  "steps:\n- tool: get_my_user_info\n  read: forgejo://owner/a\n",
+		"duplicate id":   "steps:\n- {id: a, tool: get_my_user_info}\n- {id: a, tool: get_my_user_info}\n",
+		"bad id":         "steps:\n- {id: a-b, tool: get_my_user_info}\n",
+		"bad condition":  "steps:\n- {tool: get_my_user_info, if: '.a =='}\n",
+		"retries alone":  "steps:\n- {tool: get_my_user_info, retries: 3}\n",
+		"bad interval":   "steps:\n- {tool: get_my_user_info, until: .x, interval: soon}\n",
+		"bad loop var":   "steps:\n- {tool: get_my_user_info, for_each: [1], as: steps}\n",
+		"keyword as var": "steps:\n- {tool: get_my_user_info, for_each: [1], as: select}\n",
+		"args with read": "steps:\n- {read: forgejo://owner/a, args: {a: 1}}\n",
+	}
+	for name, yaml := range tests {
+		if _, err := parsePlan(s, []byte(yaml)); err == nil {
+			t.Errorf("%s: plan accepted", name)
+		}
+	}
+
+	p, err := parsePlan(s, []byte("steps:\n- tool: get_my_user_info\n- {tool: get_my_user_info, until: .x}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Steps[0].ID != "step1" || p.Steps[1].Retries != defaultPlanRetries || p.Steps[1].interval != defaultPlanInterval {
+		t.Fatalf("defaults not applied: %+v", p.Steps)
+	}
+}
+
+const testPlan = `
+vars:
+  owner: alice
+  repo: demo
+steps:
+  - id: create
+    tool: create_issue
+    args:
+      owner: ${vars.owner}
+      repo: ${vars.repo}
+      title: Release ${vars.version}
+  - id: issue
+    tool: get_issue_by_index
+    args:
+      owner: ${vars.owner}
+      repo: ${vars.repo}
+      index: ${steps.create.Result.number}
+  - id: open
+    tool: list_repo_issues
+    args: {owner: alice, repo: demo, page: 1, limit: 10}
+  - id: comment
+    tool: create_issue_comment
+    for_each: ${steps.open.Result}
+    as: issue
+    if: issue.title | . != "Old"
+    args:
+      owner: alice
+      repo: demo
+      index: ${issue.number}
+      body: "Tracking #${steps.create.Result.number}"
+  - id: skipped
+    tool: get_my_user_info
+    if: vars.version == "0.0"
+  - id: poll
+    read: forgejo://repo/alice/demo/issue/${steps.create.Result.number}
+    until: steps.poll.title | length > 0
+    interval: 1s
+`
+
+func TestPlanRunner(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "Old", "")
+
+	s := completionServer()
+	operation.RegisterCoreResources(s)
+	p, err := parsePlan(s, []byte(testPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := newPlanRunner(s, &out, map[string]any{"owner": "alice", "repo": "demo", "version": "1.2"}, false)
+	if err := r.run(p); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+
+	steps := r.ctx["steps"].(map[string]any)
+	issue := steps["issue"].(map[string]any)["Result"].(map[string]any)
+	if issue["title"] != "Release 1.2" {
+		t.Fatalf("issue = %v", issue)
+	}
+	comments := steps["comment"].([]any)
+	if len(comments) != 2 || comments[1] != nil || comments[0] == nil {
+		t.Fatalf("loop results = %v, want one comment and one skipped item", comments)
+	}
+	if steps["skipped"] != nil || !strings.Contains(out.String(), "skipped") {
+		t.Fatalf("condition not honoured:\n%s", out.String())
+	}
+	if poll, _ := steps["poll"].(map[string]any); poll["title"] != "Release 1.2" {
+		t.Fatalf("poll = %v", steps["poll"])
+	}
+}
+
+func TestPlanRunner_LiteralConditions(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+
+	s := completionServer()
+	p, err := parsePlan(s, []byte(`
+steps:
+  - {id: yes, tool: get_my_user_info, if: true}
+  - {id: no, tool: get_my_user_info, if: false}
+  - {id: never, tool: get_my_user_info, if: "null"}
+  - {id: counted, tool: get_my_user_info, if: "length > 0"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	r := newPlanRunner(s, &out, nil, false)
+	if err := r.run(p); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	steps := r.ctx["steps"].(map[string]any)
+	if steps["yes"] == nil || steps["counted"] == nil {
+		t.Fatalf("true conditions skipped their steps:\n%s", out.String())
+	}
+	if steps["no"] != nil || steps["never"] != nil {
+		t.Fatalf("false conditions ran their steps:\n%s", out.String())
+	}
+}
+
+func TestPlanRunner_UntilAndErrors(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+
+	s := completionServer()
+	p, err := parsePlan(s, []byte(`
+steps:
+  - id: wait
+    tool: list_repo_issues
+    args: {owner: alice, repo: demo, page: 1, limit: 10}
+    until: steps.wait.Result | length > 0
+    retries: 2
+    interval: 1m
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var slept []time.Duration
+	r := newPlanRunner(s, &bytes.Buffer{}, nil, false)
+	r.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		if len(slept) == 2 {
+			srv.CreateIssue("alice", "demo", "Arrived", "")
+		}
+	}
+	if err := r.run(p); err != nil {
+		t.Fatal(err)
+	}
+	if len(slept) != 2 || slept[0] != time.Minute {
+		t.Fatalf("slept %v, want two one-minute waits", slept)
+	}
+
+	// A call that fails is retried like an unmet condition.
+	p, err = parsePlan(s, []byte(`
+steps:
+  - id: appear
+    tool: get_issue_by_index
+    args: {owner: alice, repo: demo, index: 2}
+    until: steps.appear.Result.title == "Late"
+    retries: 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	slept = nil
+	r = newPlanRunner(s, &bytes.Buffer{}, nil, false)
+	r.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		if len(slept) == 1 {
+			srv.CreateIssue("alice", "demo", "Late", "")
+		}
+	}
+	if err := r.run(p); err != nil {
+		t.Fatalf("until did not retry a failing call: %v", err)
+	}
+	r = newPlanRunner(s, &bytes.Buffer{}, nil, false)
+	r.sleep = func(time.Duration) {}
+	p, _ = parsePlan(s, []byte(`
+steps:
+  - id: gone
+    tool: get_issue_by_index
+    args: {owner: alice, repo: demo, index: 99}
+    until: steps.gone.Result.number == 99
+    retries: 1
+`))
+	if err := r.run(p); err == nil || !strings.Contains(err.Error(), "not met after 2 tries") || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("err = %v, want the last call error after the retries", err)
+	}
+
+	p, _ = parsePlan(s, []byte(`
+steps:
+  - id: missing
+    tool: get_issue_by_index
+    args: {owner: alice, repo: demo, index: 99}
+    continue_on_error: true
+  - id: next
+    tool: get_issue_by_index
+    args: {owner: alice, repo: demo, index: "${steps.missing.Result.number}"}
+`))
+	err = newPlanRunner(s, &bytes.Buffer{}, nil, false).run(p)
+	if err == nil || !strings.Contains(err.Error(), "step next") || !strings.Contains(err.Error(), "is null") {
+		t.Fatalf("err = %v, want the unresolved reference in step next", err)
+	}
+}
+
+func TestPlanRunner_DryRun(t *testing.T) {
+	s := completionServer()
+	p, err := parsePlan(s, []byte(testPlan))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := newPlanRunner(s, &out, map[string]any{"owner": "alice", "repo": "demo", "version": "1.2"}, true).run(p); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`==> create: create_issue`,
+		`args {"owner":"alice","repo":"demo","title":"Release 1.2"}`,
+		`"index":"${steps.create.Result.number}"`,
+		`for each issue in ${steps.open.Result}`,
+		`if issue.title | . != "Old"`,
+		`read forgejo://repo/alice/demo/issue/${steps.create.Result.number}`,
+		`until steps.poll.title | length > 0 (11 tries, every 1s)`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,761 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filter is a compiled --query expression. Like jq, it maps one input to
+// zero or more outputs.
+type filter func(v any) ([]any, error)
+
+// compileQuery compiles the jq subset --query accepts:
+//
+//	.  .field  ."quoted field"  .[0]  .[-1]  .[]  a | b  a, b
+//	[ ... ]  { name, alias: .path }  ( ... )
+//	== != < <= > >=  and  or  not
+//	select(f)  map(f)  length  keys  first  last  join("sep")
+//	"string", numbers, true, false, null
+func compileQuery(expr string) (filter, error) {
+	f, _, err := parseQuery(expr)
+	return f, err
+}
+
+// parseQuery compiles expr and reports whether it may emit other than
+// exactly one output, through .[], a comma or select outside [ ] and map.
+func parseQuery(expr string) (filter, bool, error) {
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, false, err
+	}
+	p := &queryParser{tokens: tokens}
+	f, err := p.pipe()
+	if err != nil {
+		return nil, false, err
+	}
+	if !p.done() {
+		return nil, false, fmt.Errorf("query: unexpected %q", p.peek().text)
+	}
+	return f, p.stream, nil
+}
+
+// applyQuery runs expr against v. An expression that always emits one
+// output returns it as is; one that can emit a stream returns an array,
+// however many outputs it had.
+func applyQuery(expr string, v any) (any, error) {
+	f, stream, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	out, err := f(v)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	if len(out) == 1 && !stream {
+		return out[0], nil
+	}
+	if out == nil {
+		out = []any{}
+	}
+	return out, nil
+}
+
+type tokenKind int
+
+const (
+	tokPunct tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+)
+
+type queryToken struct {
+	kind tokenKind
+	text string
+	// dotted marks an identifier or string written directly after a dot,
+	// as in .field or ."field".
+	dotted bool
+}
+
+func lexQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	rs := []rune(expr)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(rs) && rs[j] != '"'; j++ {
+				if rs[j] == '\\' && j+1 < len(rs) {
+					j++
+				}
+				sb.WriteRune(rs[j])
+			}
+			if j >= len(rs) {
+				return nil, fmt.Errorf("query: unterminated string")
+			}
+			tokens = append(tokens, queryToken{kind: tokString, text: sb.String(), dotted: i > 0 && rs[i-1] == '.'})
+			i = j + 1
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]) && !afterOperand(tokens)):
+			j := i + 1
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, queryToken{kind: tokNumber, text: string(rs[i:j])})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i + 1
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, queryToken{kind: tokIdent, text: string(rs[i:j]), dotted: i > 0 && rs[i-1] == '.'})
+			i = j
+		default:
+			if i+1 < len(rs) {
+				if two := string(rs[i : i+2]); two == "==" || two == "!=" || two == "<=" || two == ">=" {
+					tokens = append(tokens, queryToken{kind: tokPunct, text: two})
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune(".[](){}|,:<>", r) {
+				return nil, fmt.Errorf("query: unexpected character %q", r)
+			}
+			tokens = append(tokens, queryToken{kind: tokPunct, text: string(r)})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+// afterOperand reports whether a '-' would be binary here; the subset has
+// no arithmetic, so it only matters for rejecting "a -1".
+func afterOperand(tokens []queryToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.kind != tokPunct || last.text == "]" || last.text == ")"
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+	stream bool // parsed a filter that can emit zero or several outputs
+}
+
+// collected parses an argument whose outputs end up in one array, so
+// streams inside it do not make the whole query a stream.
+func (p *queryParser) collected(parse func() (filter, error)) (filter, error) {
+	stream := p.stream
+	f, err := parse()
+	p.stream = stream
+	return f, err
+}
+
+func (p *queryParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *queryParser) peek() queryToken {
+	if p.done() {
+		return queryToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) accept(text string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokPunct && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if !p.accept(text) {
+		if p.done() {
+			return fmt.Errorf("query: expected %q at end", text)
+		}
+		return fmt.Errorf("query: expected %q, got %q", text, p.peek().text)
+	}
+	return nil
+}
+
+func (p *queryParser) acceptKeyword(word string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokIdent && !p.tokens[p.pos].dotted && p.tokens[p.pos].text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) pipe() (filter, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("|") {
+		right, err := p.comma()
+		if err != nil {
+			return nil, err
+		}
+		left = pipeFilters(left, right)
+	}
+	return left, nil
+}
+
+func pipeFilters(left, right filter) filter {
+	return func(v any) ([]any, error) {
+		ins, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, in := range ins {
+			res, err := right(in)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, res...)
+		}
+		return out, nil
+	}
+}
+
+func (p *queryParser) comma() (filter, error) {
+	first, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	parts := []filter{first}
+	for p.accept(",") {
+		next, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, next)
+	}
+	if len(parts) == 1 {
+		return first, nil
+	}
+	p.stream = true
+	return func(v any) ([]any, error) {
+		var out []any
+		for _, part := range parts {
+			res, err := part(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, res...)
+		}
+		return out, nil
+	}, nil
+}
+
+func (p *queryParser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("or") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, func(a, b any) (any, error) { return truthy(a) || truthy(b), nil })
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (filter, error) {
+	left, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("and") {
+		right, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(left, right, func(a, b any) (any, error) { return truthy(a) && truthy(b), nil })
+	}
+	return left, nil
+}
+
+func (p *queryParser) comparison() (filter, error) {
+	left, err := p.postfix()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.postfix()
+			if err != nil {
+				return nil, err
+			}
+			return binary(left, right, func(a, b any) (any, error) { return compareValues(op, a, b) }), nil
+		}
+	}
+	return left, nil
+}
+
+// binary evaluates both sides against the same input and combines every
+// pair of outputs, as jq does.
+func binary(left, right filter, combine func(a, b any) (any, error)) filter {
+	return func(v any) ([]any, error) {
+		ls, err := left(v)
+		if err != nil {
+			return nil, err
+		}
+		rs, err := right(v)
+		if err != nil {
+			return nil, err
+		}
+		var out []any
+		for _, l := range ls {
+			for _, r := range rs {
+				res, err := combine(l, r)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, res)
+			}
+		}
+		return out, nil
+	}
+}
+
+func (p *queryParser) postfix() (filter, error) {
+	f, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			step, err := p.fieldAfterDot()
+			if err != nil {
+				return nil, err
+			}
+			f = pipeFilters(f, step)
+		case p.accept("["):
+			step, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			f = pipeFilters(f, step)
+		default:
+			return f, nil
+		}
+	}
+}
+
+// fieldAfterDot parses what follows a dot in a path: a name, a quoted name
+// or a bracket.
+func (p *queryParser) fieldAfterDot() (filter, error) {
+	if p.accept("[") {
+		return p.bracket()
+	}
+	t := p.peek()
+	if (t.kind == tokIdent || t.kind == tokString) && t.dotted {
+		p.pos++
+		return fieldFilter(t.text), nil
+	}
+	return nil, fmt.Errorf("query: expected a field name after '.'")
+}
+
+// bracket parses the rest of [], [n] after the opening bracket.
+func (p *queryParser) bracket() (filter, error) {
+	if p.accept("]") {
+		p.stream = true
+		return iterate, nil
+	}
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.pos++
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("query: bad index %q", t.text)
+		}
+		return indexFilter(n), p.expect("]")
+	case tokString:
+		p.pos++
+		return fieldFilter(t.text), p.expect("]")
+	}
+	return nil, fmt.Errorf("query: expected an index or ']'")
+}
+
+func (p *queryParser) primary() (filter, error) {
+	if p.done() {
+		return nil, fmt.Errorf("query: unexpected end")
+	}
+	t := p.peek()
+	switch {
+	case p.accept("."):
+		next := p.peek()
+		if (next.kind == tokIdent || next.kind == tokString) && next.dotted {
+			p.pos++
+			return fieldFilter(next.text), nil
+		}
+		if p.accept("[") {
+			return p.bracket()
+		}
+		return identity, nil
+	case p.accept("("):
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	case p.accept("["):
+		if p.accept("]") {
+			return constant([]any{}), nil
+		}
+		f, err := p.collected(p.pipe)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return collect(f), nil
+	case p.accept("{"):
+		return p.object()
+	case t.kind == tokString:
+		p.pos++
+		return constant(t.text), nil
+	case t.kind == tokNumber:
+		p.pos++
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("query: bad number %q", t.text)
+		}
+		return constant(n), nil
+	case t.kind == tokIdent:
+		p.pos++
+		return p.builtin(t.text)
+	}
+	return nil, fmt.Errorf("query: unexpected %q", t.text)
+}
+
+// queryKeywords are the bare words compileQuery gives a meaning of its own.
+var queryKeywords = map[string]bool{
+	"true": true, "false": true, "null": true, "and": true, "or": true, "not": true,
+	"length": true, "keys": true, "first": true, "last": true, "select": true, "map": true, "join": true,
+}
+
+func (p *queryParser) builtin(name string) (filter, error) {
+	withArg := func() (filter, error) {
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		f, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return f, p.expect(")")
+	}
+	switch name {
+	case "true":
+		return constant(true), nil
+	case "false":
+		return constant(false), nil
+	case "null":
+		return constant(nil), nil
+	case "not":
+		return func(v any) ([]any, error) { return []any{!truthy(v)}, nil }, nil
+	case "length":
+		return lengthOf, nil
+	case "keys":
+		return keysOf, nil
+	case "first":
+		return indexFilter(0), nil
+	case "last":
+		return indexFilter(-1), nil
+	case "select":
+		cond, err := withArg()
+		if err != nil {
+			return nil, err
+		}
+		p.stream = true
+		return func(v any) ([]any, error) {
+			res, err := cond(v)
+			if err != nil {
+				return nil, err
+			}
+			var out []any
+			for _, r := range res {
+				if truthy(r) {
+					out = append(out, v)
+				}
+			}
+			return out, nil
+		}, nil
+	case "map":
+		f, err := p.collected(withArg)
+		if err != nil {
+			return nil, err
+		}
+		return collect(pipeFilters(iterate, f)), nil
+	case "join":
+		sepF, err := withArg()
+		if err != nil {
+			return nil, err
+		}
+		return func(v any) ([]any, error) {
+			seps, err := sepF(v)
+			if err != nil {
+				return nil, err
+			}
+			items, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot join %s", typeName(v))
+			}
+			var out []any
+			for _, sep := range seps {
+				s, ok := sep.(string)
+				if !ok {
+					return nil, fmt.Errorf("join separator must be a string")
+				}
+				parts := make([]string, 0, len(items))
+				for _, item := range items {
+					parts = append(parts, cellString(item))
+				}
+				out = append(out, strings.Join(parts, s))
+			}
+			return out, nil
+		}, nil
+	}
+	return nil, fmt.Errorf("query: unknown function %q", name)
+}
+
+// object parses {a, b: .x, "c d": .y} after the opening brace.
+func (p *queryParser) object() (filter, error) {
+	type entry struct {
+		key   string
+		value filter
+	}
+	var entries []entry
+	for !p.accept("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		t := p.peek()
+		if t.kind != tokIdent && t.kind != tokString {
+			return nil, fmt.Errorf("query: expected an object key")
+		}
+		p.pos++
+		value := fieldFilter(t.text)
+		if p.accept(":") {
+			var err error
+			if value, err = p.or(); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry{t.text, value})
+	}
+	return func(v any) ([]any, error) {
+		objs := []map[string]any{{}}
+		for _, e := range entries {
+			vals, err := e.value(v)
+			if err != nil {
+				return nil, err
+			}
+			var next []map[string]any
+			for _, obj := range objs {
+				for _, val := range vals {
+					cp := make(map[string]any, len(obj)+1)
+					for k, x := range obj {
+						cp[k] = x
+					}
+					cp[e.key] = val
+					next = append(next, cp)
+				}
+			}
+			objs = next
+		}
+		out := make([]any, len(objs))
+		for i, obj := range objs {
+			out[i] = obj
+		}
+		return out, nil
+	}, nil
+}
+
+func identity(v any) ([]any, error) { return []any{v}, nil }
+
+func constant(c any) filter {
+	return func(any) ([]any, error) { return []any{c}, nil }
+}
+
+func collect(f filter) filter {
+	return func(v any) ([]any, error) {
+		res, err := f(v)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = []any{}
+		}
+		return []any{res}, nil
+	}
+}
+
+func fieldFilter(name string) filter {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[name]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with %q", typeName(v), name)
+	}
+}
+
+func indexFilter(n int) filter {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			i := n
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[i]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with a number", typeName(v))
+	}
+}
+
+func iterate(v any) ([]any, error) {
+	switch v := v.(type) {
+	case []any:
+		return v, nil
+	case map[string]any:
+		keys := sortedKeys(v)
+		out := make([]any, len(keys))
+		for i, k := range keys {
+			out[i] = v[k]
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+}
+
+func lengthOf(v any) ([]any, error) {
+	switch v := v.(type) {
+	case nil:
+		return []any{0.0}, nil
+	case []any:
+		return []any{float64(len(v))}, nil
+	case map[string]any:
+		return []any{float64(len(v))}, nil
+	case string:
+		return []any{float64(len([]rune(v)))}, nil
+	}
+	return nil, fmt.Errorf("%s has no length", typeName(v))
+}
+
+func keysOf(v any) ([]any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s has no keys", typeName(v))
+	}
+	keys := sortedKeys(m)
+	out := make([]any, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return []any{out}, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truthy(v any) bool {
+	return v != nil && v != false
+}
+
+func compareValues(op string, a, b any) (any, error) {
+	switch op {
+	case "==":
+		return reflect.DeepEqual(a, b), nil
+	case "!=":
+		return !reflect.DeepEqual(a, b), nil
+	}
+	var cmp int
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %s", typeName(b))
+		}
+		cmp = compareOrdered(x, y)
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %s", typeName(b))
+		}
+		cmp = strings.Compare(x, y)
+	default:
+		return nil, fmt.Errorf("cannot order %s", typeName(a))
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyQuery(t *testing.T) {
+	var issues any
+	if err := json.Unmarshal([]byte(`[
+		{"number": 1, "title": "Bug", "state": "open", "user": {"login": "alice"}, "labels": [{"name": "bug"}, {"name": "p1"}]},
+		{"number": 2, "title": "Docs", "state": "closed", "user": {"login": "bob"}, "labels": []},
+		{"number": 3, "title": "Crash", "state": "open", "user": {"login": "bob"}, "labels": [{"name": "bug"}]}
+	]`), &issues); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".", ""},
+		{".[0].title", `"Bug"`},
+		{".[-1].number", `3`},
+		{".[].number", `[1,2,3]`},
+		{"length", `3`},
+		{`map(.user.login)`, `["alice","bob","bob"]`},
+		{`[.[] | select(.state == "open") | .number]`, `[1,3]`},
+		{`.[] | select(.number > 1 and .user.login == "bob") | .title`, `["Docs","Crash"]`},
+		{`map(select(.labels | length > 0)) | length`, `2`},
+		{`.[0] | {number, author: .user.login}`, `{"author":"alice","number":1}`},
+		{`.[0].labels | map(.name) | join(",")`, `"bug,p1"`},
+		{`.[0] | keys`, `["labels","number","state","title","user"]`},
+		{`first | ."title"`, `"Bug"`},
+		{`.[1] | .state != "open" | not`, `false`},
+		{`.[5]`, `null`},
+		{`.[] | select(.number == 9)`, `[]`},
+		{`.[] | select(.number == 1) | .title`, `["Bug"]`},
+		{`.[] | select(.user.login == "bob") | .title`, `["Docs","Crash"]`},
+		{`.[0].title, .[1].title`, `["Bug","Docs"]`},
+		{`.[2].labels[] | .name`, `["bug"]`},
+		{`[.[] | select(.number == 1)] | length`, `1`},
+		{`map(select(.number == 1)) | first | .title`, `"Bug"`},
+	}
+	for _, tt := range tests {
+		got, err := applyQuery(tt.expr, issues)
+		if err != nil {
+			t.Errorf("applyQuery(%q): %v", tt.expr, err)
+			continue
+		}
+		if tt.want == "" {
+			continue
+		}
+		data, _ := json.Marshal(got)
+		if string(data) != tt.want {
+			t.Errorf("applyQuery(%q) = %s, want %s", tt.expr, data, tt.want)
+		}
+	}
+}
+
+func TestCompileQuery_Errors(t *testing.T) {
+	for _, expr := range []string{"", ".[", "select(", `.a == "x`, "nosuch", ".a |", "{1: .a}"} {
+		if _, err := compileQuery(expr); err == nil {
+			t.Errorf("compileQuery(%q): expected error", expr)
+		}
+	}
+	if _, err := applyQuery(".title", []any{1.0}); err == nil {
+		t.Error("field of an array: expected error")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package cmd
+
+import "errors"
+
+// makeRaw is unsupported here; the shell falls back to plain line input.
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func isTerminal(fd int) bool { return false }
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resourceInfo is one entry of `--cli resources`.
+type resourceInfo struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MIMEType    string `json:"mime_type,omitempty"`
+	Template    bool   `json:"template"`
+}
+
+// listResources returns the static resources and resource templates
+// registered on s, sorted by URI.
+func listResources(s *server.MCPServer) ([]resourceInfo, error) {
+	var infos []resourceInfo
+	for _, r := range s.ListResources() {
+		infos = append(infos, resourceInfo{
+			URI:         r.Resource.URI,
+			Name:        r.Resource.Name,
+			Description: r.Resource.Description,
+			MIMEType:    r.Resource.MIMEType,
+		})
+	}
+
+	// The server has no accessor for templates; ask it the way a client would.
+	result, err := handleRPC(s, mcp.MethodResourcesTemplatesList, nil)
+	if err != nil {
+		return nil, err
+	}
+	templates, ok := result.(mcp.ListResourceTemplatesResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected resource template list %T", result)
+	}
+	for _, t := range templates.ResourceTemplates {
+		uri := ""
+		if t.URITemplate != nil {
+			uri = t.URITemplate.Raw()
+		}
+		infos = append(infos, resourceInfo{
+			URI:         uri,
+			Name:        t.Name,
+			Description: t.Description,
+			MIMEType:    t.MIMEType,
+			Template:    true,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].URI < infos[j].URI })
+	return infos, nil
+}
+
+// readResource resolves uri through the server's resources/read routing,
+// so template matching and query parameters behave as they do for clients.
+func readResource(s *server.MCPServer, uri string) ([]mcp.ResourceContents, error) {
+	result, err := handleRPC(s, mcp.MethodResourcesRead, map[string]any{"uri": uri})
+	if err != nil {
+		return nil, err
+	}
+	read, ok := result.(mcp.ReadResourceResult)
+	if !ok {
+		return nil, fmt.Errorf("unexpected resource read result %T", result)
+	}
+	return read.Contents, nil
+}
+
+// handleRPC sends one JSON-RPC request to s and returns its result.
+func handleRPC(s *server.MCPServer, method mcp.MCPMethod, params any) (any, error) {
+	msg, err := json.Marshal(map[string]any{"jsonrpc": mcp.JSONRPC_VERSION, "id": 1, "method": method, "params": params})
+	if err != nil {
+		return nil, err
+	}
+	switch resp := s.HandleMessage(context.Background(), msg).(type) {
+	case mcp.JSONRPCError:
+		return nil, errors.New(resp.Error.Message)
+	case mcp.JSONRPCResponse:
+		return resp.Result, nil
+	default:
+		return nil, fmt.Errorf("%s: unexpected response %T", method, resp)
+	}
+}
+
+// cliResources prints the registered resources and templates.
+func cliResources(w io.Writer, s *server.MCPServer, outputMode string) error {
+	infos, err := listResources(s)
+	if err != nil {
+		return err
+	}
+	if outputMode == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, template := range []bool{false, true} {
+		if template {
+			fmt.Fprintln(tw, "\nTEMPLATES:")
+		} else {
+			fmt.Fprintln(tw, "\nRESOURCES:")
+		}
+		for _, info := range infos {
+			if info.Template == template {
+				fmt.Fprintf(tw, "  %s\t%s\n", info.URI, info.Name)
+			}
+		}
+	}
+	fmt.Fprintln(tw)
+	return tw.Flush()
+}
+
+// cliRead resolves a resource URI and prints its content blocks: as JSON by
+// default, their text with --output=text, or the decoded JSON of the text
+// blocks through --query and the other formats.
+func cliRead(w io.Writer, s *server.MCPServer, uri string, output cliOutput) error {
+	contents, err := readResource(s, uri)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", uri, err)
+	}
+	if output.raw() && output.Format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(contents)
+	}
+
+	var values []any
+	for _, c := range contents {
+		switch c := c.(type) {
+		case mcp.TextResourceContents:
+			if output.raw() {
+				fmt.Fprintln(w, c.Text)
+				continue
+			}
+			if v, ok := decodeResult(c.Text); ok {
+				values = append(values, v)
+			} else {
+				values = append(values, c.Text)
+			}
+		case mcp.BlobResourceContents:
+			fmt.Fprintf(w, "<%s: %d bytes base64>\n", c.MIMEType, len(c.Blob))
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return output.write(w, values[0])
+	default:
+		return output.write(w, values)
+	}
+}
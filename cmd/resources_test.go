@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+)
+
+func resourceServer() *server.MCPServer {
+	s := server.NewMCPServer("test", "0.0.0")
+	operation.RegisterCoreResources(s)
+	return s
+}
+
+func TestCLIResources(t *testing.T) {
+	var out bytes.Buffer
+	if err := cliResources(&out, resourceServer(), "text"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"forgejo://server/capabilities",
+		"forgejo://repo/{owner}/{repo}/pr/{index}",
+		"forgejo://repo/{owner}/{repo}/issues{?state,labels,page,limit}",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("resources output lacks %s:\n%s", want, out.String())
+		}
+	}
+
+	infos, err := listResources(resourceServer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos[0].URI > infos[len(infos)-1].URI {
+		t.Error("resources are not sorted by URI")
+	}
+}
+
+func TestCLIRead(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "First", "")
+	srv.CreateIssue("alice", "demo", "Second", "")
+	s := resourceServer()
+
+	var out bytes.Buffer
+	if err := cliRead(&out, s, "forgejo://repo/alice/demo/issue/1", cliOutput{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"uri": "forgejo://repo/alice/demo/issue/1"`) {
+		t.Fatalf("content blocks = %s", out.String())
+	}
+
+	out.Reset()
+	o, _ := newCLIOutput("csv", "", "index,title")
+	if err := cliRead(&out, s, "forgejo://repo/alice/demo/issues?state=open", o); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "index,title\n2,Second\n1,First\n" {
+		t.Fatalf("issue list as csv = %q", out.String())
+	}
+
+	out.Reset()
+	o, _ = newCLIOutput("table", ".issues", "")
+	if err := cliRead(&out, s, "forgejo://repo/alice/demo/issues", o); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "INDEX  STATE  TITLE   AUTHOR") {
+		t.Fatalf("issue list as table = %q", out.String())
+	}
+
+	if err := cliRead(&out, s, "forgejo://nowhere/1", o); err == nil {
+		t.Fatal("unknown URI resolved")
+	}
+}
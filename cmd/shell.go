@@ -0,0 +1,426 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// shellCommands are the REPL's built-in commands.
+var shellCommands = map[string]string{
+	"help": "Show this help",
+	"list": "List available tools",
+	"use":  "Set the default repository: use owner/repo (use - clears it)",
+	"exit": "Leave the shell",
+}
+
+const shellHistoryLimit = 1000
+
+var (
+	errExitShell = errors.New("exit")
+	lastRef      = regexp.MustCompile(`\$last((?:\.[A-Za-z0-9_]+)*)`)
+)
+
+// cliShell is the state of one `--cli shell` session.
+type cliShell struct {
+	s     *server.MCPServer
+	out   io.Writer
+	owner string
+	repo  string
+	// last is the decoded result of the previous successful call, for
+	// $last references.
+	last any
+
+	repos       []string
+	reposLoaded bool
+}
+
+func newCLIShell(s *server.MCPServer, out io.Writer) *cliShell {
+	return &cliShell{s: s, out: out}
+}
+
+func (sh *cliShell) prompt() string {
+	if sh.owner != "" {
+		return fmt.Sprintf("forgejo %s/%s> ", sh.owner, sh.repo)
+	}
+	return "forgejo> "
+}
+
+// run reads commands until EOF or exit. On a terminal it edits lines with
+// history and tab completion; otherwise it reads plain lines, so a script
+// can be piped in.
+func (sh *cliShell) run(in *os.File) error {
+	fd := int(in.Fd())
+	if !isTerminal(fd) {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if err := sh.execLine(scanner.Text()); errors.Is(err, errExitShell) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	editor := newLineEditor(in, sh.out, sh.complete)
+	historyPath := shellHistoryPath()
+	editor.history = loadHistory(historyPath)
+	defer func() { saveHistory(historyPath, editor.history) }()
+
+	fmt.Fprintln(sh.out, "Forgejo MCP shell. Type 'help' for commands, Tab to complete, Ctrl-D to exit.")
+	for {
+		restore, err := makeRaw(fd)
+		if err != nil {
+			return err
+		}
+		line, err := editor.readLine(sh.prompt())
+		restore()
+		switch {
+		case errors.Is(err, errInterrupted):
+			continue
+		case errors.Is(err, io.EOF):
+			return nil
+		case err != nil:
+			return err
+		}
+		editor.addHistory(line)
+		if err := sh.execLine(line); errors.Is(err, errExitShell) {
+			return nil
+		}
+	}
+}
+
+// execLine runs one shell line, printing results and errors. It returns
+// errExitShell when the session should end.
+func (sh *cliShell) execLine(line string) error {
+	words, err := splitWords(line)
+	if err != nil {
+		fmt.Fprintf(sh.out, "Error: %v\n", err)
+		return nil
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return errExitShell
+	case "help":
+		sh.help()
+	case "list":
+		_ = cliList(sh.out, sh.s, "text")
+	case "use":
+		sh.use(words[1:])
+	default:
+		if err := sh.call(words[0], words[1:]); err != nil {
+			fmt.Fprintf(sh.out, "Error: %v\n", err)
+		}
+	}
+	return nil
+}
+
+func (sh *cliShell) help() {
+	fmt.Fprintln(sh.out, "Commands:")
+	names := make([]string, 0, len(shellCommands))
+	for name := range shellCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(sh.out, "  %-8s %s\n", name, shellCommands[name])
+	}
+	fmt.Fprintln(sh.out, "  <tool> [--flag value ...]   Call a tool; <tool> --help lists its flags")
+	fmt.Fprintln(sh.out)
+	fmt.Fprintln(sh.out, "$last, $last.field or $last.0.field in an argument refers to the previous result.")
+}
+
+func (sh *cliShell) use(args []string) {
+	switch {
+	case len(args) == 0:
+		if sh.owner == "" {
+			fmt.Fprintln(sh.out, "No default repository.")
+		} else {
+			fmt.Fprintf(sh.out, "Default repository: %s/%s\n", sh.owner, sh.repo)
+		}
+	case args[0] == "-":
+		sh.owner, sh.repo = "", ""
+	default:
+		owner, repo, ok := strings.Cut(args[0], "/")
+		if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+			fmt.Fprintln(sh.out, "Error: usage: use owner/repo")
+			return
+		}
+		sh.owner, sh.repo = owner, repo
+	}
+}
+
+// call parses tool flags as --cli does, fills owner/repo from `use`, and
+// prints the result.
+func (sh *cliShell) call(name string, words []string) error {
+	st := sh.s.GetTool(name)
+	if st == nil {
+		return fmt.Errorf("unknown command %q (type 'help')", name)
+	}
+	for i, w := range words {
+		expanded, err := sh.expandLast(w)
+		if err != nil {
+			return err
+		}
+		words[i] = expanded
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(sh.out)
+	argsFlag := fs.String("args", "", "JSON arguments for tool invocation")
+	outputFlag := fs.String("output", "json", "Output format: json, text, table, yaml, csv or ndjson")
+	queryFlag := fs.String("query", "", "jq-style expression applied to the result")
+	columnsFlag := fs.String("columns", "", "Comma-separated columns for table and csv output")
+	helpFlag := fs.Bool("help", false, "Show tool parameter help")
+	flagValues := map[string]any{}
+	defineToolFlags(fs, st.Tool, flagValues)
+	fs.Usage = func() {}
+	if err := fs.Parse(words); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *helpFlag {
+		return cliHelp(sh.out, sh.s, name)
+	}
+	output, err := newCLIOutput(*outputFlag, *queryFlag, *columnsFlag)
+	if err != nil {
+		return err
+	}
+
+	args, err := mergeArgs(*argsFlag, flagValues)
+	if err != nil {
+		return err
+	}
+	if sh.owner != "" {
+		props := st.Tool.InputSchema.Properties
+		if _, ok := props["owner"]; ok && args["owner"] == nil {
+			args["owner"] = sh.owner
+		}
+		if _, ok := props["repo"]; ok && args["repo"] == nil {
+			args["repo"] = sh.repo
+		}
+	}
+	if err := checkRequired(st.Tool, args); err != nil {
+		return err
+	}
+
+	result, err := st.Handler(context.Background(), mcp.CallToolRequest{
+		Params: mcp.CallToolParams{Name: name, Arguments: args},
+	})
+	if err != nil {
+		return err
+	}
+	text := resultText(result)
+	if result.IsError {
+		return errors.New(text)
+	}
+	value, isJSON := decodeResult(text)
+	if isJSON {
+		sh.last = value
+	} else {
+		sh.last = text
+	}
+	if !isJSON || (output.raw() && output.Format == "text") {
+		fmt.Fprintln(sh.out, text)
+		return nil
+	}
+	return output.write(sh.out, value)
+}
+
+// expandLast replaces $last references in word with values from the
+// previous result. Whole objects and arrays are inserted as JSON.
+func (sh *cliShell) expandLast(word string) (string, error) {
+	var expandErr error
+	out := lastRef.ReplaceAllStringFunc(word, func(ref string) string {
+		if sh.last == nil {
+			expandErr = errors.New("$last: no previous result")
+			return ref
+		}
+		value := sh.last
+		path := strings.TrimPrefix(ref, "$last")
+		for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+			if key == "" {
+				continue
+			}
+			switch v := value.(type) {
+			case map[string]any:
+				next, ok := v[key]
+				if !ok {
+					expandErr = fmt.Errorf("%s: no field %q", ref, key)
+					return ref
+				}
+				value = next
+			case []any:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(v) {
+					expandErr = fmt.Errorf("%s: index %q out of range", ref, key)
+					return ref
+				}
+				value = v[i]
+			default:
+				expandErr = fmt.Errorf("%s: cannot select %q from a scalar", ref, key)
+				return ref
+			}
+		}
+		return formatValue(value)
+	})
+	return out, expandErr
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// complete is the line editor's completer: shell commands and tools first,
+// repositories after `use`, tool flags and values otherwise.
+func (sh *cliShell) complete(line string) (int, []string) {
+	start := strings.LastIndexByte(line, ' ') + 1
+	words := append(strings.Fields(line[:start]), line[start:])
+	cur := words[len(words)-1]
+
+	var cands []candidate
+	switch {
+	case len(words) == 1:
+		for name, desc := range shellCommands {
+			cands = append(cands, candidate{name, desc})
+		}
+		for name := range sh.s.ListTools() {
+			cands = append(cands, candidate{name, ""})
+		}
+		cands = filterPrefix(cands, cur)
+	case words[0] == "use" && len(words) == 2:
+		for _, full := range sh.myRepos() {
+			cands = append(cands, candidate{full, ""})
+		}
+		cands = filterPrefix(cands, cur)
+	default:
+		cands = completeWords(sh.s, words, sh.myRepos)
+	}
+	out := make([]string, 0, len(cands))
+	for _, c := range cands {
+		out = append(out, c.Value)
+	}
+	return start, out
+}
+
+// myRepos caches the caller's repositories for the session.
+func (sh *cliShell) myRepos() []string {
+	if !sh.reposLoaded {
+		sh.repos = myRepos(sh.s)
+		sh.reposLoaded = true
+	}
+	return sh.repos
+}
+
+// splitWords splits a line into words the way a POSIX shell would for
+// single quotes, double quotes and backslash escapes.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// shellHistoryPath is where history persists between sessions.
+func shellHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "forgejo-mcp", "shell_history")
+}
+
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.FieldsFunc(string(data), func(r rune) bool { return r == '\n' })
+}
+
+// saveHistory keeps the newest shellHistoryLimit entries. History is a
+// convenience, so failures are ignored.
+func saveHistory(path string, history []string) {
+	if path == "" || len(history) == 0 {
+		return
+	}
+	if len(history) > shellHistoryLimit {
+		history = history[len(history)-shellHistoryLimit:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(path, []byte(strings.Join(history, "\n")+"\n"), 0o600)
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`list_repo_issues --owner goern`, []string{"list_repo_issues", "--owner", "goern"}},
+		{`create_issue --title "two words" --body 'it''s'`, []string{"create_issue", "--title", "two words", "--body", "its"}},
+		{`a\ b "" c`, []string{"a b", "", "c"}},
+		{"   ", nil},
+	}
+	for _, tt := range tests {
+		got, err := splitWords(tt.line)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("splitWords(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+		}
+	}
+	if _, err := splitWords(`say "unterminated`); err == nil {
+		t.Error("unterminated quote accepted")
+	}
+}
+
+func TestExpandLast(t *testing.T) {
+	sh := newCLIShell(completionServer(), io.Discard)
+	if _, err := sh.expandLast("$last"); err == nil {
+		t.Fatal("$last without a result expanded")
+	}
+	sh.last = []any{map[string]any{"number": 7.0, "title": "Bug", "labels": []any{"a"}}}
+
+	for word, want := range map[string]string{
+		"$last.0.number":               "7",
+		"--index=$last.0.number":       "--index=7",
+		"$last.0.title-$last.0.number": "Bug-7",
+		"$last.0.labels":               `["a"]`,
+		"plain":                        "plain",
+	} {
+		if got, err := sh.expandLast(word); err != nil || got != want {
+			t.Errorf("expandLast(%q) = %q, %v; want %q", word, got, err, want)
+		}
+	}
+	for _, word := range []string{"$last.1.number", "$last.0.missing", "$last.0.number.x"} {
+		if _, err := sh.expandLast(word); err == nil {
+			t.Errorf("expandLast(%q): expected error", word)
+		}
+	}
+}
+
+func TestShell_UseAndLastAcrossCalls(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "First", "")
+	srv.CreateIssue("alice", "demo", "Second", "")
+
+	var out bytes.Buffer
+	sh := newCLIShell(completionServer(), &out)
+	for _, line := range []string{
+		"use alice/demo",
+		"list_repo_issues --page 1 --limit 10",
+		"get_issue_by_index --index $last.1.number",
+	} {
+		if err := sh.execLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Contains(out.String(), "Error") {
+		t.Fatalf("shell reported an error:\n%s", out.String())
+	}
+	issue, ok := sh.last.(map[string]any)
+	if !ok || issue["title"] != "First" {
+		t.Fatalf("last = %#v, want the issue selected through $last", sh.last)
+	}
+	if sh.prompt() != "forgejo alice/demo> " {
+		t.Fatalf("prompt = %q", sh.prompt())
+	}
+
+	out.Reset()
+	if err := sh.execLine("get_issue_by_index"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "--index") {
+		t.Fatalf("missing required argument not reported: %s", out.String())
+	}
+	out.Reset()
+	if err := sh.execLine("merge_pull_request --help"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Tool: merge_pull_request") {
+		t.Fatalf("tool help did not go to the shell's writer: %q", out.String())
+	}
+	out.Reset()
+	if err := sh.execLine("list"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "get_issue_by_index") {
+		t.Fatalf("tool list did not go to the shell's writer: %q", out.String())
+	}
+	if err := sh.execLine("exit"); !errors.Is(err, errExitShell) {
+		t.Fatalf("exit: err = %v", err)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	complete := func(line string) (int, []string) {
+		start := strings.LastIndexByte(line, ' ') + 1
+		var out []string
+		for _, c := range []string{"list", "list_repo_issues", "use"} {
+			if strings.HasPrefix(c, line[start:]) {
+				out = append(out, c)
+			}
+		}
+		return start, out
+	}
+	read := func(e *lineEditor) string {
+		t.Helper()
+		line, err := e.readLine("> ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return line
+	}
+
+	e := newLineEditor(strings.NewReader("u\t\r"+"list_r\x7f\x7f\t_\t\r"), io.Discard, complete)
+	if got := read(e); got != "use " {
+		t.Fatalf("unique completion = %q", got)
+	}
+	if got := read(e); got != "list_repo_issues " {
+		t.Fatalf("common-prefix completion = %q", got)
+	}
+
+	// Up arrow recalls history; Ctrl-A then typing inserts at the start.
+	e = newLineEditor(strings.NewReader("\x1b[A\x01x\r"+"\x1b[A\x1b[A\x1b[B\r"), io.Discard, nil)
+	e.history = []string{"one", "two"}
+	if got := read(e); got != "xtwo" {
+		t.Fatalf("history edit = %q", got)
+	}
+	if got := read(e); got != "two" {
+		t.Fatalf("history navigation = %q", got)
+	}
+
+	e = newLineEditor(strings.NewReader("abc\x03\x04"), io.Discard, nil)
+	if _, err := e.readLine("> "); !errors.Is(err, errInterrupted) {
+		t.Fatalf("Ctrl-C: err = %v", err)
+	}
+	if _, err := e.readLine("> "); !errors.Is(err, io.EOF) {
+		t.Fatalf("Ctrl-D: err = %v", err)
+	}
+}
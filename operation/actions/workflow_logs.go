@@ -70,6 +70,13 @@ type actionJobLogResult struct {
 	NextOffset       *int64 `json:"next_offset,omitempty"`
 }
 
+// ToolCapabilities maps version-gated tools to the server capability they
+// need. Run drops these tools when the connected server lacks the capability.
+var ToolCapabilities = map[string]forgejo.Capability{
+	ListActionRunJobsToolName: forgejo.CapabilityActionRunJobs,
+	GetActionJobLogsToolName:  forgejo.CapabilityActionJobLogs,
+}
+
 var (
 	ListActionRunJobsTool = mcp.NewTool(
 		ListActionRunJobsToolName,
@@ -114,6 +121,10 @@ func ListActionRunJobsFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.Cal
 		return to.ErrorResult(err)
 	}
 
+	if err := forgejo.RequireCapability(ctx, forgejo.CapabilityActionRunJobs); err != nil {
+		return to.ErrorResult(fmt.Errorf("list action run jobs: %w", err))
+	}
+
 	path := forgejo.APIPath("repos", owner, repo, "actions", "runs", runID, "jobs")
 	allJobs := make([]actionRunJob, 0)
 	if err := forgejo.DoJSON(ctx, http.MethodGet, path, nil, &allJobs); err != nil {
@@ -176,6 +187,10 @@ func GetActionJobLogsFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 		byteRange = fmt.Sprintf("bytes=%d-%d", offset, offset+int64(maxBytes)-1)
 	}
 
+	if err := forgejo.RequireCapability(ctx, forgejo.CapabilityActionJobLogs); err != nil {
+		return to.ErrorResult(fmt.Errorf("get action job logs: %w", err))
+	}
+
 	path := forgejo.APIPath("repos", owner, repo, "actions", "jobs", jobID, "logs")
 	if attempt > 0 {
 		query := url.Values{}
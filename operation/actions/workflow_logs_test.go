@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
 
 	"github.com/mark3labs/mcp-go/mcp"
 )
@@ -239,3 +240,30 @@ func TestGetActionJobLogsFn_RejectsInconsistentContentRange(t *testing.T) {
 		t.Fatalf("expected Content-Range error, got %v", err)
 	}
 }
+
+func TestActionJobTools_UnsupportedOnOlderForgejo(t *testing.T) {
+	_, capture := setupActionAPIServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"11.0.0+gitea-1.22.0"}`))
+	})
+	forgejo.ResetClientForTesting()
+	t.Cleanup(forgejo.ResetClientForTesting)
+
+	cases := []struct {
+		name string
+		fn   func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error)
+		args map[string]interface{}
+	}{
+		{"list jobs", ListActionRunJobsFn, map[string]interface{}{"owner": "o", "repo": "r", "run_id": float64(42)}},
+		{"job logs", GetActionJobLogsFn, map[string]interface{}{"owner": "o", "repo": "r", "job_id": float64(7)}},
+	}
+	for _, tc := range cases {
+		_, err := tc.fn(context.Background(), newCallToolRequest(tc.args))
+		if err == nil || !strings.Contains(err.Error(), "unsupported on this server (v11.0.0+gitea-1.22.0)") {
+			t.Fatalf("%s: expected unsupported error, got %v", tc.name, err)
+		}
+		if capture.path != "/api/v1/version" {
+			t.Fatalf("%s: gated tool reached %s", tc.name, capture.path)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	BulkUpdateIssuesToolName = "bulk_update_issues"
+)
+
+const (
+	bulkDefaultMaxItems    = 100
+	bulkMaxItems           = 500
+	bulkDefaultConcurrency = 4
+	bulkMaxConcurrency     = 10
+	bulkPageSize           = 50
+)
+
+// Per-item outcomes of bulk_update_issues.
+const (
+	bulkWouldUpdate = "would_update"
+	bulkUnchanged   = "unchanged"
+	bulkUpdated     = "updated"
+	bulkFailed      = "failed"
+)
+
+var (
+	BulkUpdateIssuesTool = mcp.NewTool(
+		BulkUpdateIssuesToolName,
+		mcp.WithDescription("Apply the same change to many issues of one repository. Select issues with indexes, or with the list_repo_issues filters (state, type, labels, milestones, q, created_by, assigned_by, mentioned_by, since, before). "+
+			"Operations: add_labels, remove_labels, milestone, assignees, state, comment. "+
+			"The call is a dry run by default: it returns the per-issue changes and a confirm_token. Re-run with dry_run=false and that confirm_token to apply; the token is rejected if the selection, the selected issues or the operations changed since the preview. "+
+			"Issues are updated with bounded concurrency and each gets its own result, so one failure does not stop the others."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("indexes", mcp.Description("Explicit issue indexes (comma-separated); cannot be combined with the filters")),
+		mcp.WithString("state", mcp.Description("Filter: state (open|closed|all), default open"), mcp.Enum("open", "closed", "all")),
+		mcp.WithString("type", mcp.Description("Filter: type (issues|pulls)"), mcp.Enum("issues", "pulls")),
+		mcp.WithString("labels", mcp.Description("Filter: labels (comma-separated)")),
+		mcp.WithString("milestones", mcp.Description("Filter: milestone names/IDs (comma-separated)")),
+		mcp.WithString("q", mcp.Description("Filter: "+params.Keyword)),
+		mcp.WithString("created_by", mcp.Description("Filter: creator username")),
+		mcp.WithString("assigned_by", mcp.Description("Filter: assignee username")),
+		mcp.WithString("mentioned_by", mcp.Description("Filter: mentioned username")),
+		mcp.WithString("since", mcp.Description("Filter: updated at or after (RFC3339)")),
+		mcp.WithString("before", mcp.Description("Filter: updated before (RFC3339), e.g. for issues untouched for six months")),
+		mcp.WithString("add_labels", mcp.Description("Labels to add: names or IDs (comma-separated)")),
+		mcp.WithString("remove_labels", mcp.Description("Labels to remove: names or IDs (comma-separated)")),
+		mcp.WithString("milestone", mcp.Description("Milestone title or ID to set; \"none\" clears it")),
+		mcp.WithString("assignees", mcp.Description("Assignee usernames (comma-separated); replaces the current assignees")),
+		mcp.WithString("set_state", mcp.Description("New state"), mcp.Enum("open", "closed")),
+		mcp.WithString("comment", mcp.Description("Comment to add to every selected issue")),
+		mcp.WithBoolean("dry_run", mcp.Description("Preview only (default true)"), mcp.DefaultBool(true)),
+		mcp.WithString("confirm_token", mcp.Description("confirm_token from the dry run; required when dry_run=false")),
+		mcp.WithNumber("max_items", mcp.Description(fmt.Sprintf("Refuse if the selection exceeds this many issues (default %d, at most %d)", bulkDefaultMaxItems, bulkMaxItems)), mcp.DefaultNumber(bulkDefaultMaxItems)),
+		mcp.WithNumber("concurrency", mcp.Description(fmt.Sprintf("Issues updated in parallel (default %d, at most %d)", bulkDefaultConcurrency, bulkMaxConcurrency)), mcp.DefaultNumber(bulkDefaultConcurrency)),
+	)
+)
+
+// bulkOps are the resolved operations applied to every selected issue.
+// nil fields are left alone.
+type bulkOps struct {
+	AddLabels    []int64  `json:"add_labels,omitempty"`
+	RemoveLabels []int64  `json:"remove_labels,omitempty"`
+	Milestone    *int64   `json:"milestone,omitempty"`
+	Assignees    []string `json:"assignees,omitempty"`
+	State        string   `json:"state,omitempty"`
+	Comment      string   `json:"comment,omitempty"`
+}
+
+func (o *bulkOps) empty() bool {
+	return len(o.AddLabels) == 0 && len(o.RemoveLabels) == 0 && o.Milestone == nil &&
+		o.Assignees == nil && o.State == "" && o.Comment == ""
+}
+
+type bulkItemResult struct {
+	Index   int64    `json:"index"`
+	Title   string   `json:"title"`
+	Status  string   `json:"status"`
+	Changes []string `json:"changes,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+type bulkResult struct {
+	DryRun       bool             `json:"dry_run"`
+	Selected     int              `json:"selected"`
+	ConfirmToken string           `json:"confirm_token,omitempty"`
+	Updated      int              `json:"updated"`
+	Unchanged    int              `json:"unchanged"`
+	Failed       int              `json:"failed"`
+	Items        []bulkItemResult `json:"items"`
+}
+
+// bulkTokenKey signs confirm tokens. It lives only as long as the process,
+// so a token cannot be computed without a dry run, nor reused after a restart.
+var bulkTokenKey = func() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}()
+
+// bulkConfirmToken fingerprints the selection, the issues' last update and
+// the operations, so applying requires a preview of exactly what will
+// change: an issue edited after the dry run invalidates its token.
+func bulkConfirmToken(owner, repo string, issues []*forgejo_sdk.Issue, ops bulkOps) string {
+	type selected struct {
+		Index   int64     `json:"index"`
+		Updated time.Time `json:"updated"`
+	}
+	items := make([]selected, 0, len(issues))
+	for _, issue := range issues {
+		items = append(items, selected{issue.Index, issue.Updated})
+	}
+	slices.SortFunc(items, func(a, b selected) int { return cmp.Compare(a.Index, b.Index) })
+	data, _ := json.Marshal(struct {
+		Repo   string     `json:"repo"`
+		Issues []selected `json:"issues"`
+		Ops    bulkOps    `json:"ops"`
+	}{strings.ToLower(owner + "/" + repo), items, ops})
+	mac := hmac.New(sha256.New, bulkTokenKey)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// bulkSelect returns the issues named by indexes, or every issue matching
+// opt. It fails rather than truncate when more than maxItems match.
+func bulkSelect(client *forgejo_sdk.Client, owner, repo string, indexes []int64, opt forgejo_sdk.ListIssueOption, maxItems int) ([]*forgejo_sdk.Issue, error) {
+	if len(indexes) > 0 {
+		if len(indexes) > maxItems {
+			return nil, fmt.Errorf("%d issues selected, more than max_items=%d", len(indexes), maxItems)
+		}
+		issues := make([]*forgejo_sdk.Issue, 0, len(indexes))
+		for _, index := range indexes {
+			issue, _, err := client.GetIssue(owner, repo, index)
+			if err != nil {
+				return nil, fmt.Errorf("get issue #%d: %w", index, err)
+			}
+			issues = append(issues, issue)
+		}
+		return issues, nil
+	}
+
+	var issues []*forgejo_sdk.Issue
+	for page := 1; ; page++ {
+		opt.ListOptions = forgejo_sdk.ListOptions{Page: page, PageSize: bulkPageSize}
+		batch, resp, err := client.ListRepoIssues(owner, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("list issues: %w", err)
+		}
+		issues = append(issues, batch...)
+		if len(issues) > maxItems {
+			return nil, fmt.Errorf("more than max_items=%d issues match; narrow the filters or raise max_items", maxItems)
+		}
+		// The server may cap the page below bulkPageSize; only its Link
+		// header says whether more issues match.
+		if len(batch) == 0 || !hasMore(resp) {
+			return issues, nil
+		}
+	}
+}
+
+// bulkPlan computes what ops would change on issue. The returned edit is
+// nil when no field of the issue itself changes.
+func bulkPlan(issue *forgejo_sdk.Issue, ops bulkOps, labelNames map[int64]string) (changes []string, addLabels, removeLabels []int64, edit *forgejo_sdk.EditIssueOption) {
+	has := func(id int64) bool {
+		return slices.ContainsFunc(issue.Labels, func(l *forgejo_sdk.Label) bool { return l.ID == id })
+	}
+	var added, removed []string
+	for _, id := range ops.AddLabels {
+		if !has(id) && !slices.Contains(addLabels, id) {
+			addLabels = append(addLabels, id)
+			added = append(added, labelNames[id])
+		}
+	}
+	for _, id := range ops.RemoveLabels {
+		if has(id) && !slices.Contains(removeLabels, id) {
+			removeLabels = append(removeLabels, id)
+			removed = append(removed, labelNames[id])
+		}
+	}
+	if len(added) > 0 {
+		changes = append(changes, "add labels: "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, "remove labels: "+strings.Join(removed, ", "))
+	}
+
+	opt := forgejo_sdk.EditIssueOption{}
+	changed := false
+	if ops.Milestone != nil {
+		current, currentTitle := int64(0), "none"
+		if issue.Milestone != nil {
+			current, currentTitle = issue.Milestone.ID, issue.Milestone.Title
+		}
+		if current != *ops.Milestone {
+			opt.Milestone = ops.Milestone
+			changed = true
+			changes = append(changes, fmt.Sprintf("milestone: %s → %s", currentTitle, milestoneLabel(*ops.Milestone)))
+		}
+	}
+	if ops.Assignees != nil {
+		current := make([]string, 0, len(issue.Assignees))
+		for _, u := range issue.Assignees {
+			current = append(current, u.UserName)
+		}
+		want := slices.Clone(ops.Assignees)
+		slices.Sort(current)
+		slices.Sort(want)
+		if !slices.EqualFunc(current, want, strings.EqualFold) {
+			opt.Assignees = ops.Assignees
+			changed = true
+			changes = append(changes, fmt.Sprintf("assignees: [%s] → [%s]", strings.Join(current, ", "), strings.Join(want, ", ")))
+		}
+	}
+	if ops.State != "" && string(issue.State) != ops.State {
+		state := forgejo_sdk.StateType(ops.State)
+		opt.State = &state
+		changed = true
+		changes = append(changes, fmt.Sprintf("state: %s → %s", issue.State, ops.State))
+	}
+	if ops.Comment != "" {
+		changes = append(changes, "add comment")
+	}
+	if changed {
+		edit = &opt
+	}
+	return changes, addLabels, removeLabels, edit
+}
+
+func milestoneLabel(id int64) string {
+	if id == 0 {
+		return "none"
+	}
+	return "#" + strconv.FormatInt(id, 10)
+}
+
+// bulkApply performs the planned steps for one issue, stopping at the first
+// failure.
+func bulkApply(client *forgejo_sdk.Client, owner, repo string, issue *forgejo_sdk.Issue, ops bulkOps, addLabels, removeLabels []int64, edit *forgejo_sdk.EditIssueOption) error {
+	if len(addLabels) > 0 {
+		if _, _, err := client.AddIssueLabels(owner, repo, issue.Index, forgejo_sdk.IssueLabelsOption{Labels: addLabels}); err != nil {
+			return fmt.Errorf("add labels: %w", err)
+		}
+	}
+	for _, id := range removeLabels {
+		if _, err := client.DeleteIssueLabel(owner, repo, issue.Index, id); err != nil {
+			return fmt.Errorf("remove label %d: %w", id, err)
+		}
+	}
+	if edit != nil {
+		if _, _, err := client.EditIssue(owner, repo, issue.Index, *edit); err != nil {
+			return fmt.Errorf("edit issue: %w", err)
+		}
+	}
+	if ops.Comment != "" {
+		if _, _, err := client.CreateIssueComment(owner, repo, issue.Index, forgejo_sdk.CreateIssueCommentOption{Body: ops.Comment}); err != nil {
+			return fmt.Errorf("add comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// bulkLabelOps resolves a label list and records a display name per ID.
+func bulkLabelOps(ctx context.Context, client *forgejo_sdk.Client, owner, repo, labels string, names map[int64]string) ([]int64, error) {
+	if labels == "" {
+		return nil, nil
+	}
+	ids, err := resolveLabelIDs(ctx, client, owner, repo, labels)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range splitCSV(labels) {
+		names[ids[i]] = entry
+	}
+	return ids, nil
+}
+
+func BulkUpdateIssuesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called BulkUpdateIssuesFn")
+	args := req.GetArguments()
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return strings.TrimSpace(s)
+	}
+	owner, repo := str("owner"), str("repo")
+	dryRun := true
+	if v, ok := args["dry_run"].(bool); ok {
+		dryRun = v
+	}
+	maxItems := bulkDefaultMaxItems
+	if v, ok := to.Float64Ok(args["max_items"]); ok && v > 0 {
+		maxItems = min(int(v), bulkMaxItems)
+	}
+	concurrency := bulkDefaultConcurrency
+	if v, ok := to.Float64Ok(args["concurrency"]); ok && v > 0 {
+		concurrency = min(int(v), bulkMaxConcurrency)
+	}
+
+	// Selector.
+	var indexes []int64
+	for _, s := range splitCSV(str("indexes")) {
+		n, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("invalid issue index %q", s))
+		}
+		if !slices.Contains(indexes, n) {
+			indexes = append(indexes, n)
+		}
+	}
+	filters := []string{"state", "type", "labels", "milestones", "q", "created_by", "assigned_by", "mentioned_by", "since", "before"}
+	var used []string
+	for _, f := range filters {
+		if str(f) != "" {
+			used = append(used, f)
+		}
+	}
+	if len(indexes) > 0 && len(used) > 0 {
+		return to.ErrorResult(fmt.Errorf("indexes cannot be combined with filters (%s)", strings.Join(used, ", ")))
+	}
+	state := str("state")
+	if state == "" {
+		state = "open"
+	}
+	listOpt := forgejo_sdk.ListIssueOption{
+		State:       forgejo_sdk.StateType(state),
+		Type:        forgejo_sdk.IssueType(str("type")),
+		Labels:      splitCSV(str("labels")),
+		KeyWord:     str("q"),
+		CreatedBy:   str("created_by"),
+		AssignedBy:  str("assigned_by"),
+		MentionedBy: str("mentioned_by"),
+	}
+	if m := str("milestones"); m != "" {
+		listOpt.Milestones = splitCSV(m)
+	}
+	for key, dst := range map[string]*time.Time{"since": &listOpt.Since, "before": &listOpt.Before} {
+		if v := str(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return to.ErrorResult(fmt.Errorf("invalid %s format (expected RFC3339): %w", key, err))
+			}
+			*dst = t
+		}
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	// Operations.
+	var ops bulkOps
+	labelNames := map[int64]string{}
+	if ops.AddLabels, err = bulkLabelOps(ctx, client, owner, repo, str("add_labels"), labelNames); err != nil {
+		return to.ErrorResult(err)
+	}
+	if ops.RemoveLabels, err = bulkLabelOps(ctx, client, owner, repo, str("remove_labels"), labelNames); err != nil {
+		return to.ErrorResult(err)
+	}
+	switch m := str("milestone"); {
+	case strings.EqualFold(m, "none"):
+		ops.Milestone = new(int64)
+	case m != "":
+		id, err := resolveMilestoneID(client, owner, repo, m)
+		if err != nil {
+			return to.ErrorResult(err)
+		}
+		ops.Milestone = &id
+	}
+	if a, ok := args["assignees"].(string); ok && strings.TrimSpace(a) != "" {
+		ops.Assignees = splitCSV(a)
+	}
+	ops.State = str("set_state")
+	ops.Comment = str("comment")
+	if ops.empty() {
+		return to.ErrorResult(fmt.Errorf("no operation given: set add_labels, remove_labels, milestone, assignees, set_state or comment"))
+	}
+
+	issues, err := bulkSelect(client, owner, repo, indexes, listOpt, maxItems)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	token := bulkConfirmToken(owner, repo, issues, ops)
+	if !dryRun {
+		switch confirm := str("confirm_token"); {
+		case confirm == "":
+			return to.ErrorResult(fmt.Errorf("dry_run=false requires the confirm_token of a dry run"))
+		case confirm != token:
+			return to.ErrorResult(fmt.Errorf("confirm_token does not match: the selection, its issues or the operations changed since the dry run; preview again"))
+		}
+	}
+
+	result := bulkResult{DryRun: dryRun, Selected: len(issues), Items: make([]bulkItemResult, len(issues))}
+	if dryRun {
+		result.ConfirmToken = token
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, issue := range issues {
+		changes, addLabels, removeLabels, edit := bulkPlan(issue, ops, labelNames)
+		item := bulkItemResult{Index: issue.Index, Title: issue.Title, Changes: changes}
+		switch {
+		case len(changes) == 0:
+			item.Status = bulkUnchanged
+		case dryRun:
+			item.Status = bulkWouldUpdate
+		default:
+			wg.Add(1)
+			go func(i int, issue *forgejo_sdk.Issue, item bulkItemResult) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				if err := bulkApply(client, owner, repo, issue, ops, addLabels, removeLabels, edit); err != nil {
+					item.Status, item.Error = bulkFailed, err.Error()
+				} else {
+					item.Status = bulkUpdated
+				}
+				result.Items[i] = item
+			}(i, issue, item)
+			continue
+		}
+		result.Items[i] = item
+	}
+	wg.Wait()
+
+	sort.SliceStable(result.Items, func(i, j int) bool { return result.Items[i].Index < result.Items[j].Index })
+	for _, item := range result.Items {
+		switch item.Status {
+		case bulkUpdated:
+			result.Updated++
+		case bulkUnchanged:
+			result.Unchanged++
+		case bulkFailed:
+			result.Failed++
+		}
+	}
+	return to.TextResult(result)
+}
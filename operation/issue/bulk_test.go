@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+func bulkServer(t *testing.T) *forgejotest.Server {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateLabel("alice", "demo", "wontfix", "aaaaaa")
+	srv.CreateLabel("alice", "demo", "stale", "bbbbbb")
+	srv.CreateMilestone("alice", "demo", "1.3")
+	for _, title := range []string{"one", "two", "three"} {
+		srv.CreateIssue("alice", "demo", title, "")
+	}
+	return srv
+}
+
+func bulkCall(t *testing.T, args map[string]any) bulkResult {
+	t.Helper()
+	args["owner"], args["repo"] = "alice", "demo"
+	res, err := BulkUpdateIssuesFn(context.Background(), makeReq(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bulkResult
+	resultJSON(t, res, &out)
+	return out
+}
+
+func getIssue(t *testing.T, index int64) forgejo_sdk.Issue {
+	t.Helper()
+	res, err := GetIssueByIndexFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo", "index": float64(index)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issue forgejo_sdk.Issue
+	resultJSON(t, res, &issue)
+	return issue
+}
+
+func TestBulkUpdateIssues_PreviewThenApply(t *testing.T) {
+	bulkServer(t)
+	ops := func() map[string]any {
+		return map[string]any{
+			"indexes":    "1, #3",
+			"add_labels": "wontfix",
+			"milestone":  "1.3",
+			"set_state":  "closed",
+			"comment":    "Closing as won't fix.",
+		}
+	}
+
+	preview := bulkCall(t, ops())
+	if !preview.DryRun || preview.Selected != 2 || preview.ConfirmToken == "" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	want := []string{"add labels: wontfix", "milestone: none → #", "state: open → closed", "add comment"}
+	for _, item := range preview.Items {
+		if item.Status != bulkWouldUpdate || len(item.Changes) != 4 {
+			t.Fatalf("preview item = %+v", item)
+		}
+		for i, prefix := range want {
+			if !strings.HasPrefix(item.Changes[i], prefix) {
+				t.Errorf("change %d = %q, want prefix %q", i, item.Changes[i], prefix)
+			}
+		}
+	}
+	if getIssue(t, 1).State != forgejo_sdk.StateOpen {
+		t.Fatal("dry run modified the issue")
+	}
+
+	args := ops()
+	args["dry_run"] = false
+	args["confirm_token"] = preview.ConfirmToken
+	applied := bulkCall(t, args)
+	if applied.Updated != 2 || applied.Failed != 0 || applied.ConfirmToken != "" {
+		t.Fatalf("apply = %+v", applied)
+	}
+	for _, index := range []int64{1, 3} {
+		issue := getIssue(t, index)
+		if issue.State != forgejo_sdk.StateClosed || issue.Milestone == nil || issue.Milestone.Title != "1.3" ||
+			len(issue.Labels) != 1 || issue.Labels[0].Name != "wontfix" || issue.Comments != 1 {
+			t.Fatalf("issue #%d = %+v", index, issue)
+		}
+	}
+	if getIssue(t, 2).State != forgejo_sdk.StateOpen {
+		t.Fatal("unselected issue changed")
+	}
+
+	// Applying again is a no-op for fields already in place.
+	again := ops()
+	again["comment"] = ""
+	second := bulkCall(t, again)
+	if second.Unchanged != 2 {
+		t.Fatalf("second preview = %+v", second)
+	}
+}
+
+func TestBulkUpdateIssues_ConfirmTokenRequired(t *testing.T) {
+	bulkServer(t)
+	preview := bulkCall(t, map[string]any{"labels": "", "add_labels": "stale"})
+	if preview.Selected != 3 {
+		t.Fatalf("selected = %d", preview.Selected)
+	}
+
+	for name, token := range map[string]string{"missing": "", "stale": "0000000000000000"} {
+		_, err := BulkUpdateIssuesFn(context.Background(), makeReq(map[string]any{
+			"owner": "alice", "repo": "demo", "add_labels": "stale", "dry_run": false, "confirm_token": token,
+		}))
+		if err == nil {
+			t.Errorf("%s token: want error", name)
+		}
+	}
+
+	// A token from one selection does not apply to another.
+	_, err := BulkUpdateIssuesFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "indexes": "1", "add_labels": "stale",
+		"dry_run": false, "confirm_token": preview.ConfirmToken,
+	}))
+	if err == nil || !strings.Contains(err.Error(), "changed since the dry run") {
+		t.Fatalf("want mismatch error, got %v", err)
+	}
+
+	// Nor to the same selection once one of its issues was edited.
+	if _, err := UpdateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(2), "title": "two, renamed",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	_, err = BulkUpdateIssuesFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "add_labels": "stale", "dry_run": false, "confirm_token": preview.ConfirmToken,
+	}))
+	if err == nil || !strings.Contains(err.Error(), "changed since the dry run") {
+		t.Fatalf("edited issue: want mismatch error, got %v", err)
+	}
+}
+
+func TestBulkUpdateIssues_FilterSelection(t *testing.T) {
+	bulkServer(t)
+	bulkCall(t, map[string]any{"indexes": "2", "add_labels": "wontfix", "dry_run": false,
+		"confirm_token": bulkCall(t, map[string]any{"indexes": "2", "add_labels": "wontfix"}).ConfirmToken})
+
+	preview := bulkCall(t, map[string]any{"labels": "wontfix", "set_state": "closed"})
+	if preview.Selected != 1 || preview.Items[0].Index != 2 {
+		t.Fatalf("selection = %+v", preview)
+	}
+
+	// Labelling issue 2 touched it last; before is exclusive.
+	before := getIssue(t, 2).Updated.Format(time.RFC3339)
+	old := bulkCall(t, map[string]any{"before": before, "set_state": "closed"})
+	if old.Selected != 2 {
+		t.Fatalf("before selection = %+v", old)
+	}
+}
+
+func TestBulkUpdateIssues_FilterSelectionOnCappedInstance(t *testing.T) {
+	srv := bulkServer(t)
+	srv.MaxResponseItems = 2
+	preview := bulkCall(t, map[string]any{"state": "open", "add_labels": "stale"})
+	if preview.Selected != 3 {
+		t.Fatalf("selected %d of 3 issues across capped pages", preview.Selected)
+	}
+	applied := bulkCall(t, map[string]any{"state": "open", "add_labels": "stale", "dry_run": false, "confirm_token": preview.ConfirmToken})
+	if applied.Updated != 3 || len(getIssue(t, 3).Labels) != 1 {
+		t.Fatalf("apply = %+v", applied)
+	}
+}
+
+func TestBulkUpdateIssues_Validation(t *testing.T) {
+	bulkServer(t)
+	for name, args := range map[string]map[string]any{
+		"no operation":       {"indexes": "1"},
+		"indexes and filter": {"indexes": "1", "labels": "stale", "set_state": "closed"},
+		"bad index":          {"indexes": "x", "set_state": "closed"},
+		"unknown label":      {"add_labels": "nope"},
+		"unknown milestone":  {"milestone": "9.9"},
+		"bad before":         {"before": "last year", "set_state": "closed"},
+		"too many":           {"max_items": float64(2), "set_state": "closed"},
+	} {
+		args["owner"], args["repo"] = "alice", "demo"
+		if _, err := BulkUpdateIssuesFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
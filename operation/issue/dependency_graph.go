@@ -0,0 +1,573 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const GetIssueDependencyGraphToolName = "get_issue_dependency_graph"
+
+const (
+	graphMaxDepth      = 10
+	graphMaxNodes      = 500
+	graphPageSize      = 50
+	graphFormatJSON    = "json"
+	graphFormatMermaid = "mermaid"
+	graphFormatDOT     = "dot"
+	graphDefaultNodes  = 200
+)
+
+var GetIssueDependencyGraphTool = mcp.NewTool(
+	GetIssueDependencyGraphToolName,
+	mcp.WithDescription("Walk issue dependencies in both directions (what an issue depends on and what it blocks, across repositories) starting from one issue, every issue of a milestone, or else every open issue of the repository, up to depth hops. "+
+		"Returns the graph plus an analysis: cycles, a topological order (dependencies first; omitted when there are cycles), the critical path (the longest chain of open issues, in the order they must be done), blocked issues (open with an open dependency) and ready issues (open with none). "+
+		"format=json returns nodes and edges; mermaid and dot return a diagram instead, with arrows pointing from a dependency to the issue it blocks. Issues whose edges are incomplete are marked frontier and never reported as ready: those at the depth limit, whose own edges were not fetched, and those with a neighbour left out by max_nodes. "+
+		"At most max_nodes issues are collected; truncated reports when more were reachable."),
+	mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+	mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	mcp.WithNumber("index", mcp.Description("Start from this issue")),
+	mcp.WithString("milestone", mcp.Description("Start from every issue (open and closed) of this milestone; title or numeric ID")),
+	mcp.WithNumber("depth", mcp.Description("How many dependency hops to follow from the starting issues (max "+fmt.Sprint(graphMaxDepth)+")"), mcp.DefaultNumber(3)),
+	mcp.WithNumber("max_nodes", mcp.Description("Maximum number of issues in the graph (max "+fmt.Sprint(graphMaxNodes)+")"), mcp.DefaultNumber(graphDefaultNodes)),
+	mcp.WithString("format", mcp.Description("Output format"), mcp.Enum(graphFormatJSON, graphFormatMermaid, graphFormatDOT), mcp.DefaultString(graphFormatJSON)),
+)
+
+func RegisterDependencyGraphTool(s *server.MCPServer) {
+	s.AddTool(GetIssueDependencyGraphTool, GetIssueDependencyGraphFn)
+}
+
+type graphNode struct {
+	ID       string `json:"id"`
+	Owner    string `json:"owner"`
+	Repo     string `json:"repo"`
+	Index    int64  `json:"index"`
+	Title    string `json:"title"`
+	State    string `json:"state"`
+	HTMLURL  string `json:"html_url"`
+	Depth    int    `json:"depth"`
+	Frontier bool   `json:"frontier,omitempty"` // edges not fully known
+}
+
+// graphEdge says Issue depends on DependsOn.
+type graphEdge struct {
+	Issue     string `json:"issue"`
+	DependsOn string `json:"depends_on"`
+}
+
+type blockedIssue struct {
+	ID        string   `json:"id"`
+	Title     string   `json:"title"`
+	BlockedBy []string `json:"blocked_by"`
+}
+
+type dependencyGraph struct {
+	Format             string         `json:"format"`
+	NodeCount          int            `json:"node_count"`
+	EdgeCount          int            `json:"edge_count"`
+	Truncated          bool           `json:"truncated"`
+	Nodes              []graphNode    `json:"nodes,omitempty"`
+	Edges              []graphEdge    `json:"edges,omitempty"`
+	Diagram            string         `json:"diagram,omitempty"`
+	Cycles             [][]string     `json:"cycles"`
+	TopologicalOrder   []string       `json:"topological_order,omitempty"`
+	CriticalPath       []string       `json:"critical_path"`
+	CriticalPathLength int            `json:"critical_path_length"`
+	Blocked            []blockedIssue `json:"blocked"`
+	Ready              []string       `json:"ready"`
+}
+
+// graphWalk collects the nodes and edges reachable from the seeds.
+type graphWalk struct {
+	ctx      context.Context
+	maxNodes int
+	pageSize int
+
+	nodes     map[string]*graphNode
+	edges     map[graphEdge]bool
+	queue     []*graphNode
+	truncated bool
+}
+
+func graphKey(owner, repo string, index int64) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, index)
+}
+
+// add records issue at depth and returns its node, or nil when the graph is
+// full. owner and repo name the repository the issue was listed from; the
+// dependency endpoints also say where each issue lives, which wins.
+func (g *graphWalk) add(owner, repo string, issue *forgejo_sdk.Issue, depth int) *graphNode {
+	if issue.Repository != nil {
+		owner, repo = issue.Repository.Owner, issue.Repository.Name
+	}
+	key := graphKey(owner, repo, issue.Index)
+	if n, ok := g.nodes[key]; ok {
+		return n
+	}
+	if len(g.nodes) >= g.maxNodes {
+		g.truncated = true
+		return nil
+	}
+	n := &graphNode{
+		ID:      key,
+		Owner:   owner,
+		Repo:    repo,
+		Index:   issue.Index,
+		Title:   issue.Title,
+		State:   string(issue.State),
+		HTMLURL: issue.HTMLURL,
+		Depth:   depth,
+	}
+	g.nodes[key] = n
+	g.queue = append(g.queue, n)
+	return n
+}
+
+// related lists every issue n depends on ("dependencies") or blocks
+// ("blocks"), paging until a short page.
+func (g *graphWalk) related(n *graphNode, endpoint string) ([]*forgejo_sdk.Issue, error) {
+	var all []*forgejo_sdk.Issue
+	for page := 1; ; page++ {
+		path := forgejo.APIPath("repos", n.Owner, n.Repo, "issues", n.Index, endpoint) + fmt.Sprintf("?page=%d&limit=%d", page, g.pageSize)
+		issues := []*forgejo_sdk.Issue{}
+		if err := forgejo.DoJSONList(g.ctx, http.MethodGet, path, &issues); err != nil {
+			return nil, fmt.Errorf("list %s of %s err: %w", endpoint, n.ID, err)
+		}
+		all = append(all, issues...)
+		if len(issues) < g.pageSize {
+			return all, nil
+		}
+	}
+}
+
+// walk expands nodes breadth-first until depth hops from the seeds.
+func (g *graphWalk) walk(depth int) error {
+	for len(g.queue) > 0 {
+		n := g.queue[0]
+		g.queue = g.queue[1:]
+		if n.Depth >= depth {
+			n.Frontier = true
+			continue
+		}
+		deps, err := g.related(n, "dependencies")
+		if err != nil {
+			return err
+		}
+		for _, issue := range deps {
+			if dep := g.add(n.Owner, n.Repo, issue, n.Depth+1); dep != nil {
+				g.edges[graphEdge{Issue: n.ID, DependsOn: dep.ID}] = true
+			} else {
+				// The graph is full and this dependency is missing from it;
+				// n must not look ready.
+				n.Frontier = true
+			}
+		}
+		blocks, err := g.related(n, "blocks")
+		if err != nil {
+			return err
+		}
+		for _, issue := range blocks {
+			if dependent := g.add(n.Owner, n.Repo, issue, n.Depth+1); dependent != nil {
+				g.edges[graphEdge{Issue: dependent.ID, DependsOn: n.ID}] = true
+			} else {
+				n.Frontier = true
+			}
+		}
+	}
+	return nil
+}
+
+// graphSeeds lists the starting issues: one issue, a milestone's issues, or
+// the repository's open issues.
+func graphSeeds(client *forgejo_sdk.Client, owner, repo string, args map[string]any, maxNodes int) ([]*forgejo_sdk.Issue, bool, error) {
+	index, hasIndex := to.Float64Ok(args["index"])
+	milestone, _ := args["milestone"].(string)
+	milestone = strings.TrimSpace(milestone)
+	if hasIndex && milestone != "" {
+		return nil, false, fmt.Errorf("give index or milestone, not both")
+	}
+	if hasIndex {
+		issue, _, err := client.GetIssue(owner, repo, int64(index))
+		if err != nil {
+			return nil, false, fmt.Errorf("get issue #%d err: %w", int64(index), err)
+		}
+		return []*forgejo_sdk.Issue{issue}, false, nil
+	}
+
+	opt := forgejo_sdk.ListIssueOption{State: forgejo_sdk.StateOpen}
+	if milestone != "" {
+		m, err := fetchMilestone(client, owner, repo, milestone)
+		if err != nil {
+			return nil, false, err
+		}
+		opt.State = forgejo_sdk.StateAll
+		opt.Milestones = []string{strconv.FormatInt(m.ID, 10)}
+	}
+	var seeds []*forgejo_sdk.Issue
+	for page := 1; ; page++ {
+		opt.ListOptions = forgejo_sdk.ListOptions{Page: page, PageSize: graphPageSize}
+		issues, resp, err := client.ListRepoIssues(owner, repo, opt)
+		if err != nil {
+			return nil, false, fmt.Errorf("list issues err: %w", err)
+		}
+		seeds = append(seeds, issues...)
+		if len(seeds) >= maxNodes {
+			truncated := len(seeds) > maxNodes || hasMore(resp)
+			return seeds[:maxNodes], truncated, nil
+		}
+		if len(issues) == 0 || !hasMore(resp) {
+			return seeds, false, nil
+		}
+	}
+}
+
+func GetIssueDependencyGraphFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called GetIssueDependencyGraphFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+
+	depth := 3
+	if v, ok := to.Float64Ok(args["depth"]); ok {
+		depth = int(v)
+	}
+	if depth < 1 || depth > graphMaxDepth {
+		return to.ErrorResult(fmt.Errorf("depth must be between 1 and %d", graphMaxDepth))
+	}
+	maxNodes := graphDefaultNodes
+	if v, ok := to.Float64Ok(args["max_nodes"]); ok {
+		maxNodes = int(v)
+	}
+	if maxNodes < 1 || maxNodes > graphMaxNodes {
+		return to.ErrorResult(fmt.Errorf("max_nodes must be between 1 and %d", graphMaxNodes))
+	}
+	format, _ := args["format"].(string)
+	if format == "" {
+		format = graphFormatJSON
+	}
+	if format != graphFormatJSON && format != graphFormatMermaid && format != graphFormatDOT {
+		return to.ErrorResult(fmt.Errorf("unknown format %q (want json, mermaid or dot)", format))
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	seeds, truncated, err := graphSeeds(client, owner, repo, args, maxNodes)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	g := &graphWalk{
+		ctx:      ctx,
+		maxNodes: maxNodes,
+		pageSize: graphPageSize,
+		nodes:    map[string]*graphNode{},
+		edges:    map[graphEdge]bool{},
+	}
+	if max, ok := forgejo.MaxResponseItems(ctx); ok && max > 0 && max < g.pageSize {
+		g.pageSize = max
+	}
+	for _, issue := range seeds {
+		g.add(owner, repo, issue, 0)
+	}
+	if err := g.walk(depth); err != nil {
+		return to.ErrorResult(err)
+	}
+
+	out := analyzeGraph(g.sortedNodes(), g.sortedEdges())
+	out.Format = format
+	out.Truncated = truncated || g.truncated
+	switch format {
+	case graphFormatMermaid:
+		out.Diagram = renderMermaid(out.Nodes, out.Edges, out.Cycles)
+		out.Nodes, out.Edges = nil, nil
+	case graphFormatDOT:
+		out.Diagram = renderDOT(out.Nodes, out.Edges, out.Cycles)
+		out.Nodes, out.Edges = nil, nil
+	}
+	return to.TextResult(out)
+}
+
+// compareNodes orders nodes by repository, then issue number.
+func compareNodes(a, b graphNode) int {
+	return cmp.Or(cmp.Compare(a.Owner, b.Owner), cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Index, b.Index))
+}
+
+func (g *graphWalk) sortedNodes() []graphNode {
+	nodes := make([]graphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, *n)
+	}
+	slices.SortFunc(nodes, compareNodes)
+	return nodes
+}
+
+func (g *graphWalk) sortedEdges() []graphEdge {
+	rank := map[string]graphNode{}
+	for _, n := range g.nodes {
+		rank[n.ID] = *n
+	}
+	edges := make([]graphEdge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	slices.SortFunc(edges, func(a, b graphEdge) int {
+		return cmp.Or(compareNodes(rank[a.Issue], rank[b.Issue]), compareNodes(rank[a.DependsOn], rank[b.DependsOn]))
+	})
+	return edges
+}
+
+// analyzeGraph finds cycles, a topological order, the critical path and the
+// blocked and ready issues. nodes and edges must be sorted so that the result
+// is deterministic.
+func analyzeGraph(nodes []graphNode, edges []graphEdge) dependencyGraph {
+	pos := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		pos[n.ID] = i
+	}
+	deps := make([][]int, len(nodes))
+	for _, e := range edges {
+		deps[pos[e.Issue]] = append(deps[pos[e.Issue]], pos[e.DependsOn])
+	}
+	open := func(i int) bool { return nodes[i].State == string(forgejo_sdk.StateOpen) }
+
+	out := dependencyGraph{
+		NodeCount:    len(nodes),
+		EdgeCount:    len(edges),
+		Nodes:        nodes,
+		Edges:        edges,
+		Cycles:       [][]string{},
+		CriticalPath: []string{},
+		Blocked:      []blockedIssue{},
+		Ready:        []string{},
+	}
+
+	// Tarjan emits a component only after everything it depends on, so the
+	// emission order is already dependencies first.
+	components, component := stronglyConnected(deps)
+	for _, c := range components {
+		if len(c) > 1 {
+			cycle := make([]string, len(c))
+			for i, v := range c {
+				cycle[i] = nodes[v].ID
+			}
+			out.Cycles = append(out.Cycles, cycle)
+		}
+	}
+	if len(out.Cycles) == 0 {
+		for _, c := range components {
+			out.TopologicalOrder = append(out.TopologicalOrder, nodes[c[0]].ID)
+		}
+	}
+
+	// Longest chain of open issues, ignoring edges inside a cycle so that it
+	// stays finite.
+	length := make([]int, len(nodes))
+	next := make([]int, len(nodes))
+	best := -1
+	for _, c := range components {
+		for _, v := range c {
+			next[v] = -1
+			if !open(v) {
+				continue
+			}
+			length[v] = 1
+			for _, d := range deps[v] {
+				if component[d] != component[v] && open(d) && length[d]+1 > length[v] {
+					length[v], next[v] = length[d]+1, d
+				}
+			}
+			if best < 0 || length[v] > length[best] || (length[v] == length[best] && v < best) {
+				best = v
+			}
+		}
+	}
+	if best >= 0 {
+		for v := best; v >= 0; v = next[v] {
+			out.CriticalPath = append(out.CriticalPath, nodes[v].ID)
+		}
+		slices.Reverse(out.CriticalPath)
+		out.CriticalPathLength = length[best]
+	}
+
+	for i, n := range nodes {
+		if !open(i) {
+			continue
+		}
+		var blockers []string
+		for _, d := range deps[i] {
+			if open(d) {
+				blockers = append(blockers, nodes[d].ID)
+			}
+		}
+		switch {
+		case len(blockers) > 0:
+			out.Blocked = append(out.Blocked, blockedIssue{ID: n.ID, Title: n.Title, BlockedBy: blockers})
+		case !n.Frontier:
+			out.Ready = append(out.Ready, n.ID)
+		}
+	}
+	return out
+}
+
+// stronglyConnected runs Tarjan's algorithm over the dependency lists. It
+// returns the components in emission order, each sorted, and the component
+// number of every vertex.
+func stronglyConnected(deps [][]int) ([][]int, []int) {
+	index := make([]int, len(deps))
+	low := make([]int, len(deps))
+	onStack := make([]bool, len(deps))
+	component := make([]int, len(deps))
+	for i := range index {
+		index[i] = -1
+	}
+	var (
+		stack      []int
+		components [][]int
+		counter    int
+		visit      func(v int)
+	)
+	visit = func(v int) {
+		index[v], low[v] = counter, counter
+		counter++
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range deps[v] {
+			if index[w] < 0 {
+				visit(w)
+				low[v] = min(low[v], low[w])
+			} else if onStack[w] {
+				low[v] = min(low[v], index[w])
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		var c []int
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component[w] = len(components)
+			c = append(c, w)
+			if w == v {
+				break
+			}
+		}
+		slices.Sort(c)
+		components = append(components, c)
+	}
+	for v := range deps {
+		if index[v] < 0 {
+			visit(v)
+		}
+	}
+	return components, component
+}
+
+// graphLabel names a node the way Forgejo would from inside owner/repo: a
+// bare #N for the same repository, the full reference otherwise.
+func graphLabel(n graphNode, owner, repo string) string {
+	ref := n.ID
+	if strings.EqualFold(n.Owner, owner) && strings.EqualFold(n.Repo, repo) {
+		ref = "#" + strconv.FormatInt(n.Index, 10)
+	}
+	return ref + " " + n.Title
+}
+
+// graphHome is the repository most nodes belong to, used to shorten labels.
+func graphHome(nodes []graphNode) (owner, repo string) {
+	if len(nodes) == 0 {
+		return "", ""
+	}
+	counts := map[[2]string]int{}
+	home := [2]string{nodes[0].Owner, nodes[0].Repo}
+	for _, n := range nodes {
+		k := [2]string{n.Owner, n.Repo}
+		counts[k]++
+		if counts[k] > counts[home] {
+			home = k
+		}
+	}
+	return home[0], home[1]
+}
+
+func inCycle(cycles [][]string) map[string]bool {
+	m := map[string]bool{}
+	for _, c := range cycles {
+		for _, id := range c {
+			m[id] = true
+		}
+	}
+	return m
+}
+
+// renderMermaid draws a flowchart with arrows from each dependency to the
+// issue it blocks. Closed issues are greyed out and cycle members outlined.
+func renderMermaid(nodes []graphNode, edges []graphEdge, cycles [][]string) string {
+	owner, repo := graphHome(nodes)
+	cyclic := inCycle(cycles)
+	ids := map[string]string{}
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for i, n := range nodes {
+		ids[n.ID] = "n" + strconv.Itoa(i)
+		label := strings.NewReplacer(`"`, "#quot;", "\n", " ").Replace(graphLabel(n, owner, repo))
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", ids[n.ID], label)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", ids[e.DependsOn], ids[e.Issue])
+	}
+	b.WriteString("  classDef closed fill:#eee,color:#888\n  classDef cycle stroke:#d00,stroke-width:2px\n")
+	for _, n := range nodes {
+		if n.State != string(forgejo_sdk.StateOpen) {
+			fmt.Fprintf(&b, "  class %s closed\n", ids[n.ID])
+		}
+		if cyclic[n.ID] {
+			fmt.Fprintf(&b, "  class %s cycle\n", ids[n.ID])
+		}
+	}
+	return b.String()
+}
+
+// renderDOT is renderMermaid for Graphviz.
+func renderDOT(nodes []graphNode, edges []graphEdge, cycles [][]string) string {
+	owner, repo := graphHome(nodes)
+	cyclic := inCycle(cycles)
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+	}
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n  rankdir=LR;\n  node [shape=box];\n")
+	for _, n := range nodes {
+		attrs := "label=" + quote(graphLabel(n, owner, repo))
+		if n.State != string(forgejo_sdk.StateOpen) {
+			attrs += `, style=filled, fillcolor="#eeeeee", fontcolor="#888888"`
+		}
+		if cyclic[n.ID] {
+			attrs += `, color="#dd0000", penwidth=2`
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", quote(n.ID), attrs)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", quote(e.DependsOn), quote(e.Issue))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
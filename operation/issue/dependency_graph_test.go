@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// graphServer seeds alice/demo with a release plan:
+//
+//	#1 Design schema (closed) <- #2 Migrate data <- #3 Ship release
+//	                             #4 Write docs   <- #3
+//	#5 and #6 depend on each other; #7 lives in alice/infra and blocks #4.
+func graphServer(t *testing.T) *forgejotest.Server {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateRepo("alice", "infra")
+	milestone := srv.CreateMilestone("alice", "demo", "1.0").ID
+	for _, title := range []string{"Design schema", "Migrate data", "Ship release", "Write docs", "Cache tokens", "Refresh tokens"} {
+		srv.CreateIssue("alice", "demo", title, "")
+	}
+	srv.CreateIssue("alice", "infra", "Provision docs host", "")
+
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := forgejo_sdk.StateClosed
+	if _, _, err := client.EditIssue("alice", "demo", 1, forgejo_sdk.EditIssueOption{State: &closed}); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int64{2, 3} {
+		if _, _, err := client.EditIssue("alice", "demo", index, forgejo_sdk.EditIssueOption{Milestone: &milestone}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dep := range []struct{ index, on int64 }{{2, 1}, {3, 2}, {3, 4}, {5, 6}, {6, 5}} {
+		if _, err := AddIssueDependencyFn(context.Background(), makeReq(map[string]any{
+			"owner": "alice", "repo": "demo", "index": float64(dep.index), "depends_on_index": float64(dep.on),
+		})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := forgejo.DoJSON(context.Background(), http.MethodPost, forgejo.APIPath("repos", "alice", "demo", "issues", int64(4), "dependencies"),
+		issueMetaBody{Owner: "alice", Repo: "infra", Index: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func graphCall(t *testing.T, args map[string]any) dependencyGraph {
+	t.Helper()
+	args["owner"], args["repo"] = "alice", "demo"
+	res, err := GetIssueDependencyGraphFn(context.Background(), makeReq(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out dependencyGraph
+	resultJSON(t, res, &out)
+	return out
+}
+
+func TestGetIssueDependencyGraph_FromIssue(t *testing.T) {
+	graphServer(t)
+	out := graphCall(t, map[string]any{"index": float64(3)})
+
+	if out.NodeCount != 5 || out.EdgeCount != 4 || out.Truncated || len(out.Cycles) != 0 {
+		t.Fatalf("graph = %+v", out)
+	}
+	if !slices.Contains(out.Edges, graphEdge{Issue: "alice/demo#4", DependsOn: "alice/infra#1"}) {
+		t.Errorf("cross-repo edge missing: %+v", out.Edges)
+	}
+	order := out.TopologicalOrder
+	if len(order) != 5 || slices.Index(order, "alice/demo#1") > slices.Index(order, "alice/demo#2") ||
+		slices.Index(order, "alice/demo#2") > slices.Index(order, "alice/demo#3") ||
+		slices.Index(order, "alice/infra#1") > slices.Index(order, "alice/demo#4") {
+		t.Errorf("topological order = %v", order)
+	}
+	// #1 is closed, so the docs chain is the longer one.
+	if !slices.Equal(out.CriticalPath, []string{"alice/infra#1", "alice/demo#4", "alice/demo#3"}) || out.CriticalPathLength != 3 {
+		t.Errorf("critical path = %v (%d)", out.CriticalPath, out.CriticalPathLength)
+	}
+	if len(out.Blocked) != 2 || out.Blocked[0].ID != "alice/demo#3" || !slices.Equal(out.Blocked[0].BlockedBy, []string{"alice/demo#2", "alice/demo#4"}) ||
+		out.Blocked[1].ID != "alice/demo#4" {
+		t.Errorf("blocked = %+v", out.Blocked)
+	}
+	if !slices.Equal(out.Ready, []string{"alice/demo#2", "alice/infra#1"}) {
+		t.Errorf("ready = %v", out.Ready)
+	}
+}
+
+func TestGetIssueDependencyGraph_DepthAndLimits(t *testing.T) {
+	graphServer(t)
+	out := graphCall(t, map[string]any{"index": float64(3), "depth": float64(1)})
+	if out.NodeCount != 3 || out.EdgeCount != 2 {
+		t.Fatalf("depth 1 = %+v", out)
+	}
+	for _, n := range out.Nodes {
+		if n.Frontier != (n.Depth == 1) {
+			t.Errorf("node %+v", n)
+		}
+	}
+	// Neither dependency was expanded, so neither is known to be ready.
+	if len(out.Ready) != 0 {
+		t.Errorf("ready = %v", out.Ready)
+	}
+
+	out = graphCall(t, map[string]any{"index": float64(3), "max_nodes": float64(2)})
+	if out.NodeCount != 2 || !out.Truncated {
+		t.Fatalf("max_nodes 2 = %+v", out)
+	}
+
+	// #3 depends on open #2 and #4, but only #3 fits: it is incomplete, not ready.
+	out = graphCall(t, map[string]any{"index": float64(3), "max_nodes": float64(1)})
+	if out.NodeCount != 1 || !out.Truncated || !out.Nodes[0].Frontier || len(out.Ready) != 0 {
+		t.Fatalf("max_nodes 1 = %+v", out)
+	}
+
+	for name, args := range map[string]map[string]any{
+		"depth":     {"depth": float64(11)},
+		"max_nodes": {"max_nodes": float64(0)},
+		"format":    {"format": "svg"},
+		"both":      {"index": float64(1), "milestone": "1.0"},
+		"milestone": {"milestone": "9.9"},
+	} {
+		args["owner"], args["repo"] = "alice", "demo"
+		if _, err := GetIssueDependencyGraphFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestGetIssueDependencyGraph_RepoAndMilestone(t *testing.T) {
+	graphServer(t)
+	out := graphCall(t, map[string]any{})
+	if out.NodeCount != 7 || len(out.TopologicalOrder) != 0 {
+		t.Fatalf("repo graph = %+v", out)
+	}
+	if len(out.Cycles) != 1 || !slices.Equal(out.Cycles[0], []string{"alice/demo#5", "alice/demo#6"}) {
+		t.Errorf("cycles = %v", out.Cycles)
+	}
+
+	out = graphCall(t, map[string]any{"milestone": "1.0", "depth": float64(1)})
+	if out.NodeCount != 4 || out.Nodes[0].ID != "alice/demo#1" || out.Nodes[0].Depth != 1 {
+		t.Fatalf("milestone graph = %+v", out)
+	}
+}
+
+func TestGetIssueDependencyGraph_Diagrams(t *testing.T) {
+	graphServer(t)
+	mermaid := graphCall(t, map[string]any{"index": float64(5), "format": "mermaid"})
+	if mermaid.Nodes != nil || mermaid.Edges != nil || !strings.HasPrefix(mermaid.Diagram, "graph TD\n") {
+		t.Fatalf("mermaid = %+v", mermaid)
+	}
+	for _, want := range []string{`n0["#5 Cache tokens"]`, "n1 --> n0", "n0 --> n1", "class n0 cycle"} {
+		if !strings.Contains(mermaid.Diagram, want) {
+			t.Errorf("mermaid lacks %q:\n%s", want, mermaid.Diagram)
+		}
+	}
+
+	dot := graphCall(t, map[string]any{"index": float64(2), "format": "dot"})
+	for _, want := range []string{
+		"digraph dependencies {",
+		`"alice/demo#1" [label="#1 Design schema", style=filled`,
+		`"alice/demo#1" -> "alice/demo#2";`,
+		`"alice/infra#1" [label="alice/infra#1 Provision docs host"];`,
+	} {
+		if !strings.Contains(dot.Diagram, want) {
+			t.Errorf("dot lacks %q:\n%s", want, dot.Diagram)
+		}
+	}
+}
@@ -97,7 +97,7 @@ const (
 var (
 	GetIssueByIndexTool = mcp.NewTool(
 		GetIssueByIndexToolName,
-		mcp.WithDescription("Get issue by index"),
+		mcp.WithDescription("Get issue by index, including whether its conversation is locked (is_locked) and its pin state (is_pinned, pin_order)"),
 		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
 		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
 		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
@@ -119,11 +119,20 @@ var (
 
 	CreateIssueTool = mcp.NewTool(
 		CreateIssueToolName,
-		mcp.WithDescription("Create issue"),
+		mcp.WithDescription("Create issue, optionally with labels, assignees, milestone, due date and ref in the same call; label and milestone names are resolved to IDs and an unknown name fails before anything is created. With template (see list_issue_templates), a form template's fields are validated and rendered into the body as the web UI does, its title prefix is prepended, and its labels, assignees and ref are applied; a markdown template's content is used when body is empty."),
 		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
 		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
 		mcp.WithString("title", mcp.Required(), mcp.Description(params.Title)),
 		mcp.WithString("body", mcp.Description(params.Body)),
+		mcp.WithString("labels", mcp.Description("Label names or IDs (comma-separated); org labels apply to org-owned repositories")),
+		mcp.WithString("assignees", mcp.Description("Assignee usernames (comma-separated)")),
+		mcp.WithString("milestone", mcp.Description("Milestone title or ID")),
+		mcp.WithString("due_date", mcp.Description("Due date (RFC3339, e.g. 2026-08-20T00:00:00Z)")),
+		mcp.WithString("ref", mcp.Description("Branch or tag the issue refers to")),
+		mcp.WithString("template", mcp.Description("Issue template name or file name, as listed by list_issue_templates")),
+		mcp.WithObject("fields", mcp.Description("Form template values keyed by field id: a string for input and textarea, an option label (or a list of labels when multiple) for dropdown, a list of checked option labels for checkboxes. Omitted fields take the template default.")),
+		mcp.WithString("check_duplicates", mcp.Description("Look for similar issues first (see find_similar_issues): refuse fails without creating when a match scores at or above duplicate_threshold; warn creates the issue and lists such matches in possible_duplicates"), mcp.Enum("warn", "refuse")),
+		mcp.WithNumber("duplicate_threshold", mcp.Description("Similarity score (0-1) from which check_duplicates treats an issue as a duplicate"), mcp.DefaultNumber(defaultDuplicateThreshold)),
 	)
 
 	CreateIssueCommentTool = mcp.NewTool(
@@ -281,8 +290,19 @@ func RegisterTool(s *server.MCPServer) {
 	s.AddTool(ListRepoLabelsTool, ListRepoLabelsFn)
 	s.AddTool(ListOrgLabelsTool, ListOrgLabelsFn)
 	s.AddTool(SearchIssuesTool, SearchIssuesFn)
+	s.AddTool(ListIssueTemplatesTool, ListIssueTemplatesFn)
+	s.AddTool(BulkUpdateIssuesTool, BulkUpdateIssuesFn)
 	RegisterLabelTool(s)
 	RegisterDependencyTool(s)
+	RegisterReactionTool(s)
+	RegisterLockTool(s)
+	RegisterPinTool(s)
+	RegisterSubscriptionTool(s)
+	RegisterMilestoneTool(s)
+	RegisterTimelineTool(s)
+	RegisterSimilarTool(s)
+	RegisterTransferTool(s)
+	RegisterDependencyGraphTool(s)
 }
 
 func GetIssueByIndexFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -291,17 +311,23 @@ func GetIssueByIndexFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	repo, _ := req.GetArguments()["repo"].(string)
 	index, _ := to.Float64(req.GetArguments()["index"])
 
-	client, err := forgejo.Client(ctx)
-	if err != nil {
-		return to.ErrorResult(err)
-	}
-	issue, _, err := client.GetIssue(owner, repo, int64(index))
-	if err != nil {
+	// Fetched raw: the SDK's Issue drops pin_order.
+	var issue issueDetail
+	if err := forgejo.DoJSON(ctx, http.MethodGet, forgejo.APIPath("repos", owner, repo, "issues", int64(index)), nil, &issue); err != nil {
 		return to.ErrorResult(fmt.Errorf("get issue err: %w", err))
 	}
+	issue.IsPinned = issue.PinOrder > 0
 	return to.TextResult(issue)
 }
 
+// issueDetail is an issue with the pin state Forgejo reports but the SDK
+// does not model.
+type issueDetail struct {
+	forgejo_sdk.Issue
+	PinOrder int  `json:"pin_order"`
+	IsPinned bool `json:"is_pinned"`
+}
+
 func ListRepoIssuesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	log.Debugf("Called ListRepoIssuesFn")
 	owner, _ := req.GetArguments()["owner"].(string)
@@ -535,19 +561,90 @@ func CreateIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 	repo, _ := req.GetArguments()["repo"].(string)
 	title, _ := req.GetArguments()["title"].(string)
 	body, _ := req.GetArguments()["body"].(string)
+	labels, _ := req.GetArguments()["labels"].(string)
+	assignees, _ := req.GetArguments()["assignees"].(string)
+	milestone, _ := req.GetArguments()["milestone"].(string)
+	dueDate, _ := req.GetArguments()["due_date"].(string)
+	ref, _ := req.GetArguments()["ref"].(string)
+	template, _ := req.GetArguments()["template"].(string)
+	fields, err := objectArg(req.GetArguments(), "fields")
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	if template == "" && len(fields) > 0 {
+		return to.ErrorResult(fmt.Errorf("fields requires template"))
+	}
+	checkDuplicates, _ := req.GetArguments()["check_duplicates"].(string)
+	if checkDuplicates != "" && checkDuplicates != "warn" && checkDuplicates != "refuse" {
+		return to.ErrorResult(fmt.Errorf("check_duplicates must be warn or refuse, got %q", checkDuplicates))
+	}
+	threshold := defaultDuplicateThreshold
+	if v, ok := to.Float64Ok(req.GetArguments()["duplicate_threshold"]); ok {
+		threshold = v
+	}
 
 	opt := forgejo_sdk.CreateIssueOption{
-		Title: title,
-		Body:  body,
+		Title:     title,
+		Body:      body,
+		Assignees: splitCSV(assignees),
+		Ref:       ref,
+	}
+	if dueDate != "" {
+		parsed, err := time.Parse(time.RFC3339, dueDate)
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("invalid due_date format (expected RFC3339): %w", err))
+		}
+		opt.Deadline = &parsed
 	}
 	client, err := forgejo.Client(ctx)
 	if err != nil {
 		return to.ErrorResult(err)
 	}
+	// Resolve every name before creating anything, so a typo cannot leave a
+	// half-triaged issue behind.
+	if labels != "" {
+		if opt.Labels, err = resolveLabelIDs(ctx, client, owner, repo, labels); err != nil {
+			return to.ErrorResult(err)
+		}
+	}
+	if milestone != "" {
+		if opt.Milestone, err = resolveMilestoneID(client, owner, repo, milestone); err != nil {
+			return to.ErrorResult(err)
+		}
+	}
+	if template != "" {
+		if err := applyIssueTemplate(ctx, client, owner, repo, template, fields, &opt); err != nil {
+			return to.ErrorResult(err)
+		}
+	}
+	// Checked last, against the title and body the issue would get after
+	// the template is applied.
+	var duplicates []similarIssue
+	if checkDuplicates != "" {
+		similar, err := findSimilarIssues(client, owner, repo, opt.Title, opt.Body, string(forgejo_sdk.StateAll), 5, threshold)
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("check duplicates: %w", err))
+		}
+		duplicates = similar.Matches
+		if checkDuplicates == "refuse" && len(duplicates) > 0 {
+			found := make([]string, len(duplicates))
+			for i, d := range duplicates {
+				found[i] = fmt.Sprintf("#%d %q (%s, score %.3f)", d.Index, d.Title, d.State, d.Score)
+			}
+			return to.ErrorResult(fmt.Errorf("not created: possible duplicate of %s; comment on the existing issue instead, or retry with check_duplicates=warn or a higher duplicate_threshold",
+				strings.Join(found, ", ")))
+		}
+	}
 	issue, _, err := client.CreateIssue(owner, repo, opt)
 	if err != nil {
 		return to.ErrorResult(fmt.Errorf("create issue err: %w", err))
 	}
+	if len(duplicates) > 0 {
+		return to.TextResult(struct {
+			*forgejo_sdk.Issue
+			PossibleDuplicates []similarIssue `json:"possible_duplicates"`
+		}{issue, duplicates})
+	}
 	return to.TextResult(issue)
 }
 
@@ -951,6 +1048,19 @@ func ListOrgLabelsFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 	return to.TextResult(labels)
 }
 
+// resolveMilestoneID accepts a milestone ID or title; only a title costs a
+// lookup.
+func resolveMilestoneID(client *forgejo_sdk.Client, owner, repo, milestone string) (int64, error) {
+	if id, err := strconv.ParseInt(milestone, 10, 64); err == nil {
+		return id, nil
+	}
+	m, err := fetchMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return 0, err
+	}
+	return m.ID, nil
+}
+
 func splitCSV(s string) []string {
 	parts := strings.Split(s, ",")
 	result := make([]string, 0, len(parts))
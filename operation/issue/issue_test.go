@@ -9,9 +9,11 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
 
 	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -1589,3 +1591,72 @@ func textResultString(t *testing.T, res *mcp.CallToolResult) string {
 	}
 	return tc.Text
 }
+
+func TestCreateIssue_LabelsMilestoneAssigneesDueDateRef(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	bug := srv.CreateLabel("alice", "demo", "bug", "ff0000")
+	urgent := srv.CreateLabel("alice", "demo", "Urgent", "00ff00")
+	v1 := srv.CreateMilestone("alice", "demo", "v1.0")
+
+	res, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner":     "alice",
+		"repo":      "demo",
+		"title":     "triaged",
+		"labels":    fmt.Sprintf("urgent, %d", bug.ID),
+		"assignees": "forgejotest, bob",
+		"milestone": "v1.0",
+		"due_date":  "2026-08-20T00:00:00Z",
+		"ref":       "refs/heads/main",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issue forgejo_sdk.Issue
+	resultJSON(t, res, &issue)
+
+	if len(issue.Labels) != 2 || issue.Labels[0].ID != urgent.ID || issue.Labels[1].ID != bug.ID {
+		t.Fatalf("labels = %+v", issue.Labels)
+	}
+	if len(issue.Assignees) != 2 || issue.Assignees[1].UserName != "bob" {
+		t.Fatalf("assignees = %+v", issue.Assignees)
+	}
+	if issue.Milestone == nil || issue.Milestone.ID != v1.ID {
+		t.Fatalf("milestone = %+v", issue.Milestone)
+	}
+	if issue.Deadline == nil || !issue.Deadline.Equal(time.Date(2026, 8, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("deadline = %v", issue.Deadline)
+	}
+	if issue.Ref != "refs/heads/main" {
+		t.Fatalf("ref = %q", issue.Ref)
+	}
+}
+
+func TestCreateIssue_UnknownNamesCreateNothing(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateLabel("alice", "demo", "bug", "ff0000")
+
+	for name, args := range map[string]map[string]any{
+		"label":     {"labels": "bug,nope"},
+		"milestone": {"milestone": "v9"},
+		"due date":  {"due_date": "next week"},
+	} {
+		args["owner"], args["repo"], args["title"] = "alice", "demo", "x"
+		if _, err := CreateIssueFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	res, err := ListRepoIssuesFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo", "state": "all"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []forgejo_sdk.Issue
+	resultJSON(t, res, &issues)
+	if len(issues) != 0 {
+		t.Fatalf("want no issues created, got %d", len(issues))
+	}
+}
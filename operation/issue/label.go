@@ -181,6 +181,101 @@ func orgLabelInUseCount(ctx context.Context, client *forgejo_sdk.Client, org, la
 	return total, nil
 }
 
+// labelIDsByName resolves label names to IDs among the repository's labels
+// and, when owner is an organization, its org labels. Repository labels win
+// over org labels of the same name; an exact match wins over a
+// case-insensitive one. Names that match nothing are returned as missing so
+// callers can decide whether that is an error.
+func labelIDsByName(ctx context.Context, client *forgejo_sdk.Client, owner, repo string, names []string) (ids []int64, missing []string, err error) {
+	var labels []*forgejo_sdk.Label
+	for page := 1; ; page++ {
+		batch, resp, err := client.ListRepoLabels(owner, repo, forgejo_sdk.ListLabelsOptions{
+			ListOptions: forgejo_sdk.ListOptions{Page: page, PageSize: 50},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("list repo labels: %w", err)
+		}
+		labels = append(labels, batch...)
+		// The server may cap the page below 50, so follow its Link header
+		// rather than stopping on a short page.
+		if len(batch) == 0 || !hasMore(resp) {
+			break
+		}
+	}
+	// A user-owned repository has no org labels; fetchOrgLabels reads that
+	// 404 as none. Any other failure would make names resolve differently.
+	for page := 1; ; page++ {
+		batch, header, err := fetchOrgLabels(ctx, owner, page, 50)
+		if err != nil {
+			return nil, nil, fmt.Errorf("list org labels: %w", err)
+		}
+		for _, l := range batch {
+			labels = append(labels, l.Label)
+		}
+		if len(batch) == 0 || !headerHasMore(header) {
+			break
+		}
+	}
+
+	for _, name := range names {
+		var found *forgejo_sdk.Label
+		for _, l := range labels {
+			if l.Name == name {
+				found = l
+				break
+			}
+		}
+		if found == nil {
+			for _, l := range labels {
+				if strings.EqualFold(l.Name, name) {
+					found = l
+					break
+				}
+			}
+		}
+		if found == nil {
+			missing = append(missing, name)
+			continue
+		}
+		ids = append(ids, found.ID)
+	}
+	return ids, missing, nil
+}
+
+// resolveLabelIDs turns a comma-separated list of label IDs and names into
+// IDs, in the order given. Numeric entries are taken as IDs; names must
+// exist.
+func resolveLabelIDs(ctx context.Context, client *forgejo_sdk.Client, owner, repo, labels string) ([]int64, error) {
+	entries := splitCSV(labels)
+	ids := make([]int64, len(entries))
+	byName := make([]bool, len(entries))
+	var names []string
+	for i, l := range entries {
+		if id, err := strconv.ParseInt(l, 10, 64); err == nil {
+			ids[i] = id
+		} else {
+			byName[i] = true
+			names = append(names, l)
+		}
+	}
+	if len(names) == 0 {
+		return ids, nil
+	}
+	named, missing, err := labelIDsByName(ctx, client, owner, repo, names)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("unknown labels: %s (see list_repo_labels)", strings.Join(missing, ", "))
+	}
+	for i := range ids {
+		if byName[i] {
+			ids[i], named = named[0], named[1:]
+		}
+	}
+	return ids, nil
+}
+
 // ---- Org label raw-HTTP types ----
 
 type orgLabelOption struct {
@@ -13,6 +13,7 @@ import (
 
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
 
 	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -371,3 +372,55 @@ func TestRepoLabelsResource_404(t *testing.T) {
 		t.Fatal("expected error for 404")
 	}
 }
+
+// ---- labelIDsByName ----
+
+func TestLabelIDsByName_FollowsCappedPages(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.MaxResponseItems = 30
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	var want int64
+	for i := 1; i <= 35; i++ {
+		want = srv.CreateLabel("alice", "demo", fmt.Sprintf("l%d", i), "cccccc").ID
+	}
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, missing, err := labelIDsByName(context.Background(), client, "alice", "demo", []string{"l35"})
+	if err != nil || len(missing) != 0 || len(ids) != 1 || ids[0] != want {
+		t.Fatalf("ids=%v missing=%v err=%v", ids, missing, err)
+	}
+	if _, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "title": "Late label", "labels": "l35",
+	})); err != nil {
+		t.Fatalf("create_issue labels=l35: %v", err)
+	}
+}
+
+func TestLabelIDsByName_OrgLabelErrors(t *testing.T) {
+	repoLabels := []forgejo_sdk.Label{{ID: 1, Name: "bug"}}
+	for _, tt := range []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusNotFound, false},
+		{http.StatusInternalServerError, true},
+		{http.StatusForbidden, true},
+	} {
+		newLabelBackend(t, func(mux *http.ServeMux) {
+			labelHandler(t, http.MethodGet, "/api/v1/repos/alice/demo/labels", http.StatusOK, repoLabels)(mux)
+			labelHandler(t, http.MethodGet, "/api/v1/orgs/alice/labels", tt.status, nil)(mux)
+		})
+		client, err := forgejo.Client(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids, _, err := labelIDsByName(context.Background(), client, "alice", "demo", []string{"bug"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("org labels %d: ids=%v err=%v, want error %v", tt.status, ids, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	LockIssueToolName   = "lock_issue"
+	UnlockIssueToolName = "unlock_issue"
+)
+
+var (
+	LockIssueTool = mcp.NewTool(
+		LockIssueToolName,
+		mcp.WithDescription("Lock an issue or pull request conversation so only collaborators can comment. Locking an already locked conversation is a no-op."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithString("reason", mcp.Description("Lock reason shown on the timeline. Forgejo's defaults are: Too heated, Off-topic, Resolved, Spam (the instance may configure others). Omit for no reason.")),
+	)
+
+	UnlockIssueTool = mcp.NewTool(
+		UnlockIssueToolName,
+		mcp.WithDescription("Unlock an issue or pull request conversation so everyone can comment again."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	)
+)
+
+// lockIssueOption is the body of PUT /issues/{index}/lock.
+type lockIssueOption struct {
+	Reason string `json:"lock_reason"`
+}
+
+func RegisterLockTool(s *server.MCPServer) {
+	s.AddTool(LockIssueTool, LockIssueFn)
+	s.AddTool(UnlockIssueTool, UnlockIssueFn)
+}
+
+// lockError names the likely cause of a 404: the lock endpoints are newer
+// than the rest of the issue API.
+func lockError(action string, err error) error {
+	var he *forgejo.HTTPError
+	if errors.As(err, &he) && he.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s issue err: %w (the issue does not exist, or this server has no issue lock API)", action, err)
+	}
+	return fmt.Errorf("%s issue err: %w", action, err)
+}
+
+func LockIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called LockIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	reason, _ := req.GetArguments()["reason"].(string)
+	reason = strings.TrimSpace(reason)
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "lock")
+	if err := forgejo.DoJSON(ctx, http.MethodPut, path, lockIssueOption{Reason: reason}, nil); err != nil {
+		return to.ErrorResult(lockError("lock", err))
+	}
+	if reason == "" {
+		return to.TextResult(fmt.Sprintf("Locked conversation on #%d", int64(index)))
+	}
+	return to.TextResult(fmt.Sprintf("Locked conversation on #%d as %s", int64(index), reason))
+}
+
+func UnlockIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called UnlockIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "lock")
+	if err := forgejo.DoJSON(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return to.ErrorResult(lockError("unlock", err))
+	}
+	return to.TextResult(fmt.Sprintf("Unlocked conversation on #%d", int64(index)))
+}
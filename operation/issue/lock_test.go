@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestLockUnlockIssue(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "outage", "")
+	ctx := context.Background()
+	args := map[string]any{"owner": "alice", "repo": "demo", "index": float64(1), "reason": "Too heated"}
+
+	res, err := LockIssueFn(ctx, makeReq(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg string
+	resultJSON(t, res, &msg)
+	if !strings.Contains(msg, "Too heated") {
+		t.Errorf("lock result = %q", msg)
+	}
+	if !getIssue(t, 1).IsLocked {
+		t.Fatal("issue not locked")
+	}
+
+	contents, err := issueResourceHandler(ctx, makeIssueResourceRequest("alice", "demo", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload issueResourcePayload
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !payload.IsLocked {
+		t.Error("resource does not report the lock")
+	}
+
+	if _, err := UnlockIssueFn(ctx, makeReq(args)); err != nil {
+		t.Fatal(err)
+	}
+	if getIssue(t, 1).IsLocked {
+		t.Fatal("issue still locked")
+	}
+
+	_, err = LockIssueFn(ctx, makeReq(map[string]any{"owner": "alice", "repo": "demo", "index": float64(9)}))
+	if err == nil || !strings.Contains(err.Error(), "no issue lock API") {
+		t.Fatalf("missing issue: got %v", err)
+	}
+}
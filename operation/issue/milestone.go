@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	GetMilestoneToolName         = "get_milestone"
+	CreateMilestoneToolName      = "create_milestone"
+	EditMilestoneToolName        = "edit_milestone"
+	DeleteMilestoneToolName      = "delete_milestone"
+	GetMilestoneProgressToolName = "get_milestone_progress"
+)
+
+const milestoneRefDescription = "Milestone title or numeric ID"
+
+// milestoneProgressScanCap bounds how many open issues get_milestone_progress
+// inspects for the overdue and unassigned lists.
+const milestoneProgressScanCap = 500
+
+var (
+	GetMilestoneTool = mcp.NewTool(
+		GetMilestoneToolName,
+		mcp.WithDescription("Get a repository milestone by title or ID, including its open and closed issue counts"),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("milestone", mcp.Required(), mcp.Description(milestoneRefDescription)),
+	)
+
+	CreateMilestoneTool = mcp.NewTool(
+		CreateMilestoneToolName,
+		mcp.WithDescription("Create a repository milestone"),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("title", mcp.Required(), mcp.Description(params.Title)),
+		mcp.WithString("description", mcp.Description("Milestone description")),
+		mcp.WithString("due_date", mcp.Description("Due date (RFC3339, e.g. 2026-08-20T00:00:00Z)")),
+		mcp.WithString("state", mcp.Description("Initial state"), mcp.Enum("open", "closed"), mcp.DefaultString("open")),
+	)
+
+	EditMilestoneTool = mcp.NewTool(
+		EditMilestoneToolName,
+		mcp.WithDescription("Edit a repository milestone. Only the supplied fields change; close or reopen it with state."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("milestone", mcp.Required(), mcp.Description(milestoneRefDescription)),
+		mcp.WithString("title", mcp.Description("New title")),
+		mcp.WithString("description", mcp.Description("New description")),
+		mcp.WithString("due_date", mcp.Description("New due date (RFC3339, e.g. 2026-08-20T00:00:00Z)")),
+		mcp.WithString("state", mcp.Description("New state"), mcp.Enum("open", "closed")),
+	)
+
+	DeleteMilestoneTool = mcp.NewTool(
+		DeleteMilestoneToolName,
+		mcp.WithDescription("Delete a repository milestone. Its issues stay but lose the milestone."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("milestone", mcp.Required(), mcp.Description(milestoneRefDescription)),
+	)
+
+	GetMilestoneProgressTool = mcp.NewTool(
+		GetMilestoneProgressToolName,
+		mcp.WithDescription(fmt.Sprintf("Summarize a milestone's progress: open and closed counts, percent complete, whether the milestone is past due, and which open issues and PRs are overdue (own due date, or else the milestone's, has passed) or have no assignee. At most %d open items are inspected; truncated reports when there were more.", milestoneProgressScanCap)),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithString("milestone", mcp.Required(), mcp.Description(milestoneRefDescription)),
+	)
+)
+
+func RegisterMilestoneTool(s *server.MCPServer) {
+	s.AddTool(GetMilestoneTool, GetMilestoneFn)
+	s.AddTool(CreateMilestoneTool, CreateMilestoneFn)
+	s.AddTool(EditMilestoneTool, EditMilestoneFn)
+	s.AddTool(DeleteMilestoneTool, DeleteMilestoneFn)
+	s.AddTool(GetMilestoneProgressTool, GetMilestoneProgressFn)
+}
+
+// fetchMilestone looks a milestone up by title or ID. Forgejo's
+// GET /milestones/{id} takes either; a numeric value is always an ID.
+func fetchMilestone(client *forgejo_sdk.Client, owner, repo, milestone string) (*forgejo_sdk.Milestone, error) {
+	milestone = strings.TrimSpace(milestone)
+	if milestone == "" {
+		return nil, fmt.Errorf("milestone is required")
+	}
+	m, resp, err := client.GetMilestoneByName(owner, repo, milestone)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("unknown milestone %q (see list_repo_milestones)", milestone)
+		}
+		return nil, fmt.Errorf("get milestone %q: %w", milestone, err)
+	}
+	return m, nil
+}
+
+// milestoneDueDate parses the optional due_date argument.
+func milestoneDueDate(args map[string]any) (*time.Time, error) {
+	dueDate, _ := args["due_date"].(string)
+	if dueDate == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, dueDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid due_date format (expected RFC3339): %w", err)
+	}
+	return &parsed, nil
+}
+
+func GetMilestoneFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called GetMilestoneFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	milestone, _ := req.GetArguments()["milestone"].(string)
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	m, err := fetchMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	return to.TextResult(m)
+}
+
+func CreateMilestoneFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called CreateMilestoneFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	title, _ := req.GetArguments()["title"].(string)
+	description, _ := req.GetArguments()["description"].(string)
+	state, _ := req.GetArguments()["state"].(string)
+	if state == "" {
+		state = "open"
+	}
+	deadline, err := milestoneDueDate(req.GetArguments())
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	m, _, err := client.CreateMilestone(owner, repo, forgejo_sdk.CreateMilestoneOption{
+		Title:       title,
+		Description: description,
+		State:       forgejo_sdk.StateType(state),
+		Deadline:    deadline,
+	})
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("create milestone err: %w", err))
+	}
+	return to.TextResult(m)
+}
+
+func EditMilestoneFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called EditMilestoneFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+	milestone, _ := args["milestone"].(string)
+
+	opt := forgejo_sdk.EditMilestoneOption{}
+	opt.Title, _ = args["title"].(string)
+	if description, ok := args["description"].(string); ok {
+		opt.Description = &description
+	}
+	if state, _ := args["state"].(string); state != "" {
+		st := forgejo_sdk.StateType(state)
+		opt.State = &st
+	}
+	deadline, err := milestoneDueDate(args)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	opt.Deadline = deadline
+	if opt.Title == "" && opt.Description == nil && opt.State == nil && opt.Deadline == nil {
+		return to.ErrorResult(fmt.Errorf("nothing to change: pass title, description, due_date or state"))
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	current, err := fetchMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	m, _, err := client.EditMilestone(owner, repo, current.ID, opt)
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("edit milestone err: %w", err))
+	}
+	return to.TextResult(m)
+}
+
+func DeleteMilestoneFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called DeleteMilestoneFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	milestone, _ := req.GetArguments()["milestone"].(string)
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	m, err := fetchMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	if _, err := client.DeleteMilestone(owner, repo, m.ID); err != nil {
+		return to.ErrorResult(fmt.Errorf("delete milestone err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("Deleted milestone %q (#%d)", m.Title, m.ID))
+}
+
+// progressItem is one open issue or PR listed in a milestone progress report.
+type progressItem struct {
+	Index     int64    `json:"index"`
+	Title     string   `json:"title"`
+	DueDate   string   `json:"due_date,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+	IsPull    bool     `json:"is_pull,omitempty"`
+}
+
+type milestoneProgress struct {
+	ID              int64          `json:"id"`
+	Title           string         `json:"title"`
+	State           string         `json:"state"`
+	DueOn           string         `json:"due_on,omitempty"`
+	Overdue         bool           `json:"overdue"`
+	Open            int            `json:"open"`
+	Closed          int            `json:"closed"`
+	Total           int            `json:"total"`
+	PercentComplete int            `json:"percent_complete"`
+	OverdueItems    []progressItem `json:"overdue_items"`
+	Unassigned      []progressItem `json:"unassigned"`
+	Scanned         int            `json:"scanned"`
+	Truncated       bool           `json:"truncated,omitempty"`
+}
+
+// buildMilestoneProgress summarizes m from its open items as of now.
+func buildMilestoneProgress(m *forgejo_sdk.Milestone, open []*forgejo_sdk.Issue, now time.Time) milestoneProgress {
+	p := milestoneProgress{
+		ID:           m.ID,
+		Title:        m.Title,
+		State:        string(m.State),
+		Open:         m.OpenIssues,
+		Closed:       m.ClosedIssues,
+		Total:        m.OpenIssues + m.ClosedIssues,
+		OverdueItems: []progressItem{},
+		Unassigned:   []progressItem{},
+		Scanned:      len(open),
+	}
+	if p.Total > 0 {
+		p.PercentComplete = p.Closed * 100 / p.Total
+	}
+	if m.Deadline != nil {
+		p.DueOn = m.Deadline.Format(resourceTimeFormat)
+		p.Overdue = m.State == forgejo_sdk.StateOpen && m.Deadline.Before(now)
+	}
+	for _, iss := range open {
+		item := progressItem{Index: iss.Index, Title: iss.Title, IsPull: iss.PullRequest != nil}
+		for _, a := range iss.Assignees {
+			if a != nil {
+				item.Assignees = append(item.Assignees, a.UserName)
+			}
+		}
+		due := m.Deadline
+		if iss.Deadline != nil {
+			due = iss.Deadline
+			item.DueDate = iss.Deadline.Format(resourceTimeFormat)
+		}
+		if due != nil && due.Before(now) {
+			p.OverdueItems = append(p.OverdueItems, item)
+		}
+		if len(item.Assignees) == 0 {
+			p.Unassigned = append(p.Unassigned, item)
+		}
+	}
+	return p
+}
+
+func GetMilestoneProgressFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called GetMilestoneProgressFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	milestone, _ := req.GetArguments()["milestone"].(string)
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	m, err := fetchMilestone(client, owner, repo, milestone)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	const pageSize = 50
+	var open []*forgejo_sdk.Issue
+	truncated := false
+	for page := 1; ; page++ {
+		issues, resp, err := client.ListRepoIssues(owner, repo, forgejo_sdk.ListIssueOption{
+			ListOptions: forgejo_sdk.ListOptions{Page: page, PageSize: pageSize},
+			State:       forgejo_sdk.StateOpen,
+			Milestones:  []string{strconv.FormatInt(m.ID, 10)},
+		})
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("list milestone issues err: %w", err))
+		}
+		open = append(open, issues...)
+		if len(open) >= milestoneProgressScanCap {
+			truncated = len(open) > milestoneProgressScanCap || hasMore(resp)
+			open = open[:milestoneProgressScanCap]
+			break
+		}
+		if len(issues) == 0 || !hasMore(resp) {
+			break
+		}
+	}
+
+	progress := buildMilestoneProgress(m, open, time.Now())
+	progress.Truncated = truncated
+	return to.TextResult(progress)
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func milestoneCall(t *testing.T, fn func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any, out any) {
+	t.Helper()
+	args["owner"], args["repo"] = "alice", "demo"
+	res, err := fn(context.Background(), makeReq(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultJSON(t, res, out)
+}
+
+func TestMilestoneLifecycle(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+
+	var created forgejo_sdk.Milestone
+	milestoneCall(t, CreateMilestoneFn, map[string]any{
+		"title": "v1.0", "description": "first cut", "due_date": "2026-08-20T00:00:00Z",
+	}, &created)
+	if created.Title != "v1.0" || created.Deadline == nil || created.State != forgejo_sdk.StateOpen {
+		t.Fatalf("created = %+v", created)
+	}
+
+	var edited forgejo_sdk.Milestone
+	milestoneCall(t, EditMilestoneFn, map[string]any{"milestone": "v1.0", "title": "v1.0.0", "state": "closed"}, &edited)
+	if edited.ID != created.ID || edited.Title != "v1.0.0" || edited.State != forgejo_sdk.StateClosed ||
+		edited.Description != "first cut" || edited.Closed == nil {
+		t.Fatalf("edited = %+v", edited)
+	}
+
+	var got forgejo_sdk.Milestone
+	milestoneCall(t, GetMilestoneFn, map[string]any{"milestone": "v1.0.0"}, &got)
+	if got.ID != created.ID {
+		t.Fatalf("get by title = %+v", got)
+	}
+
+	var msg string
+	milestoneCall(t, DeleteMilestoneFn, map[string]any{"milestone": "v1.0.0"}, &msg)
+	_, err := GetMilestoneFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo", "milestone": "v1.0.0"}))
+	if err == nil || !strings.Contains(err.Error(), "unknown milestone") {
+		t.Fatalf("get after delete: %v", err)
+	}
+
+	for name, args := range map[string]map[string]any{
+		"edit nothing":  {"milestone": "x"},
+		"bad due date":  {"milestone": "x", "due_date": "tomorrow"},
+		"missing title": {"milestone": "nope", "title": "y"},
+	} {
+		args["owner"], args["repo"] = "alice", "demo"
+		if _, err := EditMilestoneFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestMilestoneProgressAndResource(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	var m forgejo_sdk.Milestone
+	milestoneCall(t, CreateMilestoneFn, map[string]any{"title": "sprint", "due_date": "2025-01-31T00:00:00Z"}, &m)
+
+	for _, args := range []map[string]any{
+		{"title": "no due date, unassigned"},
+		{"title": "due later", "due_date": "2099-01-01T00:00:00Z", "assignees": "forgejotest"},
+		{"title": "done", "assignees": "forgejotest"},
+	} {
+		args["milestone"] = "sprint"
+		var issue forgejo_sdk.Issue
+		milestoneCall(t, CreateIssueFn, args, &issue)
+	}
+	srv.CreateIssue("alice", "demo", "elsewhere", "")
+	if _, err := IssueStateChangeFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(3), "state": "closed",
+	})); err != nil {
+		t.Fatal(err)
+	}
+
+	var p milestoneProgress
+	milestoneCall(t, GetMilestoneProgressFn, map[string]any{"milestone": "sprint"}, &p)
+	if p.Open != 2 || p.Closed != 1 || p.Total != 3 || p.PercentComplete != 33 || !p.Overdue || p.Scanned != 2 {
+		t.Fatalf("progress = %+v", p)
+	}
+	if len(p.OverdueItems) != 1 || p.OverdueItems[0].Index != 1 {
+		t.Errorf("overdue = %+v", p.OverdueItems)
+	}
+	if len(p.Unassigned) != 1 || p.Unassigned[0].Index != 1 {
+		t.Errorf("unassigned = %+v", p.Unassigned)
+	}
+
+	contents, err := milestoneResourceHandler(context.Background(), mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: "forgejo://repo/alice/demo/milestone/" + strconv.FormatInt(m.ID, 10)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload milestoneResourcePayload
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Title != "sprint" || payload.OpenIssues != 2 || payload.ClosedIssues != 1 || len(payload.Issues) != 3 || payload.Truncated {
+		t.Fatalf("resource = %+v", payload)
+	}
+
+	_, err = milestoneResourceHandler(context.Background(), mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: "forgejo://repo/alice/demo/milestone/999"},
+	})
+	if err == nil {
+		t.Fatal("unknown milestone: want error")
+	}
+}
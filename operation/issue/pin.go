@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	ListPinnedIssuesToolName = "list_pinned_issues"
+	PinIssueToolName         = "pin_issue"
+	UnpinIssueToolName       = "unpin_issue"
+	MovePinnedIssueToolName  = "move_pinned_issue"
+)
+
+var (
+	ListPinnedIssuesTool = mcp.NewTool(
+		ListPinnedIssuesToolName,
+		mcp.WithDescription("List the issues pinned to the top of a repository's issue list, in pin order."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	)
+
+	PinIssueTool = mcp.NewTool(
+		PinIssueToolName,
+		mcp.WithDescription("Pin an issue to the top of the repository's issue list, after the issues already pinned. Forgejo limits how many issues a repository can pin (3 by default)."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	)
+
+	UnpinIssueTool = mcp.NewTool(
+		UnpinIssueToolName,
+		mcp.WithDescription("Unpin an issue; the remaining pinned issues close the gap."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	)
+
+	MovePinnedIssueTool = mcp.NewTool(
+		MovePinnedIssueToolName,
+		mcp.WithDescription("Move a pinned issue to another position among the pinned issues. See list_pinned_issues for the current order."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithNumber("position", mcp.Required(), mcp.Description("New 1-based position; 1 is shown first")),
+	)
+)
+
+func RegisterPinTool(s *server.MCPServer) {
+	s.AddTool(ListPinnedIssuesTool, ListPinnedIssuesFn)
+	s.AddTool(PinIssueTool, PinIssueFn)
+	s.AddTool(UnpinIssueTool, UnpinIssueFn)
+	s.AddTool(MovePinnedIssueTool, MovePinnedIssueFn)
+}
+
+func fetchPinnedIssues(ctx context.Context, owner, repo string) ([]*issueDetail, error) {
+	issues := []*issueDetail{}
+	if err := forgejo.DoJSONList(ctx, http.MethodGet, forgejo.APIPath("repos", owner, repo, "issues", "pinned"), &issues); err != nil {
+		return nil, err
+	}
+	for _, issue := range issues {
+		issue.IsPinned = issue.PinOrder > 0
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].PinOrder < issues[j].PinOrder })
+	return issues, nil
+}
+
+// issuePinOrder is the best-effort pin position the issue resource embeds;
+// 0 means not pinned or unknown.
+func issuePinOrder(ctx context.Context, owner, repo string, index int64) int {
+	issues, err := fetchPinnedIssues(ctx, owner, repo)
+	if err != nil {
+		log.Debugf("pinned issues %s/%s: %v", owner, repo, err)
+		return 0
+	}
+	for _, issue := range issues {
+		if issue.Index == index {
+			return issue.PinOrder
+		}
+	}
+	return 0
+}
+
+func ListPinnedIssuesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListPinnedIssuesFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+
+	issues, err := fetchPinnedIssues(ctx, owner, repo)
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("list pinned issues err: %w", err))
+	}
+	return to.TextResult(issues)
+}
+
+func PinIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called PinIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "pin")
+	if err := forgejo.DoJSON(ctx, http.MethodPost, path, nil, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("pin issue err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("Pinned issue #%d", int64(index)))
+}
+
+func UnpinIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called UnpinIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "pin")
+	if err := forgejo.DoJSON(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("unpin issue err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("Unpinned issue #%d", int64(index)))
+}
+
+func MovePinnedIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called MovePinnedIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	position, _ := to.Float64(req.GetArguments()["position"])
+	if position < 1 {
+		return to.ErrorResult(fmt.Errorf("position must be 1 or greater"))
+	}
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "pin", int64(position))
+	if err := forgejo.DoJSON(ctx, http.MethodPatch, path, nil, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("move pinned issue err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("Moved pinned issue #%d to position %d", int64(index), int64(position)))
+}
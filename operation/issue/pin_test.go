@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func pinnedOrder(t *testing.T) []int64 {
+	t.Helper()
+	res, err := ListPinnedIssuesFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issues []issueDetail
+	resultJSON(t, res, &issues)
+	order := []int64{}
+	for i, issue := range issues {
+		if issue.PinOrder != i+1 || !issue.IsPinned {
+			t.Fatalf("pinned[%d] = #%d pin_order %d", i, issue.Index, issue.PinOrder)
+		}
+		order = append(order, issue.Index)
+	}
+	return order
+}
+
+func TestPinIssues(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	for _, title := range []string{"status", "known issues", "roadmap", "faq"} {
+		srv.CreateIssue("alice", "demo", title, "")
+	}
+	ctx := context.Background()
+	args := func(index int64, extra ...any) map[string]any {
+		a := map[string]any{"owner": "alice", "repo": "demo", "index": float64(index)}
+		for i := 0; i+1 < len(extra); i += 2 {
+			a[extra[i].(string)] = extra[i+1]
+		}
+		return a
+	}
+
+	for _, index := range []int64{1, 2, 3} {
+		if _, err := PinIssueFn(ctx, makeReq(args(index))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := PinIssueFn(ctx, makeReq(args(4))); err == nil {
+		t.Error("pinning past the limit: want error")
+	}
+
+	if _, err := MovePinnedIssueFn(ctx, makeReq(args(3, "position", float64(1)))); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedOrder(t); len(got) != 3 || got[0] != 3 || got[1] != 1 || got[2] != 2 {
+		t.Fatalf("order after move = %v", got)
+	}
+	if _, err := MovePinnedIssueFn(ctx, makeReq(args(3, "position", float64(0)))); err == nil {
+		t.Error("position 0: want error")
+	}
+
+	if _, err := UnpinIssueFn(ctx, makeReq(args(1))); err != nil {
+		t.Fatal(err)
+	}
+	if got := pinnedOrder(t); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Fatalf("order after unpin = %v", got)
+	}
+
+	res, err := GetIssueByIndexFn(ctx, makeReq(args(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var detail issueDetail
+	resultJSON(t, res, &detail)
+	if !detail.IsPinned || detail.PinOrder != 2 || detail.Title != "known issues" {
+		t.Errorf("get_issue_by_index = pinned %v order %d title %q", detail.IsPinned, detail.PinOrder, detail.Title)
+	}
+
+	contents, err := issueResourceHandler(ctx, makeIssueResourceRequest("alice", "demo", 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload issueResourcePayload
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !payload.IsPinned || payload.PinOrder != 1 {
+		t.Errorf("resource pin = %v/%d", payload.IsPinned, payload.PinOrder)
+	}
+}
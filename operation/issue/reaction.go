@@ -0,0 +1,288 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	ListIssueReactionsToolName         = "list_issue_reactions"
+	AddIssueReactionToolName           = "add_issue_reaction"
+	RemoveIssueReactionToolName        = "remove_issue_reaction"
+	ListIssueCommentReactionsToolName  = "list_issue_comment_reactions"
+	AddIssueCommentReactionToolName    = "add_issue_comment_reaction"
+	RemoveIssueCommentReactionToolName = "remove_issue_comment_reaction"
+	reactionContentDescription         = "Reaction: +1, -1, laugh, hooray, confused, heart, rocket or eyes (Forgejo's defaults; the instance may allow others). The matching emoji is accepted too."
+	reactionPullRequestNote            = " Pull requests are issues too: pass the PR index."
+)
+
+var (
+	ListIssueReactionsTool = mcp.NewTool(
+		ListIssueReactionsToolName,
+		mcp.WithDescription("List reactions on an issue or pull request, with a per-reaction summary (count and users) sorted by count."+reactionPullRequestNote),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithNumber("page", mcp.Description(params.Page), mcp.DefaultNumber(1)),
+		mcp.WithNumber("limit", mcp.Description(params.Limit), mcp.DefaultNumber(50)),
+	)
+
+	AddIssueReactionTool = mcp.NewTool(
+		AddIssueReactionToolName,
+		mcp.WithDescription("Add a reaction to an issue or pull request as the authenticated user. Adding a reaction the user already gave is a no-op."+reactionPullRequestNote),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithString("content", mcp.Required(), mcp.Description(reactionContentDescription)),
+	)
+
+	RemoveIssueReactionTool = mcp.NewTool(
+		RemoveIssueReactionToolName,
+		mcp.WithDescription("Remove the authenticated user's reaction from an issue or pull request."+reactionPullRequestNote),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithString("content", mcp.Required(), mcp.Description(reactionContentDescription)),
+	)
+
+	ListIssueCommentReactionsTool = mcp.NewTool(
+		ListIssueCommentReactionsToolName,
+		mcp.WithDescription("List reactions on an issue or pull request comment, with a per-reaction summary (count and users) sorted by count."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("comment_id", mcp.Required(), mcp.Description(params.CommentID)),
+	)
+
+	AddIssueCommentReactionTool = mcp.NewTool(
+		AddIssueCommentReactionToolName,
+		mcp.WithDescription("Add a reaction to an issue or pull request comment as the authenticated user, e.g. to acknowledge it without adding a reply. Adding a reaction the user already gave is a no-op."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("comment_id", mcp.Required(), mcp.Description(params.CommentID)),
+		mcp.WithString("content", mcp.Required(), mcp.Description(reactionContentDescription)),
+	)
+
+	RemoveIssueCommentReactionTool = mcp.NewTool(
+		RemoveIssueCommentReactionToolName,
+		mcp.WithDescription("Remove the authenticated user's reaction from an issue or pull request comment."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("comment_id", mcp.Required(), mcp.Description(params.CommentID)),
+		mcp.WithString("content", mcp.Required(), mcp.Description(reactionContentDescription)),
+	)
+)
+
+func RegisterReactionTool(s *server.MCPServer) {
+	s.AddTool(ListIssueReactionsTool, ListIssueReactionsFn)
+	s.AddTool(AddIssueReactionTool, AddIssueReactionFn)
+	s.AddTool(RemoveIssueReactionTool, RemoveIssueReactionFn)
+	s.AddTool(ListIssueCommentReactionsTool, ListIssueCommentReactionsFn)
+	s.AddTool(AddIssueCommentReactionTool, AddIssueCommentReactionFn)
+	s.AddTool(RemoveIssueCommentReactionTool, RemoveIssueCommentReactionFn)
+}
+
+// reactionAliases maps the emoji of Forgejo's default reactions to their
+// API names.
+var reactionAliases = map[string]string{
+	"👍":  "+1",
+	"👎":  "-1",
+	"😄":  "laugh",
+	"🎉":  "hooray",
+	"😕":  "confused",
+	"❤️": "heart",
+	"❤":  "heart",
+	"🚀":  "rocket",
+	"👀":  "eyes",
+}
+
+func normalizeReaction(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if alias, ok := reactionAliases[content]; ok {
+		return alias, nil
+	}
+	content = strings.TrimSuffix(strings.TrimPrefix(content, ":"), ":")
+	if content == "" {
+		return "", fmt.Errorf("content is required")
+	}
+	return content, nil
+}
+
+// reactionOption is the POST/DELETE body of the /reactions endpoints.
+type reactionOption struct {
+	Content string `json:"content"`
+}
+
+// ReactionSummary counts one reaction and who gave it.
+type ReactionSummary struct {
+	Content string   `json:"content"`
+	Count   int      `json:"count"`
+	Users   []string `json:"users"`
+}
+
+type reactionsResult struct {
+	Summary   []ReactionSummary       `json:"summary"`
+	Reactions []*forgejo_sdk.Reaction `json:"reactions"`
+	Page      int                     `json:"page,omitempty"`
+	Limit     int                     `json:"limit,omitempty"`
+}
+
+// summarizeReactions groups reactions by content, most frequent first.
+func summarizeReactions(reactions []*forgejo_sdk.Reaction) []ReactionSummary {
+	byContent := map[string]*ReactionSummary{}
+	out := []ReactionSummary{}
+	for _, r := range reactions {
+		s, ok := byContent[r.Reaction]
+		if !ok {
+			out = append(out, ReactionSummary{Content: r.Reaction, Users: []string{}})
+			s = &out[len(out)-1]
+			byContent[r.Reaction] = s
+		}
+		s.Count++
+		if r.User != nil {
+			s.Users = append(s.Users, r.User.UserName)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Content < out[j].Content
+	})
+	return out
+}
+
+func issueReactionsPath(owner, repo string, index int64) string {
+	return forgejo.APIPath("repos", owner, repo, "issues", index, "reactions")
+}
+
+func commentReactionsPath(owner, repo string, id int64) string {
+	return forgejo.APIPath("repos", owner, repo, "issues", "comments", id, "reactions")
+}
+
+// fetchReactionSummary is the best-effort summary the issue and comment
+// resources embed: reactions may be disabled, so any failure yields nil.
+func fetchReactionSummary(ctx context.Context, path string) []ReactionSummary {
+	var reactions []*forgejo_sdk.Reaction
+	if err := forgejo.DoJSONList(ctx, http.MethodGet, path, &reactions); err != nil {
+		log.Debugf("reactions %s: %v", path, err)
+		return nil
+	}
+	return summarizeReactions(reactions)
+}
+
+func listReactions(ctx context.Context, path string, page, limit int) (*mcp.CallToolResult, error) {
+	if page > 0 {
+		path += fmt.Sprintf("?page=%d&limit=%d", page, limit)
+	}
+	reactions := []*forgejo_sdk.Reaction{}
+	if err := forgejo.DoJSONList(ctx, http.MethodGet, path, &reactions); err != nil {
+		return to.ErrorResult(fmt.Errorf("list reactions err: %w", err))
+	}
+	if reactions == nil {
+		reactions = []*forgejo_sdk.Reaction{}
+	}
+	return to.TextResult(reactionsResult{
+		Summary:   summarizeReactions(reactions),
+		Reactions: reactions,
+		Page:      page,
+		Limit:     limit,
+	})
+}
+
+func addReaction(ctx context.Context, path, content string) (*mcp.CallToolResult, error) {
+	content, err := normalizeReaction(content)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	var reaction forgejo_sdk.Reaction
+	if err := forgejo.DoJSON(ctx, http.MethodPost, path, reactionOption{Content: content}, &reaction); err != nil {
+		return to.ErrorResult(fmt.Errorf("add reaction err: %w", err))
+	}
+	return to.TextResult(reaction)
+}
+
+func removeReaction(ctx context.Context, path, content string) (*mcp.CallToolResult, error) {
+	content, err := normalizeReaction(content)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	if err := forgejo.DoJSON(ctx, http.MethodDelete, path, reactionOption{Content: content}, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("remove reaction err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("Removed reaction %s", content))
+}
+
+func ListIssueReactionsFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListIssueReactionsFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	page, _ := to.Float64(req.GetArguments()["page"])
+	if page == 0 {
+		page = 1
+	}
+	limit, _ := to.Float64(req.GetArguments()["limit"])
+	if limit == 0 {
+		limit = 50
+	}
+	return listReactions(ctx, issueReactionsPath(owner, repo, int64(index)), int(page), int(limit))
+}
+
+func AddIssueReactionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called AddIssueReactionFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	content, _ := req.GetArguments()["content"].(string)
+	return addReaction(ctx, issueReactionsPath(owner, repo, int64(index)), content)
+}
+
+func RemoveIssueReactionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called RemoveIssueReactionFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	content, _ := req.GetArguments()["content"].(string)
+	return removeReaction(ctx, issueReactionsPath(owner, repo, int64(index)), content)
+}
+
+func ListIssueCommentReactionsFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListIssueCommentReactionsFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	id, _ := to.Float64(req.GetArguments()["comment_id"])
+	// The comment endpoint is not paginated.
+	return listReactions(ctx, commentReactionsPath(owner, repo, int64(id)), 0, 0)
+}
+
+func AddIssueCommentReactionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called AddIssueCommentReactionFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	id, _ := to.Float64(req.GetArguments()["comment_id"])
+	content, _ := req.GetArguments()["content"].(string)
+	return addReaction(ctx, commentReactionsPath(owner, repo, int64(id)), content)
+}
+
+func RemoveIssueCommentReactionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called RemoveIssueCommentReactionFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	id, _ := to.Float64(req.GetArguments()["comment_id"])
+	content, _ := req.GetArguments()["content"].(string)
+	return removeReaction(ctx, commentReactionsPath(owner, repo, int64(id)), content)
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func reactionServer(t *testing.T) *forgejo_sdk.Comment {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "crash on start", "")
+	res, err := CreateIssueCommentFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(1), "body": "same here",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comment forgejo_sdk.Comment
+	resultJSON(t, res, &comment)
+	return &comment
+}
+
+func reactionArgs(extra map[string]any) map[string]any {
+	args := map[string]any{"owner": "alice", "repo": "demo"}
+	for k, v := range extra {
+		args[k] = v
+	}
+	return args
+}
+
+func TestIssueReactions(t *testing.T) {
+	reactionServer(t)
+	ctx := context.Background()
+
+	for _, content := range []string{"+1", "👀", ":+1:"} {
+		if _, err := AddIssueReactionFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1), "content": content}))); err != nil {
+			t.Fatalf("add %q: %v", content, err)
+		}
+	}
+
+	res, err := ListIssueReactionsFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out reactionsResult
+	resultJSON(t, res, &out)
+	// The repeated +1 is a no-op, as in Forgejo.
+	if len(out.Reactions) != 2 || len(out.Summary) != 2 {
+		t.Fatalf("reactions = %+v", out)
+	}
+	if s := out.Summary[0]; s.Content != "+1" || s.Count != 1 || len(s.Users) != 1 || s.Users[0] != "forgejotest" {
+		t.Errorf("summary[0] = %+v", s)
+	}
+
+	if _, err := RemoveIssueReactionFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1), "content": "👍"}))); err != nil {
+		t.Fatal(err)
+	}
+	res, err = ListIssueReactionsFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultJSON(t, res, &out)
+	if len(out.Summary) != 1 || out.Summary[0].Content != "eyes" {
+		t.Fatalf("after remove = %+v", out.Summary)
+	}
+
+	if _, err := AddIssueReactionFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1), "content": "shrug"}))); err == nil {
+		t.Error("disallowed reaction: want error")
+	}
+	if _, err := AddIssueReactionFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1), "content": " "}))); err == nil {
+		t.Error("empty reaction: want error")
+	}
+}
+
+func TestIssueCommentReactions(t *testing.T) {
+	comment := reactionServer(t)
+	ctx := context.Background()
+	args := func(content string) map[string]any {
+		return reactionArgs(map[string]any{"comment_id": float64(comment.ID), "content": content})
+	}
+
+	res, err := AddIssueCommentReactionFn(ctx, makeReq(args("heart")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reaction forgejo_sdk.Reaction
+	resultJSON(t, res, &reaction)
+	if reaction.Reaction != "heart" {
+		t.Fatalf("reaction = %+v", reaction)
+	}
+
+	res, err = ListIssueCommentReactionsFn(ctx, makeReq(args("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out reactionsResult
+	resultJSON(t, res, &out)
+	if len(out.Summary) != 1 || out.Summary[0].Content != "heart" || out.Summary[0].Count != 1 {
+		t.Fatalf("summary = %+v", out.Summary)
+	}
+
+	// Issue reactions are separate from comment reactions.
+	res, err = ListIssueReactionsFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1)})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultJSON(t, res, &out)
+	if len(out.Reactions) != 0 {
+		t.Fatalf("issue reactions = %+v", out.Reactions)
+	}
+
+	if _, err := RemoveIssueCommentReactionFn(ctx, makeReq(args("❤️"))); err != nil {
+		t.Fatal(err)
+	}
+	res, err = ListIssueCommentReactionsFn(ctx, makeReq(args("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resultJSON(t, res, &out)
+	if len(out.Reactions) != 0 {
+		t.Fatalf("after remove = %+v", out.Reactions)
+	}
+}
+
+func TestResourcesIncludeReactionSummary(t *testing.T) {
+	comment := reactionServer(t)
+	ctx := context.Background()
+	for _, content := range []string{"+1", "rocket"} {
+		if _, err := AddIssueReactionFn(ctx, makeReq(reactionArgs(map[string]any{"index": float64(1), "content": content}))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := AddIssueCommentReactionFn(ctx, makeReq(reactionArgs(map[string]any{"comment_id": float64(comment.ID), "content": "+1"}))); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := issueResourceHandler(ctx, makeIssueResourceRequest("alice", "demo", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issue issueResourcePayload
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &issue); err != nil {
+		t.Fatal(err)
+	}
+	if len(issue.Reactions) != 2 {
+		t.Errorf("issue reactions = %+v", issue.Reactions)
+	}
+
+	contents, err = commentResourceHandler(ctx, makeCommentResourceRequest("alice", "demo", "issue", 1, int(comment.ID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c commentResourcePayload
+	if err := json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Reactions) != 1 || c.Reactions[0].Content != "+1" || c.Reactions[0].Count != 1 {
+		t.Errorf("comment reactions = %+v", c.Reactions)
+	}
+}
@@ -131,23 +131,27 @@ type commentRef struct {
 }
 
 type issueResourcePayload struct {
-	Owner          string       `json:"owner"`
-	Repo           string       `json:"repo"`
-	Index          int64        `json:"index"`
-	Title          string       `json:"title"`
-	State          string       `json:"state"`
-	Author         string       `json:"author"`
-	CreatedAt      string       `json:"created_at"`
-	UpdatedAt      string       `json:"updated_at"`
-	ClosedAt       string       `json:"closed_at,omitempty"`
-	DueDate        string       `json:"due_date,omitempty"`
-	Labels         []string     `json:"labels"`
-	Assignees      []string     `json:"assignees"`
-	Milestone      string       `json:"milestone,omitempty"`
-	CommentCount   int          `json:"comment_count"`
-	RecentComments []commentRef `json:"recent_comments"`
-	Truncated      bool         `json:"truncated,omitempty"`
-	ListTool       string       `json:"list_tool,omitempty"`
+	Owner          string            `json:"owner"`
+	Repo           string            `json:"repo"`
+	Index          int64             `json:"index"`
+	Title          string            `json:"title"`
+	State          string            `json:"state"`
+	Author         string            `json:"author"`
+	CreatedAt      string            `json:"created_at"`
+	UpdatedAt      string            `json:"updated_at"`
+	ClosedAt       string            `json:"closed_at,omitempty"`
+	DueDate        string            `json:"due_date,omitempty"`
+	Labels         []string          `json:"labels"`
+	Assignees      []string          `json:"assignees"`
+	Milestone      string            `json:"milestone,omitempty"`
+	CommentCount   int               `json:"comment_count"`
+	IsLocked       bool              `json:"is_locked"`
+	IsPinned       bool              `json:"is_pinned"`
+	PinOrder       int               `json:"pin_order,omitempty"`
+	RecentComments []commentRef      `json:"recent_comments"`
+	Truncated      bool              `json:"truncated,omitempty"`
+	ListTool       string            `json:"list_tool,omitempty"`
+	Reactions      []ReactionSummary `json:"reactions,omitempty"`
 }
 
 func issueResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
@@ -228,6 +232,7 @@ func issueResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]m
 		dueDate = iss.Deadline.Format("2006-01-02T15:04:05Z07:00")
 	}
 
+	pinOrder := issuePinOrder(ctx, params.Owner, params.Repo, params.Index)
 	payload := issueResourcePayload{
 		Owner:          params.Owner,
 		Repo:           params.Repo,
@@ -243,8 +248,12 @@ func issueResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]m
 		Assignees:      assignees,
 		Milestone:      milestone,
 		CommentCount:   iss.Comments,
+		IsLocked:       iss.IsLocked,
+		IsPinned:       pinOrder > 0,
+		PinOrder:       pinOrder,
 		RecentComments: boundedRefs,
 		Truncated:      bounded.Truncated,
+		Reactions:      fetchReactionSummary(ctx, issueReactionsPath(params.Owner, params.Repo, params.Index)),
 	}
 	if bounded.Truncated {
 		payload.ListTool = "list_issue_comments"
@@ -267,16 +276,17 @@ func issueResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]m
 // ---- comment handler ----
 
 type commentResourcePayload struct {
-	Owner     string `json:"owner"`
-	Repo      string `json:"repo"`
-	Kind      string `json:"kind"`
-	Index     int64  `json:"index"`
-	ID        int64  `json:"id"`
-	Author    string `json:"author"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
-	Body      string `json:"body"`
-	HTMLURL   string `json:"html_url"`
+	Owner     string            `json:"owner"`
+	Repo      string            `json:"repo"`
+	Kind      string            `json:"kind"`
+	Index     int64             `json:"index"`
+	ID        int64             `json:"id"`
+	Author    string            `json:"author"`
+	CreatedAt string            `json:"created_at"`
+	UpdatedAt string            `json:"updated_at"`
+	Body      string            `json:"body"`
+	HTMLURL   string            `json:"html_url"`
+	Reactions []ReactionSummary `json:"reactions,omitempty"`
 }
 
 func commentResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
@@ -318,6 +328,7 @@ func commentResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([
 		UpdatedAt: c.Updated.Format("2006-01-02T15:04:05Z07:00"),
 		Body:      c.Body,
 		HTMLURL:   c.HTMLURL,
+		Reactions: fetchReactionSummary(ctx, commentReactionsPath(params.Owner, params.Repo, c.ID)),
 	}
 
 	jsonBytes, err := json.Marshal(payload)
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/resource"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RegisterMilestoneResources registers the milestone resource template.
+func RegisterMilestoneResources(s *server.MCPServer) {
+	resource.RegisterTemplate(
+		s,
+		"forgejo://repo/{owner}/{repo}/milestone/{id}",
+		"Forgejo Milestone",
+		milestoneResourceHandler,
+		mcp.WithTemplateDescription(
+			"Single milestone by id with its open/closed counts and a bounded list of its issues and PRs (cap "+
+				strconv.Itoa(resource.EmbeddedListCap)+", newest first). "+
+				"URI: forgejo://repo/{owner}/{repo}/milestone/{id}. "+
+				"Use list_repo_issues with milestones={id} for the full list, get_milestone_progress for a summary.",
+		),
+		mcp.WithTemplateMIMEType("application/json"),
+	)
+	log.Debug("Registered milestone resource template")
+}
+
+type milestoneResourcePayload struct {
+	Owner        string     `json:"owner"`
+	Repo         string     `json:"repo"`
+	ID           int64      `json:"id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	State        string     `json:"state"`
+	DueOn        string     `json:"due_on,omitempty"`
+	ClosedAt     string     `json:"closed_at,omitempty"`
+	OpenIssues   int        `json:"open_issues"`
+	ClosedIssues int        `json:"closed_issues"`
+	Issues       []issueRef `json:"issues"`
+	Truncated    bool       `json:"truncated,omitempty"`
+	ListTool     string     `json:"list_tool,omitempty"`
+	Sentinel     string     `json:"sentinel,omitempty"`
+}
+
+func milestoneResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := req.Params.URI
+	p, err := resource.ParseMilestone(uri)
+	if err != nil {
+		return nil, resource.MapForgejoError(uri, err)
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("forgejo client: %w", err)
+	}
+
+	m, resp, err := client.GetMilestone(p.Owner, p.Repo, p.ID)
+	if err != nil {
+		if resp != nil {
+			return nil, resource.MapForgejoError(uri, fmt.Errorf("%d %s", resp.StatusCode, err.Error()))
+		}
+		return nil, resource.MapForgejoError(uri, err)
+	}
+
+	// One page of exactly the cap; "more exists" comes from the Link header,
+	// as in the issues list resource.
+	rawIssues, resp, err := client.ListRepoIssues(p.Owner, p.Repo, forgejo_sdk.ListIssueOption{
+		ListOptions: forgejo_sdk.ListOptions{Page: 1, PageSize: resource.EmbeddedListCap},
+		State:       forgejo_sdk.StateAll,
+		Milestones:  []string{strconv.FormatInt(m.ID, 10)},
+	})
+	if err != nil {
+		if resp != nil {
+			return nil, resource.MapForgejoError(uri, fmt.Errorf("%d %s", resp.StatusCode, err.Error()))
+		}
+		return nil, resource.MapForgejoError(uri, err)
+	}
+
+	items := make([]string, len(rawIssues))
+	for i, iss := range rawIssues {
+		items[i] = strconv.FormatInt(iss.Index, 10)
+	}
+	bounded := resource.Bounded(items, resource.EmbeddedListCap, ListRepoIssuesToolName)
+	if hasMore(resp) {
+		bounded = bounded.WithMoreRemaining(totalCount(resp))
+	}
+	refs := make([]issueRef, 0, len(bounded.Items))
+	for _, iss := range rawIssues[:len(bounded.Items)] {
+		refs = append(refs, issueRefOf(iss))
+	}
+
+	payload := milestoneResourcePayload{
+		Owner:        p.Owner,
+		Repo:         p.Repo,
+		ID:           m.ID,
+		Title:        m.Title,
+		Description:  m.Description,
+		State:        string(m.State),
+		OpenIssues:   m.OpenIssues,
+		ClosedIssues: m.ClosedIssues,
+		Issues:       refs,
+		Truncated:    bounded.Truncated,
+	}
+	if m.Deadline != nil {
+		payload.DueOn = m.Deadline.Format(resourceTimeFormat)
+	}
+	if m.Closed != nil {
+		payload.ClosedAt = m.Closed.Format(resourceTimeFormat)
+	}
+	if bounded.Truncated {
+		payload.ListTool = ListRepoIssuesToolName
+		payload.Sentinel = bounded.Sentinel()
+	}
+
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal milestone payload: %w", err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: "application/json", Text: string(jsonBytes)},
+	}, nil
+}
@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/resource"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const FindSimilarIssuesToolName = "find_similar_issues"
+
+const (
+	// similarKeywords is how many draft terms are sent to Forgejo's keyword
+	// search, and similarPageSize how many candidates each may return.
+	similarKeywords = 5
+	similarPageSize = 50
+	similarMaxLimit = 20
+	// defaultDuplicateThreshold is the score from which create_issue's
+	// check_duplicates treats a match as a duplicate.
+	defaultDuplicateThreshold = 0.5
+)
+
+var FindSimilarIssuesTool = mcp.NewTool(
+	FindSimilarIssuesToolName,
+	mcp.WithDescription("Find existing issues similar to a draft before filing it. Candidates come from Forgejo's keyword search for the draft's most distinctive title terms (up to "+fmt.Sprint(similarKeywords)+" searches of "+fmt.Sprint(similarPageSize)+" issues each) and are ranked locally by TF-IDF cosine similarity over titles (weighted double) and bodies. "+
+		"Returns the top matches with a score between 0 and 1, the shared terms and a body excerpt, plus how many candidates were scanned. Scores of about 0.5 and above usually mean the same problem. create_issue can run the same check with check_duplicates."),
+	mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+	mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	mcp.WithString("title", mcp.Required(), mcp.Description("Draft issue title")),
+	mcp.WithString("body", mcp.Description("Draft issue body")),
+	mcp.WithString("state", mcp.Description("Issue state to search (open|closed|all); closed issues catch already-fixed duplicates"), mcp.Enum("open", "closed", "all"), mcp.DefaultString("all")),
+	mcp.WithNumber("limit", mcp.Description("Maximum number of matches to return (max "+fmt.Sprint(similarMaxLimit)+")"), mcp.DefaultNumber(5)),
+	mcp.WithNumber("min_score", mcp.Description("Drop matches scoring below this (0-1)"), mcp.DefaultNumber(0.1)),
+)
+
+func RegisterSimilarTool(s *server.MCPServer) {
+	s.AddTool(FindSimilarIssuesTool, FindSimilarIssuesFn)
+}
+
+type similarIssue struct {
+	Index        int64    `json:"index"`
+	Title        string   `json:"title"`
+	State        string   `json:"state"`
+	HTMLURL      string   `json:"html_url"`
+	Score        float64  `json:"score"`
+	MatchedTerms []string `json:"matched_terms,omitempty"`
+	Excerpt      string   `json:"excerpt,omitempty"`
+}
+
+type similarIssuesResult struct {
+	Matches  []similarIssue `json:"matches"`
+	Scanned  int            `json:"scanned"`
+	Keywords []string       `json:"keywords"`
+}
+
+// similarStopwords are dropped before weighting; they carry no signal about
+// what an issue is about.
+var similarStopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a an and are as at be been but by can could did do does doesn for from
+		had has have how i if in into is it its just me my no not of on or our should so some than that the
+		their them then there these they this to too up us was we were what when where which while who why
+		will with would you your`) {
+		similarStopwords[w] = true
+	}
+}
+
+// similarTerms splits text into lowercase word terms, dropping stopwords and
+// one-letter tokens and folding simple plurals ("crashes" → "crash").
+func similarTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 2 || similarStopwords[word] {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(word, "ies") && len(word) > 4:
+			word = strings.TrimSuffix(word, "ies") + "y"
+		case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"),
+			strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"):
+			word = strings.TrimSuffix(word, "es")
+		case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && len(word) > 3:
+			word = strings.TrimSuffix(word, "s")
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// similarCounts returns the term counts of an issue, titles counting double.
+func similarCounts(title, body string) map[string]float64 {
+	counts := map[string]float64{}
+	for _, term := range similarTerms(title) {
+		counts[term] += 2
+	}
+	for _, term := range similarTerms(body) {
+		counts[term]++
+	}
+	return counts
+}
+
+// similarKeywordsOf picks the draft terms to search for: distinct title
+// terms, longest first as a cheap stand-in for rarity, then the most
+// frequent body terms if the title is short.
+func similarKeywordsOf(title, body string) []string {
+	var keywords []string
+	seen := map[string]bool{}
+	add := func(terms []string) {
+		for _, term := range terms {
+			if len(keywords) == similarKeywords {
+				return
+			}
+			if !seen[term] && len(term) > 2 {
+				seen[term] = true
+				keywords = append(keywords, term)
+			}
+		}
+	}
+	titleTerms := similarTerms(title)
+	slices.SortStableFunc(titleTerms, func(a, b string) int { return len(b) - len(a) })
+	add(titleTerms)
+
+	bodyCounts := map[string]int{}
+	for _, term := range similarTerms(body) {
+		bodyCounts[term]++
+	}
+	bodyTerms := make([]string, 0, len(bodyCounts))
+	for term := range bodyCounts {
+		bodyTerms = append(bodyTerms, term)
+	}
+	slices.SortFunc(bodyTerms, func(a, b string) int {
+		if bodyCounts[a] != bodyCounts[b] {
+			return bodyCounts[b] - bodyCounts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	add(bodyTerms)
+	return keywords
+}
+
+// rankSimilarIssues scores candidates against the draft by cosine
+// similarity of sublinear TF-IDF vectors, with document frequencies taken
+// over the candidates plus the draft. Matches below minScore are dropped and
+// at most limit are returned, best first.
+func rankSimilarIssues(title, body string, candidates []*forgejo_sdk.Issue, limit int, minScore float64) []similarIssue {
+	draft := similarCounts(title, body)
+	docs := make([]map[string]float64, len(candidates))
+	df := map[string]int{}
+	for term := range draft {
+		df[term]++
+	}
+	for i, iss := range candidates {
+		docs[i] = similarCounts(iss.Title, iss.Body)
+		for term := range docs[i] {
+			df[term]++
+		}
+	}
+	n := float64(len(candidates) + 1)
+	weigh := func(counts map[string]float64) (map[string]float64, float64) {
+		vec := make(map[string]float64, len(counts))
+		var norm float64
+		for term, count := range counts {
+			w := (1 + math.Log(count)) * (math.Log((1+n)/(1+float64(df[term]))) + 1)
+			vec[term] = w
+			norm += w * w
+		}
+		return vec, math.Sqrt(norm)
+	}
+	draftVec, draftNorm := weigh(draft)
+
+	var matches []similarIssue
+	for i, iss := range candidates {
+		vec, norm := weigh(docs[i])
+		if norm == 0 || draftNorm == 0 {
+			continue
+		}
+		type shared struct {
+			term string
+			w    float64
+		}
+		var dot float64
+		var terms []shared
+		for term, w := range vec {
+			if dw, ok := draftVec[term]; ok {
+				dot += w * dw
+				terms = append(terms, shared{term, w * dw})
+			}
+		}
+		score := math.Round(dot/(norm*draftNorm)*1000) / 1000
+		if score <= 0 || score < minScore {
+			continue
+		}
+		slices.SortFunc(terms, func(a, b shared) int {
+			if a.w != b.w {
+				if a.w > b.w {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a.term, b.term)
+		})
+		matched := make([]string, 0, 5)
+		for _, s := range terms[:min(len(terms), 5)] {
+			matched = append(matched, s.term)
+		}
+		matches = append(matches, similarIssue{
+			Index:        iss.Index,
+			Title:        iss.Title,
+			State:        string(iss.State),
+			HTMLURL:      iss.HTMLURL,
+			Score:        score,
+			MatchedTerms: matched,
+			Excerpt:      resource.Excerpt(strings.Join(strings.Fields(iss.Body), " "), 200),
+		})
+	}
+	slices.SortStableFunc(matches, func(a, b similarIssue) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return int(b.Index - a.Index)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// findSimilarIssues gathers candidates with one keyword search per draft
+// keyword and ranks them; it backs both find_similar_issues and
+// create_issue's check_duplicates.
+func findSimilarIssues(client *forgejo_sdk.Client, owner, repo, title, body, state string, limit int, minScore float64) (similarIssuesResult, error) {
+	keywords := similarKeywordsOf(title, body)
+	result := similarIssuesResult{Matches: []similarIssue{}, Keywords: keywords}
+	seen := map[int64]bool{}
+	var candidates []*forgejo_sdk.Issue
+	for _, keyword := range keywords {
+		issues, _, err := client.ListRepoIssues(owner, repo, forgejo_sdk.ListIssueOption{
+			ListOptions: forgejo_sdk.ListOptions{Page: 1, PageSize: similarPageSize},
+			State:       forgejo_sdk.StateType(state),
+			Type:        forgejo_sdk.IssueTypeIssue,
+			KeyWord:     keyword,
+		})
+		if err != nil {
+			return result, fmt.Errorf("search issues for %q err: %w", keyword, err)
+		}
+		for _, iss := range issues {
+			if !seen[iss.Index] {
+				seen[iss.Index] = true
+				candidates = append(candidates, iss)
+			}
+		}
+	}
+	result.Scanned = len(candidates)
+	if matches := rankSimilarIssues(title, body, candidates, limit, minScore); matches != nil {
+		result.Matches = matches
+	}
+	return result, nil
+}
+
+func FindSimilarIssuesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called FindSimilarIssuesFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+	title, _ := args["title"].(string)
+	body, _ := args["body"].(string)
+	state, _ := args["state"].(string)
+	if state == "" {
+		state = string(forgejo_sdk.StateAll)
+	}
+	if strings.TrimSpace(title) == "" {
+		return to.ErrorResult(fmt.Errorf("title is required"))
+	}
+	limit := 5
+	if v, ok := to.Float64Ok(args["limit"]); ok && v >= 1 {
+		limit = min(int(v), similarMaxLimit)
+	}
+	minScore := 0.1
+	if v, ok := to.Float64Ok(args["min_score"]); ok {
+		minScore = v
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	result, err := findSimilarIssues(client, owner, repo, title, body, state, limit, minScore)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	return to.TextResult(result)
+}
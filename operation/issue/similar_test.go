@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+)
+
+const (
+	draftTitle = "Server crashes on large attachment upload"
+	draftBody  = "Uploading an attachment larger than 10 MB makes the server crash with error 500."
+)
+
+func similarServer(t *testing.T) *forgejotest.Server {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "Crash when uploading large attachments", "The server crashes with a 500 when an attachment over 10 MB is uploaded.")
+	srv.CreateIssue("alice", "demo", "Add dark mode to the settings page", "The settings page is too bright at night.")
+	srv.CreateIssue("alice", "demo", "Attachment upload progress bar", "Show upload progress for large attachments.")
+	srv.CreateIssue("alice", "demo", "Typo in README", "Server is misspelled as sever.")
+	return srv
+}
+
+func TestSimilarTerms(t *testing.T) {
+	got := similarTerms("The server CRASHES on uploads, e.g. 10MB files & categories")
+	want := []string{"server", "crash", "upload", "10mb", "file", "category"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("terms = %v, want %v", got, want)
+	}
+}
+
+func TestFindSimilarIssues_RanksDuplicateFirst(t *testing.T) {
+	similarServer(t)
+	res, err := FindSimilarIssuesFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "title": draftTitle, "body": draftBody,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out similarIssuesResult
+	resultJSON(t, res, &out)
+
+	// The dark mode issue shares no keyword with the draft and is never fetched.
+	if out.Scanned != 3 || len(out.Keywords) == 0 {
+		t.Fatalf("scanned %d with keywords %v", out.Scanned, out.Keywords)
+	}
+	if len(out.Matches) < 2 || out.Matches[0].Index != 1 || out.Matches[1].Index != 3 {
+		t.Fatalf("matches = %+v", out.Matches)
+	}
+	top := out.Matches[0]
+	if top.Score < defaultDuplicateThreshold || top.Score > 1 || out.Matches[1].Score >= defaultDuplicateThreshold {
+		t.Errorf("scores = %v, %v", top.Score, out.Matches[1].Score)
+	}
+	if !slices.Contains(top.MatchedTerms, "crash") || !strings.HasPrefix(top.Excerpt, "The server crashes") {
+		t.Errorf("top match = %+v", top)
+	}
+}
+
+func TestCreateIssue_CheckDuplicates(t *testing.T) {
+	similarServer(t)
+	create := func(mode string) (map[string]any, error) {
+		res, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+			"owner": "alice", "repo": "demo", "title": draftTitle, "body": draftBody, "check_duplicates": mode,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		var out map[string]any
+		resultJSON(t, res, &out)
+		return out, nil
+	}
+
+	_, err := create("refuse")
+	if err == nil || !strings.Contains(err.Error(), `#1 "Crash when uploading large attachments"`) {
+		t.Fatalf("refuse: err = %v", err)
+	}
+	if getIssue(t, 1).Title == draftTitle {
+		t.Fatal("unexpected overwrite")
+	}
+
+	out, err := create("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dups, _ := out["possible_duplicates"].([]any)
+	if out["number"] != float64(5) || len(dups) != 1 {
+		t.Fatalf("warn result = %v", out)
+	}
+
+	// A distinct draft is created without the extra key.
+	res, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "title": "Support webhooks for releases", "check_duplicates": "refuse",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plain map[string]any
+	resultJSON(t, res, &plain)
+	if _, ok := plain["possible_duplicates"]; ok || plain["number"] != float64(6) {
+		t.Fatalf("plain result = %v", plain)
+	}
+
+	if _, err := create("maybe"); err == nil {
+		t.Fatal("want error for unknown check_duplicates mode")
+	}
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	ListIssueSubscribersToolName   = "list_issue_subscribers"
+	SubscribeIssueToolName         = "subscribe_issue"
+	UnsubscribeIssueToolName       = "unsubscribe_issue"
+	CheckIssueSubscriptionToolName = "check_issue_subscription"
+)
+
+const subscriptionUserDescription = "Login of the user to (un)subscribe; defaults to the authenticated user. Only site admins may change other users' subscriptions."
+
+var (
+	ListIssueSubscribersTool = mcp.NewTool(
+		ListIssueSubscribersToolName,
+		mcp.WithDescription("List the users subscribed to an issue or pull request, i.e. who gets notified about it. Pagination uses page (1-based) and limit (page size); the response echoes both."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithNumber("page", mcp.Description(params.Page), mcp.DefaultNumber(1)),
+		mcp.WithNumber("limit", mcp.Description(params.Limit), mcp.DefaultNumber(20)),
+	)
+
+	SubscribeIssueTool = mcp.NewTool(
+		SubscribeIssueToolName,
+		mcp.WithDescription("Subscribe a user to an issue or pull request so they get its notifications (see check_notifications), e.g. the human owner when handing off work. Subscribing an existing subscriber is a no-op."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithString("user", mcp.Description(subscriptionUserDescription)),
+	)
+
+	UnsubscribeIssueTool = mcp.NewTool(
+		UnsubscribeIssueToolName,
+		mcp.WithDescription("Unsubscribe a user from an issue or pull request. Unsubscribing a non-subscriber is a no-op."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+		mcp.WithString("user", mcp.Description(subscriptionUserDescription)),
+	)
+
+	CheckIssueSubscriptionTool = mcp.NewTool(
+		CheckIssueSubscriptionToolName,
+		mcp.WithDescription("Check whether the authenticated user is subscribed to an issue or pull request (subscribed) or has muted it (ignored)."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+		mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	)
+)
+
+// subscribersResult echoes page and limit so callers can fetch the next page.
+type subscribersResult struct {
+	Page        int                 `json:"page"`
+	Limit       int                 `json:"limit"`
+	Subscribers []*forgejo_sdk.User `json:"subscribers"`
+}
+
+func RegisterSubscriptionTool(s *server.MCPServer) {
+	s.AddTool(ListIssueSubscribersTool, ListIssueSubscribersFn)
+	s.AddTool(SubscribeIssueTool, SubscribeIssueFn)
+	s.AddTool(UnsubscribeIssueTool, UnsubscribeIssueFn)
+	s.AddTool(CheckIssueSubscriptionTool, CheckIssueSubscriptionFn)
+}
+
+// subscriptionUser returns the user argument, or the authenticated user's
+// login when it is empty.
+func subscriptionUser(ctx context.Context, args map[string]any) (string, error) {
+	user, _ := args["user"].(string)
+	if user = strings.TrimPrefix(strings.TrimSpace(user), "@"); user != "" {
+		return user, nil
+	}
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return "", err
+	}
+	me, _, err := client.GetMyUserInfo()
+	if err != nil {
+		return "", fmt.Errorf("get authenticated user err: %w", err)
+	}
+	return me.UserName, nil
+}
+
+func ListIssueSubscribersFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListIssueSubscribersFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	page, limit := parsePageLimit(req.GetArguments())
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "subscriptions") + fmt.Sprintf("?page=%d&limit=%d", page, limit)
+	users := []*forgejo_sdk.User{}
+	if err := forgejo.DoJSON(ctx, http.MethodGet, path, nil, &users); err != nil {
+		return to.ErrorResult(fmt.Errorf("list issue subscribers err: %w", err))
+	}
+	return to.TextResult(subscribersResult{Page: page, Limit: limit, Subscribers: users})
+}
+
+func SubscribeIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called SubscribeIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	user, err := subscriptionUser(ctx, req.GetArguments())
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	// 201 subscribes, 200 means already subscribed; both are success here.
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "subscriptions", user)
+	if err := forgejo.DoJSON(ctx, http.MethodPut, path, nil, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("subscribe issue err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("%s is subscribed to #%d", user, int64(index)))
+}
+
+func UnsubscribeIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called UnsubscribeIssueFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+	user, err := subscriptionUser(ctx, req.GetArguments())
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "subscriptions", user)
+	if err := forgejo.DoJSON(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return to.ErrorResult(fmt.Errorf("unsubscribe issue err: %w", err))
+	}
+	return to.TextResult(fmt.Sprintf("%s is not subscribed to #%d", user, int64(index)))
+}
+
+func CheckIssueSubscriptionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called CheckIssueSubscriptionFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+	index, _ := to.Float64(req.GetArguments()["index"])
+
+	var info forgejo_sdk.WatchInfo
+	path := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "subscriptions", "check")
+	if err := forgejo.DoJSON(ctx, http.MethodGet, path, nil, &info); err != nil {
+		return to.ErrorResult(fmt.Errorf("check issue subscription err: %w", err))
+	}
+	return to.TextResult(info)
+}
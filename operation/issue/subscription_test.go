@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+func subscriberLogins(t *testing.T) []string {
+	t.Helper()
+	res, err := ListIssueSubscribersFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo", "index": float64(1)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out subscribersResult
+	resultJSON(t, res, &out)
+	logins := []string{}
+	for _, u := range out.Subscribers {
+		logins = append(logins, u.UserName)
+	}
+	return logins
+}
+
+func TestIssueSubscriptions(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "hand-off", "")
+	ctx := context.Background()
+	args := func(user string) map[string]any {
+		a := map[string]any{"owner": "alice", "repo": "demo", "index": float64(1)}
+		if user != "" {
+			a["user"] = user
+		}
+		return a
+	}
+	checkSubscribed := func(want bool) {
+		t.Helper()
+		res, err := CheckIssueSubscriptionFn(ctx, makeReq(args("")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info forgejo_sdk.WatchInfo
+		resultJSON(t, res, &info)
+		if info.Subscribed != want {
+			t.Fatalf("subscribed = %v, want %v", info.Subscribed, want)
+		}
+	}
+
+	checkSubscribed(false)
+	// Subscribing twice is not an error.
+	for _, user := range []string{"@bob", "bob", ""} {
+		if _, err := SubscribeIssueFn(ctx, makeReq(args(user))); err != nil {
+			t.Fatalf("subscribe %q: %v", user, err)
+		}
+	}
+	if got := subscriberLogins(t); len(got) != 2 || got[0] != "bob" || got[1] != srv.User().UserName {
+		t.Fatalf("subscribers = %v", got)
+	}
+	checkSubscribed(true)
+
+	for range 2 {
+		if _, err := UnsubscribeIssueFn(ctx, makeReq(args(""))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkSubscribed(false)
+	if got := subscriberLogins(t); len(got) != 1 || got[0] != "bob" {
+		t.Fatalf("subscribers after unsubscribe = %v", got)
+	}
+
+	if _, err := SubscribeIssueFn(ctx, makeReq(map[string]any{"owner": "alice", "repo": "demo", "index": float64(7), "user": "bob"})); err == nil {
+		t.Error("missing issue: want error")
+	}
+}
@@ -0,0 +1,637 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ListIssueTemplatesToolName = "list_issue_templates"
+)
+
+var (
+	ListIssueTemplatesTool = mcp.NewTool(
+		ListIssueTemplatesToolName,
+		mcp.WithDescription("List the repository's issue templates from .forgejo/ISSUE_TEMPLATE (or .gitea/.github). Form templates list their fields with id, type, label, options, defaults and validations (required, is_number, regex); pass a template name to create_issue with the values in fields. Markdown templates return their content. Returns an empty list if the repository has no templates."),
+		mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	)
+)
+
+// Issue form field types, as in Forgejo's modules/structs/issue.go.
+const (
+	formFieldMarkdown   = "markdown"
+	formFieldTextarea   = "textarea"
+	formFieldInput      = "input"
+	formFieldDropdown   = "dropdown"
+	formFieldCheckboxes = "checkboxes"
+)
+
+// Where a form field is shown; see visibleIn.
+const (
+	formVisibleForm    = "form"
+	formVisibleContent = "content"
+)
+
+// noResponse is what Forgejo renders for an empty field.
+const noResponse = "_No response_\n"
+
+// issueTemplate is GET /repos/{owner}/{repo}/issue_templates as Forgejo
+// returns it. The SDK's IssueTemplate types options as strings, which fails
+// to decode checkbox options, so attributes stay generic here.
+type issueTemplate struct {
+	Name      string           `json:"name"`
+	Title     string           `json:"title"`
+	About     string           `json:"about"`
+	Labels    []string         `json:"labels"`
+	Assignees []string         `json:"assignees,omitempty"`
+	Ref       string           `json:"ref"`
+	Content   string           `json:"content"`
+	Fields    []issueFormField `json:"body"`
+	FileName  string           `json:"file_name"`
+}
+
+func (t *issueTemplate) isForm() bool {
+	return len(t.Fields) > 0
+}
+
+type issueFormField struct {
+	Type        string         `json:"type"`
+	ID          string         `json:"id"`
+	Attributes  map[string]any `json:"attributes"`
+	Validations map[string]any `json:"validations"`
+	Visible     []string       `json:"visible,omitempty"`
+}
+
+// formOption is a dropdown or checkboxes option.
+type formOption struct {
+	Label    string
+	Required bool
+	Visible  []string
+}
+
+func (f *issueFormField) attr(key string) string {
+	s, _ := f.Attributes[key].(string)
+	return s
+}
+
+func (f *issueFormField) attrBool(key string) bool {
+	b, _ := f.Attributes[key].(bool)
+	return b
+}
+
+func (f *issueFormField) label() string {
+	return f.attr("label")
+}
+
+func (f *issueFormField) required() bool {
+	b, _ := f.Validations["required"].(bool)
+	return b
+}
+
+func (f *issueFormField) isNumber() bool {
+	b, _ := f.Validations["is_number"].(bool)
+	return b
+}
+
+func (f *issueFormField) regex() string {
+	s, _ := f.Validations["regex"].(string)
+	return s
+}
+
+// hideLabel mirrors Forgejo: markdown fields never get a heading.
+func (f *issueFormField) hideLabel() bool {
+	return f.Type == formFieldMarkdown || f.attrBool("hide_label")
+}
+
+// visibleIn reports whether the field shows in the form or in the rendered
+// issue content. Markdown fields default to the form only, the others to
+// both.
+func (f *issueFormField) visibleIn(where string) bool {
+	if len(f.Visible) == 0 {
+		return where == formVisibleForm || f.Type != formFieldMarkdown
+	}
+	return slices.Contains(f.Visible, where)
+}
+
+// options decodes the dropdown (plain strings) and checkboxes (objects)
+// option lists.
+func (f *issueFormField) options() []formOption {
+	raw, _ := f.Attributes["options"].([]any)
+	out := make([]formOption, 0, len(raw))
+	for _, item := range raw {
+		switch item := item.(type) {
+		case string:
+			out = append(out, formOption{Label: item})
+		case map[string]any:
+			o := formOption{}
+			o.Label, _ = item["label"].(string)
+			o.Required, _ = item["required"].(bool)
+			if visible, ok := item["visible"].([]any); ok {
+				for _, v := range visible {
+					if s, ok := v.(string); ok {
+						o.Visible = append(o.Visible, s)
+					}
+				}
+			}
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// defaultOption is the index of the dropdown's preselected option, or -1.
+func (f *issueFormField) defaultOption() int {
+	d, ok := to.Float64Ok(f.Attributes["default"])
+	if !ok || d < 0 || int(d) >= len(f.options()) {
+		return -1
+	}
+	return int(d)
+}
+
+// issueTemplateSummary is one entry of list_issue_templates.
+type issueTemplateSummary struct {
+	Name      string                 `json:"name"`
+	FileName  string                 `json:"file_name"`
+	Kind      string                 `json:"kind"`
+	About     string                 `json:"about,omitempty"`
+	Title     string                 `json:"title,omitempty"`
+	Labels    []string               `json:"labels"`
+	Assignees []string               `json:"assignees"`
+	Ref       string                 `json:"ref,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+	Fields    []templateFieldSummary `json:"fields,omitempty"`
+}
+
+// templateFieldSummary flattens a form field's attributes and validations.
+// Markdown fields carry their text and have no id; they take no input.
+type templateFieldSummary struct {
+	ID          string                  `json:"id,omitempty"`
+	Type        string                  `json:"type"`
+	Label       string                  `json:"label,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Placeholder string                  `json:"placeholder,omitempty"`
+	Text        string                  `json:"text,omitempty"`
+	Default     string                  `json:"default,omitempty"`
+	Options     []templateOptionSummary `json:"options,omitempty"`
+	Required    bool                    `json:"required"`
+	Multiple    bool                    `json:"multiple,omitempty"`
+	IsNumber    bool                    `json:"is_number,omitempty"`
+	Regex       string                  `json:"regex,omitempty"`
+	Render      string                  `json:"render,omitempty"`
+	InContent   bool                    `json:"in_content"`
+}
+
+type templateOptionSummary struct {
+	Label    string `json:"label"`
+	Required bool   `json:"required,omitempty"`
+}
+
+func summarizeTemplate(t *issueTemplate) issueTemplateSummary {
+	s := issueTemplateSummary{
+		Name:      t.Name,
+		FileName:  t.FileName,
+		Kind:      "markdown",
+		About:     t.About,
+		Title:     t.Title,
+		Labels:    t.Labels,
+		Assignees: t.Assignees,
+		Ref:       t.Ref,
+		Content:   t.Content,
+	}
+	if s.Labels == nil {
+		s.Labels = []string{}
+	}
+	if s.Assignees == nil {
+		s.Assignees = []string{}
+	}
+	if !t.isForm() {
+		return s
+	}
+	s.Kind = "form"
+	s.Content = ""
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if !f.visibleIn(formVisibleForm) {
+			continue
+		}
+		fs := templateFieldSummary{
+			ID:          f.ID,
+			Type:        f.Type,
+			Label:       f.label(),
+			Description: f.attr("description"),
+			Placeholder: f.attr("placeholder"),
+			Required:    f.required(),
+			Multiple:    f.attrBool("multiple"),
+			IsNumber:    f.isNumber(),
+			Regex:       f.regex(),
+			Render:      f.attr("render"),
+			InContent:   f.ID != "" && f.visibleIn(formVisibleContent),
+		}
+		switch f.Type {
+		case formFieldMarkdown:
+			fs.Text = f.attr("value")
+		case formFieldDropdown, formFieldCheckboxes:
+			opts := f.options()
+			for _, o := range opts {
+				fs.Options = append(fs.Options, templateOptionSummary{Label: o.Label, Required: o.Required})
+			}
+			if d := f.defaultOption(); f.Type == formFieldDropdown && d >= 0 {
+				fs.Default = opts[d].Label
+			}
+		default:
+			fs.Default = f.attr("value")
+		}
+		s.Fields = append(s.Fields, fs)
+	}
+	return s
+}
+
+func fetchIssueTemplates(ctx context.Context, owner, repo string) ([]issueTemplate, error) {
+	var templates []issueTemplate
+	if err := forgejo.DoJSONList(ctx, http.MethodGet, forgejo.APIPath("repos", owner, repo, "issue_templates"), &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// findIssueTemplate matches a template by name (case-insensitively) or by
+// file name, with or without directory and extension.
+func findIssueTemplate(templates []issueTemplate, name string) (*issueTemplate, error) {
+	for i := range templates {
+		t := &templates[i]
+		base := path.Base(t.FileName)
+		if strings.EqualFold(t.Name, name) || t.FileName == name || base == name ||
+			strings.TrimSuffix(base, path.Ext(base)) == name {
+			return t, nil
+		}
+	}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("template %q not found: the repository has no issue templates", name)
+	}
+	names := make([]string, 0, len(templates))
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+	return nil, fmt.Errorf("template %q not found; available: %s", name, strings.Join(names, ", "))
+}
+
+// formValue is a field's validated input: text for inputs and textareas,
+// the chosen options for dropdowns and checkboxes.
+type formValue struct {
+	text    string
+	checked []bool
+}
+
+// renderIssueForm validates fields against the template's form and renders
+// the issue body the way Forgejo's web UI does (RenderToMarkdown in
+// modules/issue/template). Omitted fields take their default value. Every
+// problem is reported at once so a caller can fix them in one retry.
+func renderIssueForm(t *issueTemplate, fields map[string]any) (string, error) {
+	known := map[string]bool{}
+	for _, f := range t.Fields {
+		if f.ID != "" && f.Type != formFieldMarkdown {
+			known[f.ID] = true
+		}
+	}
+	var problems []string
+	for id := range fields {
+		if !known[id] {
+			problems = append(problems, fmt.Sprintf("unknown field %q", id))
+		}
+	}
+	sort.Strings(problems)
+	if len(problems) > 0 {
+		valid := make([]string, 0, len(known))
+		for id := range known {
+			valid = append(valid, id)
+		}
+		sort.Strings(valid)
+		problems[len(problems)-1] += fmt.Sprintf(" (fields: %s)", strings.Join(valid, ", "))
+	}
+
+	values := make([]formValue, len(t.Fields))
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.ID == "" || f.Type == formFieldMarkdown {
+			continue
+		}
+		v, err := formFieldValue(f, fields[f.ID])
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		values[i] = v
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("template %q: %s", t.Name, strings.Join(problems, "; "))
+	}
+
+	var b strings.Builder
+	for i := range t.Fields {
+		f := &t.Fields[i]
+		if f.ID == "" || !f.visibleIn(formVisibleContent) {
+			continue
+		}
+		writeFormField(&b, f, values[i])
+	}
+	return b.String(), nil
+}
+
+// formFieldValue converts and validates the caller's value for f; raw is nil
+// when the field was omitted.
+func formFieldValue(f *issueFormField, raw any) (formValue, error) {
+	name := fmt.Sprintf("field %q", f.ID)
+	switch f.Type {
+	case formFieldInput, formFieldTextarea:
+		text := f.attr("value")
+		switch v := raw.(type) {
+		case nil:
+		case string:
+			text = v
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return formValue{}, fmt.Errorf("%s must be a string", name)
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			if f.required() {
+				return formValue{}, fmt.Errorf("%s (%s) is required", name, f.label())
+			}
+			return formValue{}, nil
+		}
+		if f.isNumber() {
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return formValue{}, fmt.Errorf("%s must be a number", name)
+			}
+		}
+		if pattern := f.regex(); pattern != "" {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return formValue{}, fmt.Errorf("%s: invalid regex %q in template: %w", name, pattern, err)
+			}
+			if !re.MatchString(text) {
+				return formValue{}, fmt.Errorf("%s must match %s", name, pattern)
+			}
+		}
+		return formValue{text: text}, nil
+
+	case formFieldDropdown, formFieldCheckboxes:
+		opts := f.options()
+		checked := make([]bool, len(opts))
+		var chosen []string
+		switch v := raw.(type) {
+		case nil:
+			if d := f.defaultOption(); f.Type == formFieldDropdown && d >= 0 {
+				checked[d] = true
+			}
+		case string:
+			chosen = []string{v}
+		case []any:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return formValue{}, fmt.Errorf("%s must list option labels as strings", name)
+				}
+				chosen = append(chosen, s)
+			}
+		case map[string]any:
+			// {"option label": true, ...}, the natural shape for checkboxes.
+			for label, on := range v {
+				if b, _ := on.(bool); b {
+					chosen = append(chosen, label)
+				} else if _, ok := on.(bool); !ok {
+					return formValue{}, fmt.Errorf("%s: option %q must be true or false", name, label)
+				}
+			}
+		case bool:
+			// A lone checkbox may be given as true/false.
+			if f.Type != formFieldCheckboxes || len(opts) != 1 {
+				return formValue{}, fmt.Errorf("%s must be an option label or a list of labels", name)
+			}
+			checked[0] = v
+		default:
+			return formValue{}, fmt.Errorf("%s must be an option label or a list of labels", name)
+		}
+		for _, label := range chosen {
+			idx := slices.IndexFunc(opts, func(o formOption) bool { return o.Label == label })
+			if idx < 0 {
+				idx = slices.IndexFunc(opts, func(o formOption) bool { return strings.EqualFold(o.Label, label) })
+			}
+			if idx < 0 {
+				labels := make([]string, 0, len(opts))
+				for _, o := range opts {
+					labels = append(labels, o.Label)
+				}
+				return formValue{}, fmt.Errorf("%s: %q is not an option (options: %s)", name, label, strings.Join(labels, ", "))
+			}
+			checked[idx] = true
+		}
+
+		n := 0
+		for _, c := range checked {
+			if c {
+				n++
+			}
+		}
+		if f.Type == formFieldDropdown {
+			if n > 1 && !f.attrBool("multiple") {
+				return formValue{}, fmt.Errorf("%s accepts a single option", name)
+			}
+			if n == 0 && f.required() {
+				return formValue{}, fmt.Errorf("%s (%s) is required", name, f.label())
+			}
+		} else {
+			for i, o := range opts {
+				if o.Required && !checked[i] {
+					return formValue{}, fmt.Errorf("%s: option %q must be checked", name, o.Label)
+				}
+			}
+		}
+		return formValue{checked: checked}, nil
+	}
+	return formValue{}, nil
+}
+
+// writeFormField renders one field: a "### label" heading unless hidden,
+// the value or "_No response_", and a blank line.
+func writeFormField(b *strings.Builder, f *issueFormField, v formValue) {
+	if !f.hideLabel() {
+		fmt.Fprintf(b, "### %s\n\n", f.label())
+	}
+	switch f.Type {
+	case formFieldMarkdown:
+		b.WriteString(f.attr("value"))
+	case formFieldCheckboxes:
+		for i, o := range f.options() {
+			if len(o.Visible) > 0 && !slices.Contains(o.Visible, formVisibleContent) {
+				continue
+			}
+			mark := " "
+			if v.checked[i] {
+				mark = "x"
+			}
+			fmt.Fprintf(b, "- [%s] %s\n", mark, o.Label)
+		}
+	case formFieldDropdown:
+		var selected []string
+		for i, o := range f.options() {
+			if v.checked[i] {
+				selected = append(selected, o.Label)
+			}
+		}
+		switch {
+		case len(selected) == 0:
+			b.WriteString(noResponse)
+		case f.attrBool("list"):
+			for _, s := range selected {
+				fmt.Fprintf(b, "- %s\n", s)
+			}
+		default:
+			fmt.Fprintf(b, "%s\n", strings.Join(selected, ", "))
+		}
+	case formFieldInput:
+		if v.text == "" {
+			b.WriteString(noResponse)
+		} else {
+			fmt.Fprintf(b, "%s\n", v.text)
+		}
+	case formFieldTextarea:
+		switch render := f.attr("render"); {
+		case v.text == "":
+			b.WriteString(noResponse)
+		case render != "":
+			fence := codeFence(v.text)
+			fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, render, v.text, fence)
+		default:
+			fmt.Fprintf(b, "%s\n", v.text)
+		}
+	}
+	b.WriteString("\n")
+}
+
+// codeFence is the shortest backtick fence, at least three, that does not
+// occur in s.
+func codeFence(s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence
+}
+
+// applyIssueTemplate fills opt from the named template: the title prefix,
+// labels, assignees and ref, and the body rendered from fields (form
+// templates) or the template content when body is empty (markdown
+// templates). Template labels that do not exist in the repository are
+// skipped, as the web UI does.
+func applyIssueTemplate(ctx context.Context, client *forgejo_sdk.Client, owner, repo, name string, fields map[string]any, opt *forgejo_sdk.CreateIssueOption) error {
+	templates, err := fetchIssueTemplates(ctx, owner, repo)
+	if err != nil {
+		return fmt.Errorf("list issue templates: %w", err)
+	}
+	t, err := findIssueTemplate(templates, name)
+	if err != nil {
+		return err
+	}
+
+	if t.isForm() {
+		if opt.Body != "" {
+			return fmt.Errorf("template %q is a form: pass its values in fields instead of body", t.Name)
+		}
+		if opt.Body, err = renderIssueForm(t, fields); err != nil {
+			return err
+		}
+	} else {
+		if len(fields) > 0 {
+			return fmt.Errorf("template %q is a markdown template and takes no fields: edit its content into body", t.Name)
+		}
+		if opt.Body == "" {
+			opt.Body = t.Content
+		}
+	}
+
+	if prefix := t.Title; prefix != "" && !strings.HasPrefix(opt.Title, strings.TrimSpace(prefix)) {
+		opt.Title = prefix + opt.Title
+	}
+	if len(t.Labels) > 0 {
+		ids, missing, err := labelIDsByName(ctx, client, owner, repo, t.Labels)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			log.Debugf("template %q: skipping unknown labels %v", t.Name, missing)
+		}
+		for _, id := range ids {
+			if !slices.Contains(opt.Labels, id) {
+				opt.Labels = append(opt.Labels, id)
+			}
+		}
+	}
+	for _, a := range t.Assignees {
+		if !slices.Contains(opt.Assignees, a) {
+			opt.Assignees = append(opt.Assignees, a)
+		}
+	}
+	if opt.Ref == "" {
+		opt.Ref = t.Ref
+	}
+	return nil
+}
+
+// objectArg reads an object argument, accepting a JSON-encoded string from
+// clients that cannot send nested objects.
+func objectArg(args map[string]any, key string) (map[string]any, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(v), &m); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object: %w", key, err)
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("%s must be an object", key)
+	}
+}
+
+func ListIssueTemplatesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListIssueTemplatesFn")
+	owner, _ := req.GetArguments()["owner"].(string)
+	repo, _ := req.GetArguments()["repo"].(string)
+
+	templates, err := fetchIssueTemplates(ctx, owner, repo)
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("list issue templates err: %w", err))
+	}
+	out := make([]issueTemplateSummary, 0, len(templates))
+	for i := range templates {
+		out = append(out, summarizeTemplate(&templates[i]))
+	}
+	return to.TextResult(out)
+}
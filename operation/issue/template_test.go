@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const bugFormTemplate = `{
+	"name": "Bug report",
+	"about": "Something is broken",
+	"title": "[Bug]: ",
+	"labels": ["bug", "missing-label"],
+	"assignees": ["forgejotest"],
+	"file_name": ".forgejo/ISSUE_TEMPLATE/bug.yaml",
+	"body": [
+		{"type": "markdown", "attributes": {"value": "Thanks for reporting!"}},
+		{"type": "input", "id": "version", "attributes": {"label": "Version", "value": "11.0"},
+		 "validations": {"required": true, "regex": "^[0-9.]+$"}},
+		{"type": "input", "id": "count", "attributes": {"label": "How often"}, "validations": {"is_number": true}},
+		{"type": "textarea", "id": "logs", "attributes": {"label": "Logs", "render": "shell"}},
+		{"type": "textarea", "id": "notes", "attributes": {"label": "Notes"}},
+		{"type": "dropdown", "id": "browser", "attributes": {"label": "Browsers", "options": ["Firefox", "Chrome"], "multiple": true}},
+		{"type": "dropdown", "id": "severity", "attributes": {"label": "Severity", "options": ["low", "high"], "default": 0}},
+		{"type": "checkboxes", "id": "terms", "attributes": {"label": "Checklist", "options": [
+			{"label": "I searched existing issues", "required": true},
+			{"label": "I can reproduce it"},
+			{"label": "Form only", "visible": ["form"]}
+		]}},
+		{"type": "input", "id": "internal", "attributes": {"label": "Internal"}, "visible": ["form"]}
+	]
+}`
+
+const featureMarkdownTemplate = `{
+	"name": "Feature request",
+	"title": "[Feature] ",
+	"file_name": ".forgejo/ISSUE_TEMPLATE/feature.md",
+	"content": "## Problem\n\n## Proposal\n"
+}`
+
+func templateServer(t *testing.T) *forgejotest.Server {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateLabel("alice", "demo", "bug", "ff0000")
+	srv.AddIssueTemplate("alice", "demo", bugFormTemplate)
+	srv.AddIssueTemplate("alice", "demo", featureMarkdownTemplate)
+	return srv
+}
+
+func resultJSON(t *testing.T, res *mcp.CallToolResult, out any) {
+	t.Helper()
+	if res == nil || res.IsError {
+		t.Fatalf("tool failed: %+v", res)
+	}
+	var envelope struct{ Result json.RawMessage }
+	if err := json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &envelope); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestListIssueTemplates(t *testing.T) {
+	templateServer(t)
+
+	res, err := ListIssueTemplatesFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "demo"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []issueTemplateSummary
+	resultJSON(t, res, &got)
+	if len(got) != 2 {
+		t.Fatalf("want 2 templates, got %d", len(got))
+	}
+	bug, feature := got[0], got[1]
+	if bug.Kind != "form" || feature.Kind != "markdown" {
+		t.Fatalf("kinds: %q, %q", bug.Kind, feature.Kind)
+	}
+	if feature.Content != "## Problem\n\n## Proposal\n" || feature.Fields != nil {
+		t.Fatalf("markdown template: %+v", feature)
+	}
+	if len(bug.Fields) != 9 {
+		t.Fatalf("want 9 form fields, got %d: %+v", len(bug.Fields), bug.Fields)
+	}
+	version := bug.Fields[1]
+	if version.ID != "version" || !version.Required || version.Regex != "^[0-9.]+$" || version.Default != "11.0" {
+		t.Fatalf("version field: %+v", version)
+	}
+	if severity := bug.Fields[6]; severity.Default != "low" || len(severity.Options) != 2 {
+		t.Fatalf("severity field: %+v", severity)
+	}
+	if terms := bug.Fields[7]; !terms.Options[0].Required || terms.Options[1].Required {
+		t.Fatalf("checkbox options: %+v", terms.Options)
+	}
+	if bug.Fields[0].Text != "Thanks for reporting!" || bug.Fields[0].InContent {
+		t.Fatalf("markdown field: %+v", bug.Fields[0])
+	}
+	if bug.Fields[8].InContent {
+		t.Fatal("form-only field reported as rendered")
+	}
+}
+
+func TestListIssueTemplates_None(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "empty")
+
+	res, _ := ListIssueTemplatesFn(context.Background(), makeReq(map[string]any{"owner": "alice", "repo": "empty"}))
+	var got []issueTemplateSummary
+	resultJSON(t, res, &got)
+	if got == nil || len(got) != 0 {
+		t.Fatalf("want empty list, got %+v", got)
+	}
+}
+
+func TestCreateIssue_FormTemplate(t *testing.T) {
+	templateServer(t)
+
+	res, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner":    "alice",
+		"repo":     "demo",
+		"title":    "crash on save",
+		"template": "bug",
+		"fields": map[string]any{
+			"count":   float64(3),
+			"logs":    "panic: boom\n```inner```",
+			"browser": []any{"Firefox", "chrome"},
+			"terms":   []any{"I searched existing issues"},
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var issue forgejo_sdk.Issue
+	resultJSON(t, res, &issue)
+
+	if issue.Title != "[Bug]: crash on save" {
+		t.Fatalf("title = %q", issue.Title)
+	}
+	want := "### Version\n\n11.0\n\n" +
+		"### How often\n\n3\n\n" +
+		"### Logs\n\n````shell\npanic: boom\n```inner```\n````\n\n" +
+		"### Notes\n\n_No response_\n\n" +
+		"### Browsers\n\nFirefox, Chrome\n\n" +
+		"### Severity\n\nlow\n\n" +
+		"### Checklist\n\n- [x] I searched existing issues\n- [ ] I can reproduce it\n\n"
+	if issue.Body != want {
+		t.Fatalf("body mismatch:\n got: %q\nwant: %q", issue.Body, want)
+	}
+	if len(issue.Labels) != 1 || issue.Labels[0].Name != "bug" {
+		t.Fatalf("labels = %+v", issue.Labels)
+	}
+	if len(issue.Assignees) != 1 || issue.Assignees[0].UserName != "forgejotest" {
+		t.Fatalf("assignees = %+v", issue.Assignees)
+	}
+}
+
+func TestCreateIssue_FormTemplateValidation(t *testing.T) {
+	templateServer(t)
+
+	_, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner":    "alice",
+		"repo":     "demo",
+		"title":    "x",
+		"template": "Bug report",
+		"fields": map[string]any{
+			"version":  "v2",
+			"count":    "often",
+			"severity": []any{"low", "high"},
+			"terms":    []any{"I can reproduce it"},
+			"typo":     "?",
+		},
+	}))
+	msg := errorText(err)
+	for _, want := range []string{
+		`unknown field "typo"`,
+		`field "version" must match ^[0-9.]+$`,
+		`field "count" must be a number`,
+		`field "severity" accepts a single option`,
+		`option "I searched existing issues" must be checked`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q lacks %q", msg, want)
+		}
+	}
+}
+
+func TestCreateIssue_MarkdownTemplate(t *testing.T) {
+	templateServer(t)
+
+	res, _ := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "title": "dark mode", "template": "feature",
+	}))
+	var issue forgejo_sdk.Issue
+	resultJSON(t, res, &issue)
+	if issue.Title != "[Feature] dark mode" || issue.Body != "## Problem\n\n## Proposal\n" {
+		t.Fatalf("issue = %q / %q", issue.Title, issue.Body)
+	}
+
+	_, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "title": "x", "template": "feature",
+		"fields": `{"a": "b"}`,
+	}))
+	if !strings.Contains(errorText(err), "takes no fields") {
+		t.Fatalf("want fields rejected for markdown template, got %v", err)
+	}
+}
+
+func TestCreateIssue_TemplateErrors(t *testing.T) {
+	templateServer(t)
+
+	for name, args := range map[string]map[string]any{
+		"unknown template":   {"template": "nope"},
+		"fields without":     {"fields": map[string]any{"version": "1"}},
+		"body on form":       {"template": "bug", "body": "free text"},
+		"missing required":   {"template": "bug", "fields": map[string]any{"version": " ", "terms": []any{"I searched existing issues"}}},
+		"option not offered": {"template": "bug", "fields": map[string]any{"browser": "Safari", "terms": []any{"I searched existing issues"}}},
+	} {
+		args["owner"], args["repo"], args["title"] = "alice", "demo", "x"
+		if _, err := CreateIssueFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestCodeFence(t *testing.T) {
+	for in, want := range map[string]string{
+		"plain":         "```",
+		"a ``` b":       "````",
+		"a ```` b ```":  "`````",
+		"two `` ticks ": "```",
+	} {
+		if got := codeFence(in); got != want {
+			t.Errorf("codeFence(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const ListIssueTimelineToolName = "list_issue_timeline"
+
+var ListIssueTimelineTool = mcp.NewTool(
+	ListIssueTimelineToolName,
+	mcp.WithDescription("List the timeline of an issue or pull request, oldest first: comments plus label, assignee, milestone, title, due date, lock and pin changes, closes/reopens/merges, dependency changes, reviews and cross-references from other issues, PRs and commits. "+
+		"Each event has a normalized event name (e.g. commented, labeled, unlabeled, assigned, unassigned, milestoned, demilestoned, renamed, closed, reopened, merged, cross_referenced, referenced_in_commit) next to Forgejo's raw type, plus the actor and the fields that event changed (label, assignee, from/to, ref, commit_sha). "+
+		"Set include_bodies=false for a compact view without comment and review bodies. "+
+		"Pagination uses page (1-based) and limit (page size); the response is an envelope {events, page, limit, count, has_next}. has_next true means a further page exists; re-issue the call with page incremented."),
+	mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+	mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	mcp.WithString("since", mcp.Description("Only events updated at or after this time (RFC3339)")),
+	mcp.WithString("before", mcp.Description("Only events updated before this time (RFC3339)")),
+	mcp.WithBoolean("include_bodies", mcp.Description("Include comment and review bodies (default true); false returns a compact timeline"), mcp.DefaultBool(true)),
+	mcp.WithNumber("page", mcp.Description(params.Page), mcp.DefaultNumber(1)),
+	mcp.WithNumber("limit", mcp.Description(params.Limit), mcp.DefaultNumber(20)),
+)
+
+func RegisterTimelineTool(s *server.MCPServer) {
+	s.AddTool(ListIssueTimelineTool, ListIssueTimelineFn)
+}
+
+// timelineComment is the subset of Forgejo's TimelineComment the tool reads;
+// the SDK has no timeline support.
+type timelineComment struct {
+	ID              int64                  `json:"id"`
+	Type            string                 `json:"type"`
+	HTMLURL         string                 `json:"html_url"`
+	User            *forgejo_sdk.User      `json:"user"`
+	Body            string                 `json:"body"`
+	Created         time.Time              `json:"created_at"`
+	OldMilestone    *forgejo_sdk.Milestone `json:"old_milestone"`
+	Milestone       *forgejo_sdk.Milestone `json:"milestone"`
+	OldTitle        string                 `json:"old_title"`
+	NewTitle        string                 `json:"new_title"`
+	OldRef          string                 `json:"old_ref"`
+	NewRef          string                 `json:"new_ref"`
+	RefIssue        *forgejo_sdk.Issue     `json:"ref_issue"`
+	RefAction       string                 `json:"ref_action"`
+	RefCommitSHA    string                 `json:"ref_commit_sha"`
+	Label           *forgejo_sdk.Label     `json:"label"`
+	Assignee        *forgejo_sdk.User      `json:"assignee"`
+	AssigneeTeam    *forgejo_sdk.Team      `json:"assignee_team"`
+	RemovedAssignee bool                   `json:"removed_assignee"`
+	DependentIssue  *forgejo_sdk.Issue     `json:"dependent_issue"`
+}
+
+// timelineRef points at the issue or PR on the other end of a
+// cross-reference or dependency.
+type timelineRef struct {
+	Repo   string `json:"repo,omitempty"`
+	Index  int64  `json:"index"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	IsPull bool   `json:"is_pull,omitempty"`
+}
+
+type timelineEvent struct {
+	ID        int64        `json:"id"`
+	Event     string       `json:"event"`
+	Type      string       `json:"type"`
+	Actor     string       `json:"actor,omitempty"`
+	CreatedAt string       `json:"created_at"`
+	Body      string       `json:"body,omitempty"`
+	Label     string       `json:"label,omitempty"`
+	Assignee  string       `json:"assignee,omitempty"`
+	From      string       `json:"from,omitempty"`
+	To        string       `json:"to,omitempty"`
+	CommitSHA string       `json:"commit_sha,omitempty"`
+	Ref       *timelineRef `json:"ref,omitempty"`
+	RefAction string       `json:"ref_action,omitempty"`
+	HTMLURL   string       `json:"html_url,omitempty"`
+}
+
+// timelineEnvelope carries no total_count: Forgejo's X-Total-Count on this
+// endpoint is the length of the returned page, not a grand total.
+type timelineEnvelope struct {
+	Events  []timelineEvent `json:"events"`
+	Page    int             `json:"page"`
+	Limit   int             `json:"limit"`
+	Count   int             `json:"count"`
+	HasNext bool            `json:"has_next"`
+}
+
+// timelineEventNames maps Forgejo comment types whose meaning does not
+// depend on the payload to their normalized event name. Types missing here
+// and not handled in normalizeTimelineEvent keep their raw name.
+var timelineEventNames = map[string]string{
+	"comment":              "commented",
+	"close":                "closed",
+	"reopen":               "reopened",
+	"merge_pull":           "merged",
+	"change_title":         "renamed",
+	"commit_ref":           "referenced_in_commit",
+	"comment_ref":          "cross_referenced",
+	"issue_ref":            "cross_referenced",
+	"pull_ref":             "cross_referenced",
+	"added_deadline":       "due_date_added",
+	"modified_deadline":    "due_date_changed",
+	"removed_deadline":     "due_date_removed",
+	"lock":                 "locked",
+	"unlock":               "unlocked",
+	"pin":                  "pinned",
+	"unpin":                "unpinned",
+	"add_dependency":       "dependency_added",
+	"remove_dependency":    "dependency_removed",
+	"review":               "reviewed",
+	"code":                 "review_commented",
+	"pull_push":            "pushed",
+	"change_target_branch": "base_changed",
+	"delete_branch":        "branch_deleted",
+}
+
+func timelineRefOf(issue *forgejo_sdk.Issue) *timelineRef {
+	if issue == nil {
+		return nil
+	}
+	ref := &timelineRef{
+		Index:  issue.Index,
+		Title:  issue.Title,
+		State:  string(issue.State),
+		IsPull: issue.PullRequest != nil,
+	}
+	if issue.Repository != nil {
+		ref.Repo = issue.Repository.FullName
+	}
+	return ref
+}
+
+// normalizeTimelineEvent flattens one Forgejo timeline entry. Label and due
+// date entries encode their change in the body, so it is decoded into
+// label/from/to and never returned as a body.
+func normalizeTimelineEvent(c timelineComment, includeBodies bool) timelineEvent {
+	ev := timelineEvent{
+		ID:        c.ID,
+		Event:     c.Type,
+		Type:      c.Type,
+		CreatedAt: c.Created.Format(time.RFC3339),
+		Body:      c.Body,
+		CommitSHA: c.RefCommitSHA,
+		Ref:       timelineRefOf(c.RefIssue),
+		RefAction: c.RefAction,
+		HTMLURL:   c.HTMLURL,
+	}
+	if name, ok := timelineEventNames[c.Type]; ok {
+		ev.Event = name
+	}
+	if c.User != nil {
+		ev.Actor = c.User.UserName
+	}
+
+	switch c.Type {
+	case "label":
+		// Forgejo stores "1" for an added label and "" for a removed one.
+		ev.Event, ev.Body = "unlabeled", ""
+		if c.Body == "1" {
+			ev.Event = "labeled"
+		}
+		if c.Label != nil {
+			ev.Label = c.Label.Name
+		}
+	case "assignees", "review_request":
+		ev.Event = "assigned"
+		if c.Type == "review_request" {
+			ev.Event = "review_requested"
+		}
+		if c.RemovedAssignee {
+			ev.Event = "unassigned"
+			if c.Type == "review_request" {
+				ev.Event = "review_request_removed"
+			}
+		}
+		if c.Assignee != nil {
+			ev.Assignee = c.Assignee.UserName
+		} else if c.AssigneeTeam != nil {
+			ev.Assignee = c.AssigneeTeam.Name
+		}
+	case "milestone":
+		ev.Event = "milestoned"
+		if c.Milestone == nil {
+			ev.Event = "demilestoned"
+		}
+		if c.OldMilestone != nil {
+			ev.From = c.OldMilestone.Title
+		}
+		if c.Milestone != nil {
+			ev.To = c.Milestone.Title
+		}
+	case "change_title":
+		ev.From, ev.To = c.OldTitle, c.NewTitle
+	case "change_target_branch":
+		ev.From, ev.To = c.OldRef, c.NewRef
+	case "added_deadline":
+		ev.To, ev.Body = c.Body, ""
+	case "modified_deadline":
+		// "new|old"
+		ev.To, ev.From, _ = strings.Cut(c.Body, "|")
+		ev.Body = ""
+	case "removed_deadline":
+		ev.From, ev.Body = c.Body, ""
+	case "add_dependency", "remove_dependency":
+		ev.Ref = timelineRefOf(c.DependentIssue)
+	}
+
+	if !includeBodies {
+		ev.Body = ""
+	}
+	return ev
+}
+
+func ListIssueTimelineFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called ListIssueTimelineFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+	index, _ := to.Float64(args["index"])
+	page, limit := parsePageLimit(args)
+	includeBodies := true
+	if v, ok := args["include_bodies"].(bool); ok {
+		includeBodies = v
+	}
+
+	query := url.Values{}
+	for _, key := range []string{"since", "before"} {
+		value, _ := args[key].(string)
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return to.ErrorResult(fmt.Errorf("invalid %s time format (expected RFC3339): %w", key, err))
+		}
+		query.Set(key, value)
+	}
+	base := forgejo.APIPath("repos", owner, repo, "issues", int64(index), "timeline")
+	fetch := func(page int) ([]timelineComment, error) {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("limit", strconv.Itoa(limit))
+		comments := []timelineComment{}
+		err := forgejo.DoJSON(ctx, http.MethodGet, base+"?"+query.Encode(), nil, &comments)
+		return comments, err
+	}
+
+	comments, err := fetch(page)
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("list issue timeline err: %w", err))
+	}
+	// Forgejo drops entries the caller may not see (review code comments,
+	// references from private repositories) after paging, and caps limit at
+	// max_response_items, so a short page does not mean the last one. Probe
+	// the next page at the same limit instead.
+	hasNext := false
+	if len(comments) > 0 {
+		next, err := fetch(page + 1)
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("probe next timeline page err: %w", err))
+		}
+		hasNext = len(next) > 0
+	}
+
+	events := make([]timelineEvent, len(comments))
+	for i, c := range comments {
+		events[i] = normalizeTimelineEvent(c, includeBodies)
+	}
+	return to.TextResult(timelineEnvelope{
+		Events:  events,
+		Page:    page,
+		Limit:   limit,
+		Count:   len(events),
+		HasNext: hasNext,
+	})
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+)
+
+func timelineServer(t *testing.T) *forgejotest.Server {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "flaky test", "")
+	return srv
+}
+
+func timelineCall(t *testing.T, args map[string]any) timelineEnvelope {
+	t.Helper()
+	args["owner"], args["repo"], args["index"] = "alice", "demo", float64(1)
+	res, err := ListIssueTimelineFn(context.Background(), makeReq(args))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out timelineEnvelope
+	resultJSON(t, res, &out)
+	return out
+}
+
+func TestListIssueTimeline_NormalizesEvents(t *testing.T) {
+	srv := timelineServer(t)
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"label","body":"1","label":{"name":"bug"}}`)
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"assignees","assignee":{"login":"bob"},"removed_assignee":true}`)
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"milestone","old_milestone":{"title":"1.2"},"milestone":{"title":"1.3"}}`)
+	if _, err := CreateIssueCommentFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(1), "body": "Seen on main too.",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"modified_deadline","body":"2024-03-01|2024-02-01"}`)
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"pull_ref","ref_action":"closes","ref_issue":{"number":7,"title":"Fix flake","state":"open","pull_request":{},"repository":{"full_name":"alice/demo"}}}`)
+	srv.AddTimelineEvent("alice", "demo", 1, `{"type":"close","ref_commit_sha":"abc123"}`)
+
+	out := timelineCall(t, map[string]any{})
+	if out.Count != 7 || out.HasNext || out.Page != 1 || out.Limit != 20 {
+		t.Fatalf("envelope = %+v", out)
+	}
+	want := []timelineEvent{
+		{Event: "labeled", Type: "label", Label: "bug"},
+		{Event: "unassigned", Type: "assignees", Assignee: "bob"},
+		{Event: "milestoned", Type: "milestone", From: "1.2", To: "1.3"},
+		{Event: "commented", Type: "comment", Body: "Seen on main too."},
+		{Event: "due_date_changed", Type: "modified_deadline", From: "2024-02-01", To: "2024-03-01"},
+		{Event: "cross_referenced", Type: "pull_ref", RefAction: "closes"},
+		{Event: "closed", Type: "close", CommitSHA: "abc123"},
+	}
+	for i, w := range want {
+		got := out.Events[i]
+		if got.Actor != "forgejotest" || got.CreatedAt == "" {
+			t.Errorf("event %d actor/time = %q/%q", i, got.Actor, got.CreatedAt)
+		}
+		got.ID, got.Actor, got.CreatedAt, got.HTMLURL, got.Ref = 0, "", "", "", nil
+		if got != w {
+			t.Errorf("event %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if ref := out.Events[5].Ref; ref == nil || ref.Index != 7 || !ref.IsPull || ref.Repo != "alice/demo" {
+		t.Errorf("ref = %+v", ref)
+	}
+
+	compact := timelineCall(t, map[string]any{"include_bodies": false})
+	if compact.Events[3].Event != "commented" || compact.Events[3].Body != "" {
+		t.Errorf("compact comment = %+v", compact.Events[3])
+	}
+}
+
+func TestListIssueTimeline_Paging(t *testing.T) {
+	srv := timelineServer(t)
+	for range 5 {
+		srv.AddTimelineEvent("alice", "demo", 1, `{"type":"lock"}`)
+	}
+
+	first := timelineCall(t, map[string]any{"limit": float64(2)})
+	if first.Count != 2 || !first.HasNext || first.Events[0].Event != "locked" {
+		t.Fatalf("first page = %+v", first)
+	}
+	last := timelineCall(t, map[string]any{"page": float64(3), "limit": float64(2)})
+	if last.Count != 1 || last.HasNext {
+		t.Fatalf("last page = %+v", last)
+	}
+
+	since := first.Events[1].CreatedAt
+	filtered := timelineCall(t, map[string]any{"since": since})
+	if filtered.Count != 4 {
+		t.Fatalf("since %s: count = %d", since, filtered.Count)
+	}
+
+	// A capped page is short but not the last one.
+	srv.MaxResponseItems = 2
+	capped := timelineCall(t, map[string]any{"limit": float64(4)})
+	if capped.Count != 2 || !capped.HasNext {
+		t.Fatalf("capped page = %+v", capped)
+	}
+
+	if _, err := ListIssueTimelineFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(1), "before": "yesterday",
+	})); err == nil {
+		t.Fatal("want error for invalid before")
+	}
+}
@@ -0,0 +1,504 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const TransferIssueToolName = "transfer_issue"
+
+// Idempotency markers, hidden in the rendered markdown. The target issue
+// names its source, each copied comment the source comment ID, and the
+// source's cross-link comment the target, so a retry finds what an earlier
+// attempt already created instead of duplicating it.
+const (
+	transferFromMarker    = "<!-- forgejo-mcp:transferred-from %s -->"
+	transferToMarker      = "<!-- forgejo-mcp:transferred-to %s -->"
+	transferCommentMarker = "<!-- forgejo-mcp:transferred-comment %d -->"
+)
+
+var (
+	transferToPattern      = regexp.MustCompile(`<!-- forgejo-mcp:transferred-to (\S+)#(\d+) -->`)
+	transferCommentPattern = regexp.MustCompile(`<!-- forgejo-mcp:transferred-comment (\d+) -->`)
+)
+
+var TransferIssueTool = mcp.NewTool(
+	TransferIssueToolName,
+	mcp.WithDescription("Move an issue to another repository by recreating it there: title, body, due date, labels mapped by name where the target has them, assignees, milestone by title, comments quoted with their original author and time, and attachments re-uploaded (files of 1 MiB and more are skipped and keep their original link). "+
+		"Both issues are cross-linked and the source is closed. The call is a dry run by default and reports what would be copied and dropped; re-run with dry_run=false to transfer. "+
+		"Hidden markers in the copied issue and comments make a retry after a partial failure resume where it stopped instead of creating duplicates. Pull requests cannot be transferred."),
+	mcp.WithString("owner", mcp.Required(), mcp.Description(params.Owner)),
+	mcp.WithString("repo", mcp.Required(), mcp.Description(params.Repo)),
+	mcp.WithNumber("index", mcp.Required(), mcp.Description(params.IssueIndex)),
+	mcp.WithString("target_owner", mcp.Description("Owner of the target repository; defaults to owner")),
+	mcp.WithString("target_repo", mcp.Required(), mcp.Description("Name of the target repository")),
+	mcp.WithBoolean("dry_run", mcp.Description("Preview only (default true)"), mcp.DefaultBool(true)),
+)
+
+func RegisterTransferTool(s *server.MCPServer) {
+	s.AddTool(TransferIssueTool, TransferIssueFn)
+}
+
+type transferResult struct {
+	DryRun             bool     `json:"dry_run"`
+	Source             string   `json:"source"`
+	Target             string   `json:"target,omitempty"`
+	TargetURL          string   `json:"target_url,omitempty"`
+	Resumed            bool     `json:"resumed,omitempty"`
+	Labels             []string `json:"labels"`
+	DroppedLabels      []string `json:"dropped_labels,omitempty"`
+	Assignees          []string `json:"assignees"`
+	DroppedAssignees   []string `json:"dropped_assignees,omitempty"`
+	Milestone          string   `json:"milestone,omitempty"`
+	DroppedMilestone   string   `json:"dropped_milestone,omitempty"`
+	Comments           int      `json:"comments"`
+	CommentsPresent    int      `json:"comments_already_copied,omitempty"`
+	Attachments        int      `json:"attachments"`
+	SkippedAttachments []string `json:"skipped_attachments,omitempty"`
+	SourceClosed       bool     `json:"source_closed"`
+}
+
+// transfer carries the state of one transfer_issue call.
+type transfer struct {
+	ctx          context.Context
+	client       *forgejo_sdk.Client
+	dryRun       bool
+	owner, repo  string
+	targetOwner  string
+	targetRepo   string
+	issue        *forgejo_sdk.Issue
+	comments     []*forgejo_sdk.Comment
+	target       *forgejo_sdk.Issue
+	replacements []string // old, new download URL pairs for strings.NewReplacer
+	result       transferResult
+}
+
+func issueKey(owner, repo string, index int64) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, index)
+}
+
+// quoteMarkdown prefixes every line with "> ".
+func quoteMarkdown(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func commentAuthor(c *forgejo_sdk.Comment) string {
+	if c.OriginalAuthor != "" {
+		return c.OriginalAuthor
+	}
+	if c.Poster != nil {
+		return c.Poster.UserName
+	}
+	return "ghost"
+}
+
+// findTarget looks for the issue an earlier attempt created: first through
+// the source's cross-link comment, then, for an attempt that stopped before
+// posting it, through a title search in the target repository.
+func (t *transfer) findTarget() error {
+	from := fmt.Sprintf(transferFromMarker, issueKey(t.owner, t.repo, t.issue.Index))
+	want := strings.ToLower(t.targetOwner + "/" + t.targetRepo)
+	for _, c := range t.comments {
+		m := transferToPattern.FindStringSubmatch(c.Body)
+		if m == nil || strings.ToLower(m[1]) != want {
+			continue
+		}
+		index, _ := strconv.ParseInt(m[2], 10, 64)
+		issue, resp, err := t.client.GetIssue(t.targetOwner, t.targetRepo, index)
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return fmt.Errorf("get target issue: %w", err)
+		}
+		if strings.Contains(issue.Body, from) {
+			t.target = issue
+			return nil
+		}
+	}
+	candidates, _, err := t.client.ListRepoIssues(t.targetOwner, t.targetRepo, forgejo_sdk.ListIssueOption{
+		ListOptions: forgejo_sdk.ListOptions{Page: 1, PageSize: 50},
+		State:       forgejo_sdk.StateAll,
+		Type:        forgejo_sdk.IssueTypeIssue,
+		KeyWord:     t.issue.Title,
+	})
+	if err != nil {
+		return fmt.Errorf("search target repository: %w", err)
+	}
+	for _, issue := range candidates {
+		if strings.Contains(issue.Body, from) {
+			t.target = issue
+			return nil
+		}
+	}
+	return nil
+}
+
+// mapLabels keeps the source's labels the target repository (or its org)
+// has under the same name.
+func (t *transfer) mapLabels() ([]int64, error) {
+	if len(t.issue.Labels) == 0 {
+		return nil, nil
+	}
+	names := make([]string, len(t.issue.Labels))
+	for i, l := range t.issue.Labels {
+		names[i] = l.Name
+	}
+	ids, missing, err := labelIDsByName(t.ctx, t.client, t.targetOwner, t.targetRepo, names)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if slices.Contains(missing, name) {
+			t.result.DroppedLabels = append(t.result.DroppedLabels, name)
+		} else {
+			t.result.Labels = append(t.result.Labels, name)
+		}
+	}
+	return ids, nil
+}
+
+// mapMilestone finds the target milestone with the source milestone's title.
+func (t *transfer) mapMilestone() (int64, error) {
+	if t.issue.Milestone == nil {
+		return 0, nil
+	}
+	title := t.issue.Milestone.Title
+	m, resp, err := t.client.GetMilestoneByName(t.targetOwner, t.targetRepo, title)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			t.result.DroppedMilestone = title
+			return 0, nil
+		}
+		return 0, fmt.Errorf("get target milestone %q: %w", title, err)
+	}
+	t.result.Milestone = m.Title
+	return m.ID, nil
+}
+
+// createTarget creates the target issue. Forgejo answers 422 for assignees
+// without access to the target repository, so that failure is retried once
+// without them.
+func (t *transfer) createTarget(labels []int64, milestone int64) error {
+	source := issueKey(t.owner, t.repo, t.issue.Index)
+	author := "ghost"
+	if t.issue.Poster != nil {
+		author = t.issue.Poster.UserName
+	}
+	parts := []string{fmt.Sprintf("_Moved from [%s](%s); originally opened by %s on %s._",
+		source, t.issue.HTMLURL, author, t.issue.Created.UTC().Format(time.RFC3339))}
+	if body := strings.TrimSpace(t.issue.Body); body != "" {
+		parts = append(parts, body)
+	}
+	parts = append(parts, fmt.Sprintf(transferFromMarker, source))
+	opt := forgejo_sdk.CreateIssueOption{
+		Title:     t.issue.Title,
+		Body:      strings.Join(parts, "\n\n"),
+		Assignees: t.result.Assignees,
+		Deadline:  t.issue.Deadline,
+		Milestone: milestone,
+		Labels:    labels,
+	}
+	issue, resp, err := t.client.CreateIssue(t.targetOwner, t.targetRepo, opt)
+	if err != nil && len(opt.Assignees) > 0 && resp != nil && resp.StatusCode == http.StatusUnprocessableEntity {
+		opt.Assignees = nil
+		var retryErr error
+		if issue, _, retryErr = t.client.CreateIssue(t.targetOwner, t.targetRepo, opt); retryErr == nil {
+			t.result.DroppedAssignees, t.result.Assignees = t.result.Assignees, []string{}
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("create target issue: %w", err)
+	}
+	t.target = issue
+	return nil
+}
+
+// crossLink comments on the source with a link to the target, unless an
+// earlier attempt already did.
+func (t *transfer) crossLink() error {
+	key := issueKey(t.targetOwner, t.targetRepo, t.target.Index)
+	marker := fmt.Sprintf(transferToMarker, key)
+	for _, c := range t.comments {
+		if strings.Contains(c.Body, marker) {
+			return nil
+		}
+	}
+	body := fmt.Sprintf("Moved to [%s](%s).\n\n%s", key, t.target.HTMLURL, marker)
+	if _, _, err := t.client.CreateIssueComment(t.owner, t.repo, t.issue.Index, forgejo_sdk.CreateIssueCommentOption{Body: body}); err != nil {
+		return fmt.Errorf("cross-link source issue: %w", err)
+	}
+	return nil
+}
+
+// copyAttachments uploads the attachments of src that dst (an issue or
+// comment asset path in the target) lacks by name. Existing ones count as
+// copied too, so their URLs are rewritten on a retry.
+func (t *transfer) copyAttachments(src, dst string, dstExists bool) error {
+	var assets []*forgejo_sdk.Attachment
+	if err := forgejo.DoJSONList(t.ctx, http.MethodGet, src, &assets); err != nil {
+		return fmt.Errorf("list source attachments: %w", err)
+	}
+	if len(assets) == 0 {
+		return nil
+	}
+	present := map[string]*forgejo_sdk.Attachment{}
+	if dstExists {
+		var existing []*forgejo_sdk.Attachment
+		if err := forgejo.DoJSONList(t.ctx, http.MethodGet, dst, &existing); err != nil {
+			return fmt.Errorf("list target attachments: %w", err)
+		}
+		for _, att := range existing {
+			present[att.Name] = att
+		}
+	}
+	for _, att := range assets {
+		if copied, ok := present[att.Name]; ok {
+			t.replacements = append(t.replacements, att.DownloadURL, copied.DownloadURL)
+			continue
+		}
+		if att.Size >= forgejo.MaxInlineDownloadBytes {
+			t.result.SkippedAttachments = append(t.result.SkippedAttachments, att.Name+": too large to copy, original link kept")
+			continue
+		}
+		t.result.Attachments++
+		if t.dryRun {
+			continue
+		}
+		data, contentType, err := forgejo.DoRaw(t.ctx, att.DownloadURL)
+		if err != nil {
+			if errors.Is(err, forgejo.ErrPayloadTooLarge) {
+				t.result.Attachments--
+				t.result.SkippedAttachments = append(t.result.SkippedAttachments, att.Name+": too large to copy, original link kept")
+				continue
+			}
+			return fmt.Errorf("download attachment %q: %w", att.Name, err)
+		}
+		var copied forgejo_sdk.Attachment
+		if err := forgejo.DoMultipart(t.ctx, http.MethodPost, dst, "attachment", att.Name, contentType, bytes.NewReader(data), &copied); err != nil {
+			return fmt.Errorf("upload attachment %q: %w", att.Name, err)
+		}
+		t.replacements = append(t.replacements, att.DownloadURL, copied.DownloadURL)
+	}
+	return nil
+}
+
+// rewriteLinks points links to source attachments at their copies.
+func (t *transfer) rewriteLinks(body string) string {
+	if len(t.replacements) == 0 {
+		return body
+	}
+	return strings.NewReplacer(t.replacements...).Replace(body)
+}
+
+// copyComments quotes every source comment into the target, skipping those
+// an earlier attempt copied and the transfer's own cross-link comments.
+func (t *transfer) copyComments() error {
+	copied := map[int64]*forgejo_sdk.Comment{}
+	if t.target != nil {
+		existing, _, err := t.client.ListIssueComments(t.targetOwner, t.targetRepo, t.target.Index, forgejo_sdk.ListIssueCommentOptions{})
+		if err != nil {
+			return fmt.Errorf("list target comments: %w", err)
+		}
+		for _, c := range existing {
+			if m := transferCommentPattern.FindStringSubmatch(c.Body); m != nil {
+				id, _ := strconv.ParseInt(m[1], 10, 64)
+				copied[id] = c
+			}
+		}
+	}
+	for _, c := range t.comments {
+		if transferToPattern.MatchString(c.Body) {
+			continue
+		}
+		src := forgejo.APIPath("repos", t.owner, t.repo, "issues", "comments", c.ID, "assets")
+		target, exists := copied[c.ID]
+		if exists {
+			t.result.CommentsPresent++
+		} else {
+			t.result.Comments++
+		}
+		if t.dryRun {
+			dst := ""
+			if exists {
+				dst = forgejo.APIPath("repos", t.targetOwner, t.targetRepo, "issues", "comments", target.ID, "assets")
+			}
+			if err := t.copyAttachments(src, dst, exists); err != nil {
+				return err
+			}
+			continue
+		}
+		if !exists {
+			body := fmt.Sprintf("**%s** commented on %s:\n\n%s\n\n%s",
+				commentAuthor(c), c.Created.UTC().Format(time.RFC3339), quoteMarkdown(c.Body), fmt.Sprintf(transferCommentMarker, c.ID))
+			var err error
+			if target, _, err = t.client.CreateIssueComment(t.targetOwner, t.targetRepo, t.target.Index, forgejo_sdk.CreateIssueCommentOption{Body: body}); err != nil {
+				return fmt.Errorf("copy comment %d: %w", c.ID, err)
+			}
+		}
+		dst := forgejo.APIPath("repos", t.targetOwner, t.targetRepo, "issues", "comments", target.ID, "assets")
+		if err := t.copyAttachments(src, dst, exists); err != nil {
+			return err
+		}
+		if body := t.rewriteLinks(target.Body); body != target.Body {
+			if _, _, err := t.client.EditIssueComment(t.targetOwner, t.targetRepo, target.ID, forgejo_sdk.EditIssueCommentOption{Body: body}); err != nil {
+				return fmt.Errorf("rewrite attachment links in comment %d: %w", target.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *transfer) run() error {
+	if err := t.findTarget(); err != nil {
+		return err
+	}
+	labels, err := t.mapLabels()
+	if err != nil {
+		return err
+	}
+	milestone, err := t.mapMilestone()
+	if err != nil {
+		return err
+	}
+
+	resumed := t.target != nil
+	if !resumed && !t.dryRun {
+		if err := t.createTarget(labels, milestone); err != nil {
+			return err
+		}
+	}
+	if t.target != nil {
+		t.result.Resumed = resumed
+		t.result.Target = issueKey(t.targetOwner, t.targetRepo, t.target.Index)
+		t.result.TargetURL = t.target.HTMLURL
+	}
+	if !t.dryRun {
+		// Linked first: from here on a retry finds the target through it.
+		if err := t.crossLink(); err != nil {
+			return err
+		}
+	}
+
+	src := forgejo.APIPath("repos", t.owner, t.repo, "issues", t.issue.Index, "assets")
+	dst := ""
+	if t.target != nil {
+		dst = forgejo.APIPath("repos", t.targetOwner, t.targetRepo, "issues", t.target.Index, "assets")
+	}
+	if err := t.copyAttachments(src, dst, resumed); err != nil {
+		return err
+	}
+	if !t.dryRun {
+		if body := t.rewriteLinks(t.target.Body); body != t.target.Body {
+			if _, _, err := t.client.EditIssue(t.targetOwner, t.targetRepo, t.target.Index, forgejo_sdk.EditIssueOption{Body: &body}); err != nil {
+				return fmt.Errorf("rewrite attachment links in target issue: %w", err)
+			}
+		}
+	}
+	if err := t.copyComments(); err != nil {
+		return err
+	}
+
+	if t.issue.State == forgejo_sdk.StateClosed {
+		t.result.SourceClosed = true
+	} else if !t.dryRun {
+		closed := forgejo_sdk.StateClosed
+		if _, _, err := t.client.EditIssue(t.owner, t.repo, t.issue.Index, forgejo_sdk.EditIssueOption{State: &closed}); err != nil {
+			return fmt.Errorf("close source issue: %w", err)
+		}
+		t.result.SourceClosed = true
+	}
+	return nil
+}
+
+func TransferIssueFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called TransferIssueFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+	index, _ := to.Float64(args["index"])
+	targetOwner, _ := args["target_owner"].(string)
+	if targetOwner == "" {
+		targetOwner = owner
+	}
+	targetRepo, _ := args["target_repo"].(string)
+	if targetRepo == "" {
+		return to.ErrorResult(fmt.Errorf("target_repo is required"))
+	}
+	if strings.EqualFold(owner+"/"+repo, targetOwner+"/"+targetRepo) {
+		return to.ErrorResult(fmt.Errorf("target repository is the source repository"))
+	}
+	dryRun := true
+	if v, ok := args["dry_run"].(bool); ok {
+		dryRun = v
+	}
+
+	client, err := forgejo.Client(ctx)
+	if err != nil {
+		return to.ErrorResult(err)
+	}
+	issue, _, err := client.GetIssue(owner, repo, int64(index))
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("get issue err: %w", err))
+	}
+	if issue.PullRequest != nil {
+		return to.ErrorResult(fmt.Errorf("%s is a pull request; only issues can be transferred", issueKey(owner, repo, issue.Index)))
+	}
+	comments, _, err := client.ListIssueComments(owner, repo, issue.Index, forgejo_sdk.ListIssueCommentOptions{})
+	if err != nil {
+		return to.ErrorResult(fmt.Errorf("list comments err: %w", err))
+	}
+
+	t := &transfer{
+		ctx:         ctx,
+		client:      client,
+		dryRun:      dryRun,
+		owner:       owner,
+		repo:        repo,
+		targetOwner: targetOwner,
+		targetRepo:  targetRepo,
+		issue:       issue,
+		comments:    comments,
+		result: transferResult{
+			DryRun:    dryRun,
+			Source:    issueKey(owner, repo, issue.Index),
+			Labels:    []string{},
+			Assignees: []string{},
+		},
+	}
+	for _, u := range issue.Assignees {
+		t.result.Assignees = append(t.result.Assignees, u.UserName)
+	}
+	if err := t.run(); err != nil {
+		// Report the progress made; a retry resumes from it.
+		if t.result.Target != "" {
+			return to.ErrorResult(fmt.Errorf("transfer to %s stopped, retry to resume: %w", t.result.Target, err))
+		}
+		return to.ErrorResult(fmt.Errorf("transfer issue err: %w", err))
+	}
+	return to.TextResult(t.result)
+}
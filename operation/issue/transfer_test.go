@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package issue
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+func transferServer(t *testing.T) (*forgejotest.Server, *forgejo_sdk.Client) {
+	t.Helper()
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	for _, repo := range []string{"old", "new"} {
+		srv.CreateRepo("alice", repo)
+		srv.CreateLabel("alice", repo, "bug", "ee0701")
+		srv.CreateMilestone("alice", repo, "1.0")
+	}
+	srv.CreateLabel("alice", "old", "triage", "cccccc")
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, client
+}
+
+func transferCall(t *testing.T, index int64, dryRun bool) transferResult {
+	t.Helper()
+	res, err := TransferIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "old", "index": float64(index), "target_repo": "new", "dry_run": dryRun,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out transferResult
+	resultJSON(t, res, &out)
+	return out
+}
+
+func TestTransferIssue_CopiesAndResumes(t *testing.T) {
+	srv, client := transferServer(t)
+	if _, err := CreateIssueFn(context.Background(), makeReq(map[string]any{
+		"owner": "alice", "repo": "old", "title": "Login fails", "body": "Steps to reproduce.",
+		"labels": "bug,triage", "milestone": "1.0", "assignees": "forgejotest",
+	})); err != nil {
+		t.Fatal(err)
+	}
+	shot := srv.AddIssueAttachment("alice", "old", 1, "shot.png", []byte("png bytes"))
+	body := "Steps to reproduce.\n\n![shot](" + shot.DownloadURL + ")"
+	if _, _, err := client.EditIssue("alice", "old", 1, forgejo_sdk.EditIssueOption{Body: &body}); err != nil {
+		t.Fatal(err)
+	}
+	first, _, err := client.CreateIssueComment("alice", "old", 1, forgejo_sdk.CreateIssueCommentOption{Body: "Same here.\nOn Firefox too."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.AddCommentAttachment("alice", "old", first.ID, "log.txt", []byte("stack trace"))
+	if _, _, err := client.CreateIssueComment("alice", "old", 1, forgejo_sdk.CreateIssueCommentOption{Body: "Fixed upstream?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	preview := transferCall(t, 1, true)
+	if !preview.DryRun || preview.Target != "" || preview.Comments != 2 || preview.Attachments != 2 ||
+		preview.Milestone != "1.0" || len(preview.Labels) != 1 || len(preview.DroppedLabels) != 1 || preview.DroppedLabels[0] != "triage" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if issues, _, _ := client.ListRepoIssues("alice", "new", forgejo_sdk.ListIssueOption{State: forgejo_sdk.StateAll}); len(issues) != 0 {
+		t.Fatal("dry run created an issue")
+	}
+
+	done := transferCall(t, 1, false)
+	if done.Target != "alice/new#1" || done.Resumed || done.Comments != 2 || done.Attachments != 2 || !done.SourceClosed {
+		t.Fatalf("transfer = %+v", done)
+	}
+	target, _, err := client.GetIssue("alice", "new", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(target.Body, "_Moved from [alice/old#1]") || strings.Contains(target.Body, shot.DownloadURL) ||
+		!strings.Contains(target.Body, "/attachments/") || target.Milestone == nil || len(target.Labels) != 1 ||
+		len(target.Assignees) != 1 {
+		t.Fatalf("target = %+v\nbody:\n%s", target, target.Body)
+	}
+	comments, _, _ := client.ListIssueComments("alice", "new", 1, forgejo_sdk.ListIssueCommentOptions{})
+	if len(comments) != 2 || !strings.HasPrefix(comments[0].Body, "**forgejotest** commented on ") ||
+		!strings.Contains(comments[0].Body, "> Same here.\n> On Firefox too.") {
+		t.Fatalf("target comments = %+v", comments)
+	}
+	source, _, _ := client.GetIssue("alice", "old", 1)
+	sourceComments, _, _ := client.ListIssueComments("alice", "old", 1, forgejo_sdk.ListIssueCommentOptions{})
+	if source.State != forgejo_sdk.StateClosed || len(sourceComments) != 3 || !strings.Contains(sourceComments[2].Body, "alice/new#1") {
+		t.Fatalf("source = %s with comments %+v", source.State, sourceComments)
+	}
+
+	// A retry finds everything in place and creates nothing.
+	again := transferCall(t, 1, false)
+	if !again.Resumed || again.Target != "alice/new#1" || again.Comments != 0 || again.CommentsPresent != 2 || again.Attachments != 0 {
+		t.Fatalf("retry = %+v", again)
+	}
+	issues, _, _ := client.ListRepoIssues("alice", "new", forgejo_sdk.ListIssueOption{State: forgejo_sdk.StateAll})
+	comments, _, _ = client.ListIssueComments("alice", "new", 1, forgejo_sdk.ListIssueCommentOptions{})
+	if len(issues) != 1 || len(comments) != 2 {
+		t.Fatalf("retry duplicated: %d issues, %d comments", len(issues), len(comments))
+	}
+}
+
+func TestTransferIssue_ResumesBeforeCrossLink(t *testing.T) {
+	srv, _ := transferServer(t)
+	srv.CreateIssue("alice", "old", "Flaky build", "")
+	// An attempt that stopped right after creating the target.
+	srv.CreateIssue("alice", "new", "Unrelated", "")
+	srv.CreateIssue("alice", "new", "Flaky build", "copy\n\n<!-- forgejo-mcp:transferred-from alice/old#1 -->")
+
+	out := transferCall(t, 1, false)
+	if !out.Resumed || out.Target != "alice/new#2" || !out.SourceClosed {
+		t.Fatalf("transfer = %+v", out)
+	}
+}
+
+func TestTransferIssue_Validation(t *testing.T) {
+	srv, _ := transferServer(t)
+	srv.CreateIssue("alice", "old", "Flaky build", "")
+	for name, args := range map[string]map[string]any{
+		"same repo":   {"target_repo": "old"},
+		"no target":   {},
+		"no issue":    {"target_repo": "new", "index": float64(9)},
+		"unknown dst": {"target_repo": "nope", "dry_run": false},
+	} {
+		args["owner"], args["repo"] = "alice", "old"
+		if _, ok := args["index"]; !ok {
+			args["index"] = float64(1)
+		}
+		if _, err := TransferIssueFn(context.Background(), makeReq(args)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
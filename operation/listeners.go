@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Per-listener handling of the caller's Authorization header.
+const (
+	// AuthOptional uses the caller's token when sent and falls back to the
+	// server's configured token otherwise. This is the historical behaviour.
+	AuthOptional = "optional"
+	// AuthRequired rejects requests without a token with 401, so the server
+	// token is never used on behalf of anonymous callers.
+	AuthRequired = "required"
+	// AuthNone ignores any caller token and always acts with the server
+	// token; suited to a Unix socket whose file mode is the access control.
+	AuthNone = "none"
+)
+
+const (
+	protocolHTTP = "http"
+	protocolSSE  = "sse"
+
+	httpEndpointPath = "/mcp"
+	shutdownTimeout  = 5 * time.Second
+)
+
+// Listener is one network endpoint serving the MCP protocol over streamable
+// HTTP or SSE.
+type Listener struct {
+	Protocol string
+	Network  string // "tcp" or "unix"
+	Address  string
+	// Mode is applied to a Unix socket after it is created; zero leaves the
+	// umask default.
+	Mode fs.FileMode
+	Auth string
+}
+
+// ParseListener parses a listener spec:
+//
+//	http://:8080                 streamable HTTP on TCP
+//	sse://127.0.0.1:8081         SSE on TCP
+//	http+unix:///run/mcp.sock    streamable HTTP on a Unix socket
+//
+// with optional query parameters auth=optional|required|none and, for Unix
+// sockets, mode=<octal permissions>.
+func ParseListener(spec string) (Listener, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return Listener{}, fmt.Errorf("listener %q: %w", spec, err)
+	}
+	protocol, network, _ := strings.Cut(u.Scheme, "+")
+	if network == "" {
+		network = "tcp"
+	}
+	l := Listener{Protocol: protocol, Network: network, Auth: AuthOptional}
+	if protocol != protocolHTTP && protocol != protocolSSE {
+		return Listener{}, fmt.Errorf("listener %q: protocol must be http or sse", spec)
+	}
+	switch network {
+	case "tcp":
+		if u.Host == "" {
+			return Listener{}, fmt.Errorf("listener %q: missing host:port", spec)
+		}
+		l.Address = u.Host
+	case "unix":
+		l.Address = u.Path
+		if l.Address == "" {
+			return Listener{}, fmt.Errorf("listener %q: missing socket path", spec)
+		}
+	default:
+		return Listener{}, fmt.Errorf("listener %q: network must be tcp or unix", spec)
+	}
+
+	query := u.Query()
+	if auth := query.Get("auth"); auth != "" {
+		switch auth {
+		case AuthOptional, AuthRequired, AuthNone:
+			l.Auth = auth
+		default:
+			return Listener{}, fmt.Errorf("listener %q: auth must be optional, required or none", spec)
+		}
+	}
+	if mode := query.Get("mode"); mode != "" {
+		if network != "unix" {
+			return Listener{}, fmt.Errorf("listener %q: mode only applies to unix sockets", spec)
+		}
+		perm, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || perm > 0o777 {
+			return Listener{}, fmt.Errorf("listener %q: mode must be octal permissions such as 0660", spec)
+		}
+		l.Mode = fs.FileMode(perm)
+	}
+	return l, nil
+}
+
+func (l Listener) String() string {
+	if l.Network == "unix" {
+		return l.Protocol + "+unix://" + l.Address
+	}
+	return l.Protocol + "://" + l.Address
+}
+
+// servePlan resolves --transport (a comma-separated list of stdio, sse and
+// http) and the --listen specs into whether to serve stdio plus the network
+// listeners to open. The sse and http transports keep listening on
+// --sse-port/--http-port as before.
+func servePlan(transports string, specs []string) (bool, []Listener, error) {
+	stdio := false
+	var listeners []Listener
+	for _, name := range strings.Split(transports, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "stdio":
+			stdio = true
+		case protocolSSE:
+			listeners = append(listeners, Listener{Protocol: protocolSSE, Network: "tcp", Address: fmt.Sprintf(":%d", flag.SSEPort), Auth: AuthOptional})
+		case protocolHTTP:
+			listeners = append(listeners, Listener{Protocol: protocolHTTP, Network: "tcp", Address: fmt.Sprintf(":%d", flag.HTTPPort), Auth: AuthOptional})
+		default:
+			return false, nil, fmt.Errorf("invalid transport type: %s. Must be 'stdio', 'sse', or 'http'", name)
+		}
+	}
+	for _, spec := range specs {
+		l, err := ParseListener(spec)
+		if err != nil {
+			return false, nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	if !stdio && len(listeners) == 0 {
+		return false, nil, errors.New("nothing to serve: set --transport or --listen")
+	}
+	return stdio, listeners, nil
+}
+
+// listen opens the socket. A stale Unix socket left by a crashed process is
+// removed first; any other file at that path is left alone and fails.
+func (l Listener) listen() (net.Listener, error) {
+	if l.Network == "unix" {
+		if info, err := os.Lstat(l.Address); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			if conn, err := net.Dial("unix", l.Address); err == nil {
+				_ = conn.Close()
+				return nil, fmt.Errorf("%s is in use by another process", l.Address)
+			}
+			_ = os.Remove(l.Address)
+		}
+	}
+	ln, err := net.Listen(l.Network, l.Address)
+	if err != nil {
+		return nil, err
+	}
+	if l.Network == "unix" && l.Mode != 0 {
+		if err := os.Chmod(l.Address, l.Mode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("chmod %s: %w", l.Address, err)
+		}
+	}
+	return ln, nil
+}
+
+// handler builds the MCP handler for this listener wrapped in its auth
+// policy. Every listener shares s, so tool registrations, caches and
+// detected server state are common to all of them.
+func (l Listener) handler(s *server.MCPServer) http.Handler {
+	var h http.Handler
+	if l.Protocol == protocolSSE {
+		h = server.NewSSEServer(s, server.WithSSEContextFunc(requestContext))
+	} else {
+		mux := http.NewServeMux()
+		mux.Handle(httpEndpointPath, server.NewStreamableHTTPServer(s, server.WithHTTPContextFunc(requestContext)))
+		h = mux
+	}
+	return authPolicy(l.Auth, h)
+}
+
+func authPolicy(mode string, next http.Handler) http.Handler {
+	switch mode {
+	case AuthNone:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.Clone(r.Context())
+			r.Header.Del("Authorization")
+			next.ServeHTTP(w, r)
+		})
+	case AuthRequired:
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if extractToken(r.Header.Get("Authorization")) == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="forgejo-mcp"`)
+				http.Error(w, "authorization required", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	default:
+		return next
+	}
+}
+
+// serve runs stdio and every listener against one MCP server until the
+// first of them stops, SIGINT/SIGTERM arrives, or stdin closes, then shuts
+// the rest down.
+func serve(s *server.MCPServer, stdio bool, listeners []Listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	type running struct {
+		listener Listener
+		server   *http.Server
+	}
+	var started []running
+	shutdown := func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		for _, r := range started {
+			if err := r.server.Shutdown(shutdownCtx); err != nil {
+				log.Warn("Listener did not shut down cleanly",
+					log.StringField("listener", r.listener.String()),
+					log.ErrorField(err),
+				)
+			}
+		}
+	}
+	defer shutdown()
+
+	done := make(chan error, len(listeners)+1)
+	for _, l := range listeners {
+		ln, err := l.listen()
+		if err != nil {
+			log.Error("Failed to open listener",
+				log.StringField("listener", l.String()),
+				log.ErrorField(err),
+			)
+			return fmt.Errorf("failed to start %s: %w", l, err)
+		}
+		srv := &http.Server{Handler: l.handler(s), ReadHeaderTimeout: 10 * time.Second}
+		started = append(started, running{listener: l, server: srv})
+		go func(l Listener) {
+			err := srv.Serve(ln)
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			if err != nil {
+				err = fmt.Errorf("%s: %w", l, err)
+			}
+			done <- err
+		}(l)
+		log.Info("MCP listener ready",
+			log.StringField("listener", l.String()),
+			log.StringField("auth", l.Auth),
+		)
+	}
+	if stdio {
+		go func() {
+			done <- server.NewStdioServer(s).Listen(ctx, os.Stdin, os.Stdout)
+		}()
+		log.Info("MCP server ready for stdio communication")
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Info("MCP server shutting down")
+		return nil
+	case err := <-done:
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("MCP transport failed", log.ErrorField(err))
+			return err
+		}
+		log.Info("MCP server shutdown")
+		return nil
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestParseListener(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Listener
+		wantErr bool
+	}{
+		{spec: "http://:8080", want: Listener{Protocol: "http", Network: "tcp", Address: ":8080", Auth: AuthOptional}},
+		{spec: "sse://127.0.0.1:9000?auth=required", want: Listener{Protocol: "sse", Network: "tcp", Address: "127.0.0.1:9000", Auth: AuthRequired}},
+		{spec: "http+unix:///run/mcp.sock?mode=0660&auth=none", want: Listener{Protocol: "http", Network: "unix", Address: "/run/mcp.sock", Mode: 0o660, Auth: AuthNone}},
+		{spec: "stdio://", wantErr: true},
+		{spec: "http+udp://:53", wantErr: true},
+		{spec: "http://", wantErr: true},
+		{spec: "http+unix://", wantErr: true},
+		{spec: "http://:8080?auth=maybe", wantErr: true},
+		{spec: "http://:8080?mode=0600", wantErr: true},
+		{spec: "http+unix:///x.sock?mode=rw", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseListener(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseListener(%q) = %+v, want error", tt.spec, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseListener(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServePlan(t *testing.T) {
+	flag.SSEPort, flag.HTTPPort = 8081, 8082
+	t.Cleanup(func() { flag.SSEPort, flag.HTTPPort = 0, 0 })
+
+	stdio, listeners, err := servePlan("stdio, http", []string{"sse+unix:///tmp/a.sock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stdio || len(listeners) != 2 {
+		t.Fatalf("stdio = %v, listeners = %+v", stdio, listeners)
+	}
+	if listeners[0].String() != "http://:8082" || listeners[1].String() != "sse+unix:///tmp/a.sock" {
+		t.Fatalf("listeners = %v, %v", listeners[0], listeners[1])
+	}
+
+	if _, _, err := servePlan("grpc", nil); err == nil || !strings.Contains(err.Error(), "invalid transport type") {
+		t.Fatalf("unknown transport: err = %v", err)
+	}
+	if _, _, err := servePlan("", nil); err == nil {
+		t.Fatal("empty plan was accepted")
+	}
+	if stdio, listeners, err := servePlan("", []string{"http://:0"}); err != nil || stdio || len(listeners) != 1 {
+		t.Fatalf("listen-only plan: %v %v %v", stdio, listeners, err)
+	}
+}
+
+func TestAuthPolicy(t *testing.T) {
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("Authorization")
+	})
+	request := func(mode, auth string) int {
+		seen = ""
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		authPolicy(mode, next).ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := request(AuthOptional, ""); code != http.StatusOK {
+		t.Fatalf("optional without token: %d", code)
+	}
+	if code := request(AuthRequired, ""); code != http.StatusUnauthorized {
+		t.Fatalf("required without token: %d", code)
+	}
+	if code := request(AuthRequired, "token abc"); code != http.StatusOK || seen != "token abc" {
+		t.Fatalf("required with token: %d, seen %q", code, seen)
+	}
+	if code := request(AuthNone, "Bearer abc"); code != http.StatusOK || seen != "" {
+		t.Fatalf("none must strip the caller token: %d, seen %q", code, seen)
+	}
+}
+
+func TestUnixListener_ServesMCPWithSocketMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mcp.sock")
+	l, err := ParseListener("http+unix://" + path + "?mode=0600&auth=none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A stale socket from a crashed process must not block startup.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	ln, err := l.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: l.handler(server.NewMCPServer("test", "1.0.0"))}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 || info.Mode().Perm() != 0o600 {
+		t.Fatalf("socket mode = %v, want socket with 0600", info.Mode())
+	}
+	if _, err := l.listen(); err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Fatalf("second listener on a live socket: err = %v", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"t","version":"0"}}}`
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), `"serverInfo"`) {
+		t.Fatalf("initialize over unix socket: %d %s", resp.StatusCode, data)
+	}
+}
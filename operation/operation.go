@@ -3,7 +3,7 @@ package operation
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"os"
 	"strings"
 
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/actions"
@@ -15,6 +15,7 @@ import (
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/pull"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/release"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/repo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/repocontext"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/search"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/tracking"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/user"
@@ -22,6 +23,7 @@ import (
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/wiki"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/gitremote"
 	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
 
 	"github.com/mark3labs/mcp-go/server"
@@ -49,6 +51,7 @@ func RegisterTool(s *server.MCPServer) {
 	RegisterBranchProtectionTool(s)
 	RegisterHookTool(s)
 	RegisterWikiTool(s)
+	RegisterRepoContextTool(s)
 
 	log.Info("All MCP tools registered successfully")
 }
@@ -130,6 +133,11 @@ func RegisterWikiTool(s *server.MCPServer) {
 	log.Debug("Registered wiki tools")
 }
 
+func RegisterRepoContextTool(s *server.MCPServer) {
+	repocontext.RegisterTool(s)
+	log.Debug("Registered repository context tools")
+}
+
 func extractToken(auth string) string {
 	if auth == "" {
 		return ""
@@ -149,9 +157,27 @@ func extractToken(auth string) string {
 
 func Run(transport, version string) error {
 	flag.Version = version
+	stdio, listeners, err := servePlan(transport, flag.Listeners)
+	if err != nil {
+		log.Error("Invalid transport configuration",
+			log.StringField("transport", transport),
+			log.ErrorField(err),
+		)
+		return err
+	}
 	mcpServer = newMCPServer(version)
 	RegisterTool(mcpServer)
 	RegisterCoreResources(mcpServer)
+	repocontext.Advertise(mcpServer)
+	if flag.DetectRepo {
+		detectRepoContext()
+	}
+	if forgejo.SudoEnabled() {
+		advertiseActAs(mcpServer)
+		log.Info("Impersonation enabled",
+			log.StringField("allowlist", strings.Join(flag.SudoAllowlist, ",")),
+		)
+	}
 
 	// Test connection to Forgejo instance before starting the server
 	log.Info("Testing connection to Forgejo instance",
@@ -167,80 +193,68 @@ func Run(transport, version string) error {
 	log.Info("Successfully connected to Forgejo instance",
 		log.SanitizedURLField("url", flag.URL),
 	)
+	pruneUnsupportedTools(mcpServer)
+	// Probe the configured token's scopes up front so the first tools/list
+	// does not pay for it; per-request tokens are probed on first use.
+	forgejo.ScopesFor(context.Background())
 
-	switch transport {
-	case "stdio":
-		log.Info("Starting MCP server with stdio transport")
-		log.Info("MCP server ready for stdio communication")
-		if err := server.ServeStdio(mcpServer); err != nil {
-			log.Error("MCP stdio server failed",
-				log.ErrorField(err),
-			)
-			return err
-		}
-		log.Info("MCP stdio server shutdown")
-	case "sse":
-		sseServer := server.NewSSEServer(mcpServer, server.WithSSEContextFunc(func(ctx context.Context, r *http.Request) context.Context {
-			if token := extractToken(r.Header.Get("Authorization")); token != "" {
-				return forgejo.WithToken(ctx, token)
-			}
-			return ctx
-		}))
-		log.Info("Starting MCP SSE server",
-			log.IntField("port", flag.SSEPort),
-		)
-		log.Info("MCP SSE server ready for connections",
-			log.IntField("port", flag.SSEPort),
-			log.StringField("endpoint", fmt.Sprintf("http://localhost:%d", flag.SSEPort)),
-		)
-		if err := sseServer.Start(fmt.Sprintf(":%d", flag.SSEPort)); err != nil {
-			log.Error("Failed to start SSE server",
-				log.IntField("port", flag.SSEPort),
-				log.ErrorField(err),
-			)
-			return fmt.Errorf("failed to start SSE server: %w", err)
-		}
-		log.Info("MCP SSE server shutdown")
-	case "http":
-		httpServer := server.NewStreamableHTTPServer(mcpServer, server.WithHTTPContextFunc(func(ctx context.Context, r *http.Request) context.Context {
-			if token := extractToken(r.Header.Get("Authorization")); token != "" {
-				return forgejo.WithToken(ctx, token)
-			}
-			return ctx
-		}))
-		log.Info("Starting MCP streamable HTTP server",
-			log.IntField("port", flag.HTTPPort),
-		)
-		log.Info("MCP streamable HTTP server ready for connections",
-			log.IntField("port", flag.HTTPPort),
-			log.StringField("endpoint", fmt.Sprintf("http://localhost:%d", flag.HTTPPort)),
-		)
-		if err := httpServer.Start(fmt.Sprintf(":%d", flag.HTTPPort)); err != nil {
-			log.Error("Failed to start streamable HTTP server",
-				log.IntField("port", flag.HTTPPort),
-				log.ErrorField(err),
-			)
-			return fmt.Errorf("failed to start streamable HTTP server: %w", err)
-		}
-		log.Info("MCP streamable HTTP server shutdown")
-	default:
-		log.Error("Invalid transport configuration",
-			log.StringField("transport", transport),
-			log.StringField("valid_options", "stdio, sse, http"),
-		)
-		return fmt.Errorf("invalid transport type: %s. Must be 'stdio', 'sse', or 'http'", transport)
+	return serve(mcpServer, stdio, listeners)
+}
+
+// detectRepoContext seeds the default repository from the git checkout the
+// server was started in. Failure only means callers must name owner/repo.
+func detectRepoContext() {
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Warn("Cannot detect repository context", log.ErrorField(err))
+		return
+	}
+	found, err := gitremote.Detect(dir, flag.URL)
+	if err != nil {
+		log.Info("No repository context detected", log.ErrorField(err))
+		return
 	}
-	return nil
+	repocontext.SetDetected(found.Owner, found.Name, found.Remote)
+	log.Info("Detected repository context",
+		log.StringField("owner", found.Owner),
+		log.StringField("repo", found.Name),
+		log.StringField("remote", found.Remote),
+	)
 }
 
 func testConnection() error {
 	return forgejo.VerifyConnection()
 }
 
+// pruneUnsupportedTools removes version-gated tools the connected server is
+// known not to support, so clients never see them in tools/list. When the
+// version cannot be detected every tool stays registered and the handlers
+// fall back to their own call-time check.
+func pruneUnsupportedTools(s *server.MCPServer) {
+	info, ok := forgejo.DetectServer(context.Background())
+	if !ok {
+		return
+	}
+	var unsupported []string
+	for name, capability := range actions.ToolCapabilities {
+		if enabled, reason := info.Supports(capability); !enabled {
+			unsupported = append(unsupported, name)
+			log.Info("Skipping tool unsupported by server",
+				log.StringField("tool", name),
+				log.StringField("reason", reason),
+			)
+		}
+	}
+	if len(unsupported) > 0 {
+		s.DeleteTools(unsupported...)
+	}
+}
+
 func RegisterCoreResources(s *server.MCPServer) {
 	RegisterCommitResource(s)
 	RegisterIssueResources(s)
 	RegisterLabelResources(s)
+	RegisterMilestoneResources(s)
 	RegisterOwnerResource(s)
 	RegisterPullResources(s)
 	RegisterRepoResource(s)
@@ -248,6 +262,7 @@ func RegisterCoreResources(s *server.MCPServer) {
 	RegisterBranchProtectionResources(s)
 	RegisterHookResources(s)
 	RegisterWikiResource(s)
+	RegisterCapabilitiesResource(s)
 	log.Debug("Registered core resource templates")
 }
 
@@ -256,11 +271,21 @@ func RegisterWikiResource(s *server.MCPServer) {
 	log.Debug("Registered wiki resource template")
 }
 
+func RegisterCapabilitiesResource(s *server.MCPServer) {
+	version.RegisterCapabilitiesResource(s)
+	log.Debug("Registered server capabilities resource")
+}
+
 func RegisterLabelResources(s *server.MCPServer) {
 	issue.RegisterLabelResources(s)
 	log.Debug("Registered label resource templates")
 }
 
+func RegisterMilestoneResources(s *server.MCPServer) {
+	issue.RegisterMilestoneResources(s)
+	log.Debug("Registered milestone resource template")
+}
+
 func RegisterBranchProtectionResources(s *server.MCPServer) {
 	branchprotection.RegisterResource(s)
 	log.Debug("Registered branch protection resource templates")
@@ -302,10 +327,20 @@ func RegisterStatusResource(s *server.MCPServer) {
 }
 
 func newMCPServer(version string) *server.MCPServer {
-	return server.NewMCPServer(
+	hooks := &server.Hooks{}
+	s := server.NewMCPServer(
 		"Forgejo MCP Server",
 		version,
 		server.WithLogging(),
 		server.WithResourceCapabilities(false, false),
+		server.WithToolCapabilities(true),
+		server.WithToolFilter(scopeToolFilter),
+		server.WithToolHandlerMiddleware(sudoMiddleware),
+		server.WithToolHandlerMiddleware(repocontext.Middleware),
+		server.WithHooks(hooks),
 	)
+	hooks.AddBeforeAny(notifyOnScopeChange(s))
+	hooks.AddOnUnregisterSession(forgetSessionScopes)
+	hooks.AddOnUnregisterSession(repocontext.Forget)
+	return s
 }
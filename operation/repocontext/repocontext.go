@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package repocontext lets callers omit owner and repo: a session can pin a
+// default with set_repo_context, and the server can pre-seed one from the
+// git checkout it was started in.
+package repocontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/params"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	SetRepoContextToolName = "set_repo_context"
+
+	// SourceSession and SourceGit say where the active context came from.
+	SourceSession = "session"
+	SourceGit     = "git"
+
+	repoArgNote = " (defaults to the repository context)"
+)
+
+// ErrNoContext is returned when a tool call omits owner/repo and neither a
+// session context nor a detected checkout supplies them.
+var ErrNoContext = errors.New("owner and repo are required: pass them or call " + SetRepoContextToolName)
+
+// RepoContext is the default repository for tool calls.
+type RepoContext struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Source string `json:"source"`
+	Remote string `json:"remote,omitempty"`
+}
+
+var (
+	SetRepoContextTool = mcp.NewTool(
+		SetRepoContextToolName,
+		mcp.WithDescription("Pin the default owner/repo for this session so later calls may omit them. Without arguments, report the active context."),
+		mcp.WithString("owner", mcp.Description(params.Owner)),
+		mcp.WithString("repo", mcp.Description(params.Repo)),
+		mcp.WithBoolean("clear", mcp.Description("Drop the session pin and fall back to the detected checkout, if any")),
+	)
+)
+
+var (
+	mu       sync.RWMutex
+	detected *RepoContext
+	// sessions is keyed by MCP session ID; calls without a session (the CLI,
+	// direct handler tests) share the "" entry.
+	sessions = map[string]RepoContext{}
+
+	// repoTools names the tools that take owner and repo, recorded by
+	// Advertise so the middleware leaves every other tool alone.
+	repoTools = map[string]bool{}
+)
+
+func RegisterTool(s *server.MCPServer) {
+	s.AddTool(SetRepoContextTool, SetRepoContextFn)
+}
+
+// SetDetected installs the context found in the server's working directory.
+func SetDetected(owner, repo, remote string) {
+	mu.Lock()
+	defer mu.Unlock()
+	detected = &RepoContext{Owner: owner, Repo: repo, Source: SourceGit, Remote: remote}
+}
+
+func sessionKey(ctx context.Context) string {
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		return session.SessionID()
+	}
+	return ""
+}
+
+// Current returns the context for the calling session: its own pin first,
+// then the detected checkout.
+func Current(ctx context.Context) (RepoContext, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if pinned, ok := sessions[sessionKey(ctx)]; ok {
+		return pinned, true
+	}
+	if detected != nil {
+		return *detected, true
+	}
+	return RepoContext{}, false
+}
+
+// Forget drops a session's pin when the session ends.
+func Forget(ctx context.Context, session server.ClientSession) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(sessions, session.SessionID())
+}
+
+func SetRepoContextFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called SetRepoContextFn")
+	args := req.GetArguments()
+	owner, _ := args["owner"].(string)
+	repo, _ := args["repo"].(string)
+	owner, repo = strings.TrimSpace(owner), strings.TrimSpace(repo)
+	clearPin, _ := args["clear"].(bool)
+
+	switch {
+	case clearPin:
+		mu.Lock()
+		delete(sessions, sessionKey(ctx))
+		mu.Unlock()
+	case owner == "" && repo == "":
+		// Report only.
+	case owner == "" || repo == "":
+		return to.ErrorResult(errors.New("set repo context: owner and repo must be given together"))
+	default:
+		client, err := forgejo.Client(ctx)
+		if err != nil {
+			return to.ErrorResult(err)
+		}
+		// Resolve the repository first so a typo cannot silently redirect
+		// every later call, and store Forgejo's canonical spelling.
+		found, _, err := client.GetRepo(owner, repo)
+		if err != nil {
+			return to.ErrorResult(fmt.Errorf("set repo context %s/%s: %w", owner, repo, err))
+		}
+		pinned := RepoContext{Owner: owner, Repo: found.Name, Source: SourceSession}
+		if found.Owner != nil && found.Owner.UserName != "" {
+			pinned.Owner = found.Owner.UserName
+		}
+		mu.Lock()
+		sessions[sessionKey(ctx)] = pinned
+		mu.Unlock()
+	}
+
+	current, ok := Current(ctx)
+	if !ok {
+		return to.TextResult(map[string]any{"active": false})
+	}
+	return to.TextResult(map[string]any{"active": true, "context": current})
+}
+
+// Middleware fills omitted owner/repo arguments from the caller's context.
+// A call naming only one of them keeps it and takes the other from the
+// context; a call with neither and no context fails with ErrNoContext.
+func Middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		mu.RLock()
+		applies := repoTools[req.Params.Name]
+		mu.RUnlock()
+		if !applies {
+			return next(ctx, req)
+		}
+		args := req.GetArguments()
+		owner, _ := args["owner"].(string)
+		repo, _ := args["repo"].(string)
+		if strings.TrimSpace(owner) != "" && strings.TrimSpace(repo) != "" {
+			return next(ctx, req)
+		}
+		current, ok := Current(ctx)
+		if !ok {
+			return to.ErrorResult(ErrNoContext)
+		}
+		filled := maps.Clone(args)
+		if filled == nil {
+			filled = map[string]any{}
+		}
+		if strings.TrimSpace(owner) == "" {
+			filled["owner"] = current.Owner
+		}
+		if strings.TrimSpace(repo) == "" {
+			filled["repo"] = current.Repo
+		}
+		req.Params.Arguments = filled
+		log.Debug("Filled repository from context",
+			log.StringField("tool", req.Params.Name),
+			log.StringField("owner", filled["owner"].(string)),
+			log.StringField("repo", filled["repo"].(string)),
+			log.StringField("source", current.Source),
+		)
+		return next(ctx, req)
+	}
+}
+
+// Advertise makes owner and repo optional on every tool that takes both, so
+// clients validating against the schema may omit them.
+func Advertise(s *server.MCPServer) {
+	tools := s.ListTools()
+	updated := make([]server.ServerTool, 0, len(tools))
+	names := make([]string, 0, len(tools))
+	for name, st := range tools {
+		if name == SetRepoContextToolName {
+			continue
+		}
+		tool := st.Tool
+		props := tool.InputSchema.Properties
+		if _, ok := props["owner"]; !ok {
+			continue
+		}
+		if _, ok := props["repo"]; !ok {
+			continue
+		}
+		// Copy before editing: the schema is shared with the package-level
+		// tool definitions, which must stay unchanged.
+		tool.InputSchema.Properties = maps.Clone(props)
+		for _, key := range []string{"owner", "repo"} {
+			if prop, ok := props[key].(map[string]any); ok {
+				prop = maps.Clone(prop)
+				if desc, _ := prop["description"].(string); !strings.HasSuffix(desc, repoArgNote) {
+					prop["description"] = desc + repoArgNote
+				}
+				tool.InputSchema.Properties[key] = prop
+			}
+		}
+		tool.InputSchema.Required = slices.DeleteFunc(slices.Clone(tool.InputSchema.Required), func(name string) bool {
+			return name == "owner" || name == "repo"
+		})
+		updated = append(updated, server.ServerTool{Tool: tool, Handler: st.Handler})
+		names = append(names, name)
+	}
+	mu.Lock()
+	for _, name := range names {
+		repoTools[name] = true
+	}
+	mu.Unlock()
+	s.AddTools(updated...)
+}
+
+// resetForTesting clears all state.
+func resetForTesting() {
+	mu.Lock()
+	defer mu.Unlock()
+	detected = nil
+	sessions = map[string]RepoContext{}
+	repoTools = map[string]bool{}
+}
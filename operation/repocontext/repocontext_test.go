@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package repocontext
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type fakeSession struct{ id string }
+
+func (f fakeSession) Initialize()                                         {}
+func (f fakeSession) Initialized() bool                                   { return true }
+func (f fakeSession) NotificationChannel() chan<- mcp.JSONRPCNotification { return nil }
+func (f fakeSession) SessionID() string                                   { return f.id }
+
+func withSession(id string) context.Context {
+	return server.NewMCPServer("t", "").WithContext(context.Background(), fakeSession{id: id})
+}
+
+var repoTool = mcp.NewTool("get_thing",
+	mcp.WithString("owner", mcp.Required(), mcp.Description("Repository owner")),
+	mcp.WithString("repo", mcp.Required(), mcp.Description("Repository name")),
+	mcp.WithNumber("index", mcp.Required()),
+)
+
+// captureArgs returns a handler recording the arguments it was called with.
+func captureArgs(got *map[string]any) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		*got = req.GetArguments()
+		return mcp.NewToolResultText("ok"), nil
+	}
+}
+
+func call(ctx context.Context, h server.ToolHandlerFunc, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return h(ctx, req)
+}
+
+func TestAdvertise_RelaxesRequiredWithoutTouchingDefinition(t *testing.T) {
+	t.Cleanup(resetForTesting)
+	s := server.NewMCPServer("t", "")
+	s.AddTool(repoTool, captureArgs(new(map[string]any)))
+	RegisterTool(s)
+
+	Advertise(s)
+
+	tool := s.GetTool("get_thing").Tool
+	if slices.Contains(tool.InputSchema.Required, "owner") || slices.Contains(tool.InputSchema.Required, "repo") {
+		t.Fatalf("owner/repo still required: %v", tool.InputSchema.Required)
+	}
+	if !slices.Contains(tool.InputSchema.Required, "index") {
+		t.Fatalf("unrelated required argument dropped: %v", tool.InputSchema.Required)
+	}
+	desc := tool.InputSchema.Properties["owner"].(map[string]any)["description"].(string)
+	if !strings.HasSuffix(desc, repoArgNote) {
+		t.Errorf("owner description = %q, want context note", desc)
+	}
+	if !slices.Contains(repoTool.InputSchema.Required, "owner") {
+		t.Fatal("package-level tool definition was mutated")
+	}
+	if repoTools[SetRepoContextToolName] {
+		t.Fatal("set_repo_context must not be filled from its own context")
+	}
+}
+
+func TestMiddleware_FillsFromDetectedAndSessionContext(t *testing.T) {
+	t.Cleanup(resetForTesting)
+	s := server.NewMCPServer("t", "")
+	var got map[string]any
+	s.AddTool(repoTool, captureArgs(&got))
+	Advertise(s)
+	handler := Middleware(captureArgs(&got))
+
+	if _, err := call(context.Background(), handler, "get_thing", map[string]any{"index": 1.0}); !errors.Is(err, ErrNoContext) {
+		t.Fatalf("without context: err = %v, want ErrNoContext", err)
+	}
+
+	SetDetected("alice", "demo", "origin")
+	if _, err := call(withSession("a"), handler, "get_thing", map[string]any{"index": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	if got["owner"] != "alice" || got["repo"] != "demo" {
+		t.Fatalf("detected context not applied: %v", got)
+	}
+
+	mu.Lock()
+	sessions["b"] = RepoContext{Owner: "bob", Repo: "tools", Source: SourceSession}
+	mu.Unlock()
+	if _, err := call(withSession("b"), handler, "get_thing", map[string]any{"repo": "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if got["owner"] != "bob" || got["repo"] != "other" {
+		t.Fatalf("session pin with explicit repo: %v", got)
+	}
+
+	if _, err := call(withSession("b"), handler, "unrelated_tool", map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, filled := got["owner"]; filled {
+		t.Fatalf("middleware touched a tool without owner/repo: %v", got)
+	}
+}
+
+func TestSetRepoContext_ValidatesAndPinsPerSession(t *testing.T) {
+	t.Cleanup(resetForTesting)
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("Alice", "Demo")
+
+	ctxA, ctxB := withSession("a"), withSession("b")
+	if _, err := call(ctxA, SetRepoContextFn, SetRepoContextToolName, map[string]any{"owner": "alice", "repo": "missing"}); err == nil {
+		t.Fatal("unknown repository was pinned")
+	}
+	result, err := call(ctxA, SetRepoContextFn, SetRepoContextToolName, map[string]any{"owner": "alice", "repo": "demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	if !strings.Contains(text, `"owner":"Alice"`) || !strings.Contains(text, `"repo":"Demo"`) {
+		t.Fatalf("pin did not use canonical names: %s", text)
+	}
+	if _, ok := Current(ctxB); ok {
+		t.Fatal("pin leaked into another session")
+	}
+
+	if _, err := call(ctxA, SetRepoContextFn, SetRepoContextToolName, map[string]any{"clear": true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Current(ctxA); ok {
+		t.Fatal("clear did not drop the pin")
+	}
+	if _, err := call(ctxA, SetRepoContextFn, SetRepoContextToolName, map[string]any{"owner": "alice"}); err == nil {
+		t.Fatal("owner without repo was accepted")
+	}
+}
@@ -495,6 +495,35 @@ func ParseHooks(uri string) (HooksParams, error) {
 	return HooksParams{Owner: parts[0], Repo: parts[1]}, nil
 }
 
+// MilestoneParams holds parsed fields from forgejo://repo/{owner}/{repo}/milestone/{id}.
+type MilestoneParams struct {
+	Owner string
+	Repo  string
+	ID    int64
+}
+
+// ParseMilestone parses forgejo://repo/{owner}/{repo}/milestone/{id}.
+// Returns ErrInvalidParams if the id is not numeric.
+func ParseMilestone(uri string) (MilestoneParams, error) {
+	u, err := parseForgejoURI(uri)
+	if err != nil {
+		return MilestoneParams{}, err
+	}
+	if u.Host != "repo" {
+		return MilestoneParams{}, fmt.Errorf("%w: expected forgejo://repo/..., got %q", ErrInvalidParams, uri)
+	}
+	parts := splitPath(u.Path)
+	// parts: [owner, repo, "milestone", id]
+	if len(parts) != 4 || parts[2] != "milestone" {
+		return MilestoneParams{}, fmt.Errorf("%w: expected forgejo://repo/{owner}/{repo}/milestone/{id}, got %q", ErrInvalidParams, uri)
+	}
+	id, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return MilestoneParams{}, fmt.Errorf("%w: invalid URI %q: id must be numeric", ErrInvalidParams, uri)
+	}
+	return MilestoneParams{Owner: parts[0], Repo: parts[1], ID: id}, nil
+}
+
 func parseForgejoURI(uri string) (*url.URL, error) {
 	u, err := url.Parse(uri)
 	if err != nil {
@@ -81,6 +81,23 @@ func TestParseIssue_NonNumericIndex(t *testing.T) {
 	}
 }
 
+func TestParseMilestone_HappyPath(t *testing.T) {
+	p, err := ParseMilestone("forgejo://repo/goern/forgejo-mcp/milestone/7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Owner != "goern" || p.Repo != "forgejo-mcp" || p.ID != 7 {
+		t.Errorf("unexpected params: %+v", p)
+	}
+}
+
+func TestParseMilestone_NonNumericID(t *testing.T) {
+	_, err := ParseMilestone("forgejo://repo/goern/forgejo-mcp/milestone/v1.0")
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("expected ErrInvalidParams, got %v", err)
+	}
+}
+
 func TestParsePR_HappyPath(t *testing.T) {
 	p, err := ParsePR("forgejo://repo/goern/forgejo-mcp/pr/7")
 	if err != nil {
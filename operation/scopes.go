@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// toolScope is the token scope a tool needs. An empty category means the
+// tool never talks to an authenticated endpoint and is always visible.
+type toolScope struct {
+	category string
+	write    bool
+}
+
+// scopeDomains maps each registration domain to the token scope category
+// its tools need. Tools whose category differs from their domain's are
+// listed in scopeOverrides.
+var scopeDomains = []struct {
+	category string
+	register func(*server.MCPServer)
+}{
+	{forgejo.ScopeUser, RegisterUserTool},
+	{forgejo.ScopeRepository, RegisterRepoTool},
+	{forgejo.ScopeIssue, RegisterIssueTool},
+	{forgejo.ScopeRepository, RegisterPullTool},
+	{forgejo.ScopeRepository, RegisterPullReviewTool},
+	{forgejo.ScopeRepository, RegisterSearchTool},
+	{"", RegisterVersionTool},
+	{forgejo.ScopeRepository, RegisterActionsTool},
+	{forgejo.ScopeOrganization, RegisterOrgTool},
+	{forgejo.ScopeIssue, RegisterTrackingTool},
+	{forgejo.ScopeIssue, RegisterAttachmentTool},
+	{forgejo.ScopeRepository, RegisterReleaseTool},
+	{forgejo.ScopeRepository, RegisterBranchProtectionTool},
+	{forgejo.ScopeRepository, RegisterHookTool},
+	{forgejo.ScopeRepository, RegisterWikiTool},
+	{"", RegisterRepoContextTool},
+}
+
+var scopeOverrides = map[string]string{
+	"check_notifications":          forgejo.ScopeNotification,
+	"get_notification_thread":      forgejo.ScopeNotification,
+	"mark_notification_read":       forgejo.ScopeNotification,
+	"mark_all_notifications_read":  forgejo.ScopeNotification,
+	"list_repo_notifications":      forgejo.ScopeNotification,
+	"mark_repo_notifications_read": forgejo.ScopeNotification,
+	"search_users":                 forgejo.ScopeUser,
+	"search_org_teams":             forgejo.ScopeOrganization,
+	"list_org_labels":              forgejo.ScopeOrganization,
+	"get_org_label":                forgejo.ScopeOrganization,
+	"create_org_label":             forgejo.ScopeOrganization,
+	"edit_org_label":               forgejo.ScopeOrganization,
+	"delete_org_label":             forgejo.ScopeOrganization,
+}
+
+var (
+	toolScopesOnce sync.Once
+	toolScopes     map[string]toolScope
+)
+
+// isReadTool classifies a tool as read-only from its name; every tool in
+// this server follows the list_/get_/search_/find_/check_/download_
+// convention.
+func isReadTool(name string) bool {
+	for _, prefix := range []string{"list_", "get_", "search_", "find_", "check_", "download_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredScopes builds the tool → scope table once by registering each
+// domain on a scratch server and recording which names it added.
+func requiredScopes() map[string]toolScope {
+	toolScopesOnce.Do(func() {
+		toolScopes = map[string]toolScope{}
+		scratch := server.NewMCPServer("scope-table", "")
+		for _, domain := range scopeDomains {
+			before := scratch.ListTools()
+			domain.register(scratch)
+			for name := range scratch.ListTools() {
+				if _, seen := before[name]; seen {
+					continue
+				}
+				category := domain.category
+				if override, ok := scopeOverrides[name]; ok {
+					category = override
+				}
+				toolScopes[name] = toolScope{category: category, write: !isReadTool(name)}
+			}
+		}
+	})
+	return toolScopes
+}
+
+// scopeToolFilter hides tools the caller's token lacks the scope for. It
+// runs for both tools/list and tools/call, so a hidden tool cannot be
+// invoked either. Unknown scopes (probe failed, no token) hide nothing.
+func scopeToolFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	scopes := forgejo.ScopesFor(ctx)
+	if !scopes.Known {
+		return tools
+	}
+	required := requiredScopes()
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		need, ok := required[tool.Name]
+		if !ok || need.category == "" || scopes.Allows(need.category, need.write) {
+			visible = append(visible, tool)
+		}
+	}
+	return visible
+}
+
+// sessionScopes remembers which scope set each session last saw, so a
+// session whose Authorization header switches to a token with different
+// scopes is told to re-fetch its tool list.
+var sessionScopes sync.Map
+
+func notifyOnScopeChange(s *server.MCPServer) server.BeforeAnyHookFunc {
+	return func(ctx context.Context, id any, method mcp.MCPMethod, message any) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return
+		}
+		fingerprint := forgejo.ScopesFor(ctx).Fingerprint()
+		previous, loaded := sessionScopes.Swap(session.SessionID(), fingerprint)
+		if !loaded || previous == fingerprint {
+			return
+		}
+		log.Info("Token scopes changed for session; notifying client",
+			log.StringField("session_id", session.SessionID()),
+		)
+		if err := s.SendNotificationToSpecificClient(session.SessionID(), mcp.MethodNotificationToolsListChanged, nil); err != nil {
+			log.Warn("Failed to send tools/list_changed",
+				log.StringField("session_id", session.SessionID()),
+				log.ErrorField(err),
+			)
+		}
+	}
+}
+
+// forgetSessionScopes drops the remembered scope set when a session ends.
+func forgetSessionScopes(ctx context.Context, session server.ClientSession) {
+	sessionScopes.Delete(session.SessionID())
+}
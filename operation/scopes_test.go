@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestRequiredScopes_CoversEveryTool(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	RegisterTool(s)
+	required := requiredScopes()
+	for name := range s.ListTools() {
+		if _, ok := required[name]; !ok {
+			t.Errorf("tool %s has no scope entry", name)
+		}
+	}
+	if got := required["create_org_label"]; got.category != forgejo.ScopeOrganization || !got.write {
+		t.Errorf("create_org_label = %+v", got)
+	}
+	if got := required["check_notifications"]; got.category != forgejo.ScopeNotification || got.write {
+		t.Errorf("check_notifications = %+v", got)
+	}
+	if got := required["find_similar_issues"]; got.category != forgejo.ScopeIssue || got.write {
+		t.Errorf("find_similar_issues = %+v", got)
+	}
+}
+
+// TestScopeToolFilter_HidesUnscopedTools models a read-only issue token with
+// write probing on: issue reads stay visible, issue writes and every org tool
+// disappear.
+func TestScopeToolFilter_HidesUnscopedTools(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+		if r.Method == http.MethodGet && path != "/orgs" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"token does not have at least one of required scope(s)"}`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "read-only"
+	flag.ProbeWriteScopes = true
+	t.Cleanup(func() { flag.ProbeWriteScopes = false })
+	forgejo.ResetClientForTesting()
+	t.Cleanup(forgejo.ResetClientForTesting)
+
+	tools := []mcp.Tool{
+		{Name: "get_issue_by_index"},
+		{Name: "create_issue"},
+		{Name: "list_my_orgs"},
+		{Name: "get_forgejo_mcp_server_version"},
+	}
+	var names []string
+	for _, tool := range scopeToolFilter(context.Background(), tools) {
+		names = append(names, tool.Name)
+	}
+	got := strings.Join(names, ",")
+	if got != "get_issue_by_index,get_forgejo_mcp_server_version" {
+		t.Fatalf("visible tools = %s", got)
+	}
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+)
+
+const (
+	// ActAsArgument is the per-call tool argument naming the login to
+	// impersonate. It is stripped before the tool handler runs.
+	ActAsArgument = "act_as"
+
+	// ActAsHeader lets an HTTP/SSE client impersonate a login for every call
+	// on the request. A per-call act_as argument takes precedence.
+	ActAsHeader = "X-Forgejo-Act-As"
+
+	actAsDescription = "Login to act as via the admin Sudo header (operator allowlist applies)"
+)
+
+type actAsContextKey struct{}
+
+// requestContext builds the per-request context for the SSE and streamable
+// HTTP transports: the caller's token plus any requested impersonation
+// target. The target is only validated, and only applied, at call time.
+func requestContext(ctx context.Context, r *http.Request) context.Context {
+	if token := extractToken(r.Header.Get("Authorization")); token != "" {
+		ctx = forgejo.WithToken(ctx, token)
+	}
+	if login := strings.TrimSpace(r.Header.Get(ActAsHeader)); login != "" {
+		ctx = context.WithValue(ctx, actAsContextKey{}, login)
+	}
+	return ctx
+}
+
+// sudoMiddleware resolves the impersonation target for a tool call, checks it
+// against the allowlist, audit-logs the decision and hands the handler a
+// context that makes every upstream request carry the Sudo header.
+func sudoMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		login, source := "", ""
+		args := req.GetArguments()
+		if value, ok := args[ActAsArgument]; ok {
+			stripped := maps.Clone(args)
+			delete(stripped, ActAsArgument)
+			req.Params.Arguments = stripped
+			if text, _ := value.(string); strings.TrimSpace(text) != "" {
+				login, source = strings.TrimSpace(text), "argument"
+			}
+		}
+		if login == "" {
+			if header, _ := ctx.Value(actAsContextKey{}).(string); header != "" {
+				login, source = header, "header"
+			}
+		}
+		if login == "" {
+			return next(ctx, req)
+		}
+
+		fields := []zap.Field{
+			log.StringField("tool", req.Params.Name),
+			log.StringField("act_as", login),
+			log.StringField("source", source),
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			fields = append(fields, log.StringField("session_id", session.SessionID()))
+		}
+		if err := forgejo.CheckSudoTarget(login); err != nil {
+			log.WarnCtx(ctx, "Impersonation denied", append(fields, log.ErrorField(err))...)
+			return to.ErrorResult(fmt.Errorf("act_as: %w", err))
+		}
+		log.InfoCtx(ctx, "Impersonating user for tool call", fields...)
+		return next(forgejo.WithSudo(ctx, login), req)
+	}
+}
+
+// advertiseActAs adds the optional act_as argument to every registered tool
+// so clients can discover it. Only called when impersonation is enabled.
+func advertiseActAs(s *server.MCPServer) {
+	tools := s.ListTools()
+	updated := make([]server.ServerTool, 0, len(tools))
+	for _, st := range tools {
+		tool := st.Tool
+		// Copy the property map: tool values share it with the package-level
+		// tool definitions, which must stay unchanged.
+		tool.InputSchema.Properties = maps.Clone(tool.InputSchema.Properties)
+		if tool.InputSchema.Properties == nil {
+			tool.InputSchema.Properties = map[string]any{}
+		}
+		tool.InputSchema.Properties[ActAsArgument] = map[string]any{
+			"type":        "string",
+			"description": actAsDescription,
+		}
+		updated = append(updated, server.ServerTool{Tool: tool, Handler: st.Handler})
+	}
+	s.AddTools(updated...)
+}
@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package operation
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/version"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// captureSudo is a tool handler that records what the middleware handed it.
+type captureSudo struct {
+	sudo string
+	args map[string]any
+	hits int
+}
+
+func (c *captureSudo) handler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	c.hits++
+	c.sudo, _ = ctx.Value(forgejo.SudoContextKey).(string)
+	c.args = req.GetArguments()
+	return mcp.NewToolResultText("ok"), nil
+}
+
+func callWithActAs(ctx context.Context, h server.ToolHandlerFunc, args map[string]any) error {
+	_, err := sudoMiddleware(h)(ctx, mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "get_my_user_info", Arguments: args}})
+	return err
+}
+
+func TestSudoMiddleware(t *testing.T) {
+	flag.SudoAllowlist = []string{"alice"}
+	t.Cleanup(func() { flag.SudoAllowlist = nil })
+
+	t.Run("argument is applied and stripped", func(t *testing.T) {
+		c := &captureSudo{}
+		if err := callWithActAs(context.Background(), c.handler, map[string]any{"act_as": "alice", "owner": "o"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.sudo != "alice" {
+			t.Fatalf("sudo = %q", c.sudo)
+		}
+		if _, present := c.args["act_as"]; present || c.args["owner"] != "o" {
+			t.Fatalf("args = %v", c.args)
+		}
+	})
+
+	t.Run("header is applied", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.Header.Set(ActAsHeader, "alice")
+		c := &captureSudo{}
+		if err := callWithActAs(requestContext(context.Background(), r), c.handler, map[string]any{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.sudo != "alice" {
+			t.Fatalf("sudo = %q", c.sudo)
+		}
+	})
+
+	t.Run("argument overrides header", func(t *testing.T) {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.Header.Set(ActAsHeader, "mallory")
+		c := &captureSudo{}
+		if err := callWithActAs(requestContext(context.Background(), r), c.handler, map[string]any{"act_as": "alice"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.sudo != "alice" {
+			t.Fatalf("sudo = %q", c.sudo)
+		}
+	})
+
+	t.Run("non-allowlisted target is refused", func(t *testing.T) {
+		c := &captureSudo{}
+		err := callWithActAs(context.Background(), c.handler, map[string]any{"act_as": "mallory"})
+		if err == nil || !strings.Contains(err.Error(), "allowlist") {
+			t.Fatalf("expected allowlist error, got %v", err)
+		}
+		if c.hits != 0 {
+			t.Fatal("handler ran for a refused impersonation")
+		}
+	})
+
+	t.Run("no target leaves the call alone", func(t *testing.T) {
+		c := &captureSudo{}
+		if err := callWithActAs(context.Background(), c.handler, map[string]any{"owner": "o"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c.sudo != "" || c.hits != 1 {
+			t.Fatalf("sudo = %q hits = %d", c.sudo, c.hits)
+		}
+	})
+}
+
+func TestAdvertiseActAs_LeavesToolDefinitionsUntouched(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	version.RegisterTool(s)
+	advertiseActAs(s)
+
+	st := s.GetTool(version.GetServerCapabilitiesToolName)
+	if _, ok := st.Tool.InputSchema.Properties[ActAsArgument]; !ok {
+		t.Fatal("act_as not advertised")
+	}
+	if _, ok := version.GetServerCapabilitiesTool.InputSchema.Properties[ActAsArgument]; ok {
+		t.Fatal("package-level tool definition was mutated")
+	}
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/actions"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/to"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	GetServerCapabilitiesToolName = "get_server_capabilities"
+
+	capabilitiesResourceURI = "forgejo://server/capabilities"
+)
+
+var GetServerCapabilitiesTool = mcp.NewTool(
+	GetServerCapabilitiesToolName,
+	mcp.WithDescription("Report the connected Forgejo server's version and flavor, which version-gated capabilities are enabled and why, and which tools each capability gates."),
+)
+
+// gatedTool names one tool and the capability it needs.
+type gatedTool struct {
+	Tool       string             `json:"tool"`
+	Capability forgejo.Capability `json:"capability"`
+	Enabled    bool               `json:"enabled"`
+}
+
+type capabilitiesReport struct {
+	Server       forgejo.ServerInfo         `json:"server"`
+	Detected     bool                       `json:"detected"`
+	Capabilities []forgejo.CapabilityStatus `json:"capabilities"`
+	GatedTools   []gatedTool                `json:"gated_tools"`
+}
+
+func buildCapabilitiesReport(ctx context.Context) capabilitiesReport {
+	info, statuses := forgejo.Capabilities(ctx)
+	enabled := make(map[forgejo.Capability]bool, len(statuses))
+	for _, status := range statuses {
+		enabled[status.Name] = status.Enabled
+	}
+	tools := make([]gatedTool, 0, len(actions.ToolCapabilities))
+	for name, capability := range actions.ToolCapabilities {
+		tools = append(tools, gatedTool{Tool: name, Capability: capability, Enabled: enabled[capability]})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Tool < tools[j].Tool })
+	return capabilitiesReport{
+		Server:       info,
+		Detected:     info.Flavor != forgejo.FlavorUnknown,
+		Capabilities: statuses,
+		GatedTools:   tools,
+	}
+}
+
+func GetServerCapabilitiesFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Debugf("Called GetServerCapabilitiesFn")
+	return to.TextResult(buildCapabilitiesReport(ctx))
+}
+
+// RegisterCapabilitiesResource registers the static forgejo://server/capabilities resource.
+func RegisterCapabilitiesResource(s *server.MCPServer) {
+	s.AddResource(
+		mcp.NewResource(
+			capabilitiesResourceURI,
+			"Forgejo Server Capabilities",
+			mcp.WithResourceDescription("Detected server version and flavor plus the enabled/disabled state of every version-gated capability."),
+			mcp.WithMIMEType("application/json"),
+		),
+		capabilitiesResourceHandler,
+	)
+	log.Debug("Registered server capabilities resource")
+}
+
+func capabilitiesResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	body, err := json.Marshal(buildCapabilitiesReport(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(body),
+		},
+	}, nil
+}
@@ -25,6 +25,7 @@ var (
 
 func RegisterTool(s *server.MCPServer) {
 	s.AddTool(GetForgejoMCPServerVersionTool, GetForgejoMCPServerVersionFn)
+	s.AddTool(GetServerCapabilitiesTool, GetServerCapabilitiesFn)
 }
 
 func GetForgejoMCPServerVersionFn(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
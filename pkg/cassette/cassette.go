@@ -0,0 +1,281 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package cassette records upstream Forgejo HTTP traffic to a directory and
+// replays it later without a network, so demos and tests can run against a
+// disconnected machine.
+//
+// A cassette is a directory of numbered JSON files, one per interaction, in
+// the order the requests completed. Credentials never reach disk: the
+// Authorization, Cookie and Set-Cookie headers, token-like query parameters,
+// and every occurrence of a caller-supplied secret are replaced with
+// Redacted before writing.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Redacted replaces every secret the recorder strips.
+const Redacted = "REDACTED"
+
+// redactedHeaders never carry anything a replay needs.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// redactedQuery are the query parameters Forgejo accepts credentials in.
+var redactedQuery = []string{"token", "access_token"}
+
+// Interaction is one recorded request/response pair as stored on disk.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the recorded side of an upstream call. URL holds only the path
+// and query, so a cassette replays against whatever base URL is configured.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is the recorded upstream answer. Bodies that are not valid UTF-8
+// (attachment downloads) are stored base64-encoded in BodyBase64 instead.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"body_base64,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that forwards to another RoundTripper and
+// writes every completed interaction into a cassette directory.
+type Recorder struct {
+	dir     string
+	next    http.RoundTripper
+	secrets []string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder creates dir if needed and returns a Recorder forwarding to
+// next (http.DefaultTransport when nil). secrets are additionally scrubbed
+// from every recorded header, URL and body.
+func NewRecorder(dir string, next http.RoundTripper, secrets ...string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cassette dir: %w", err)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	var kept []string
+	for _, secret := range secrets {
+		if secret != "" {
+			kept = append(kept, secret)
+		}
+	}
+	return &Recorder{dir: dir, next: next, secrets: kept}, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cassette: read request body: %w", err)
+		}
+		// RoundTrippers must not modify the caller's request.
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.scrub(redactURL(req.URL)),
+			Header: r.redactHeader(req.Header),
+			Body:   r.scrub(string(reqBody)),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+		},
+	}
+	if utf8.Valid(respBody) {
+		interaction.Response.Body = r.scrub(string(respBody))
+	} else {
+		interaction.Response.BodyBase64 = respBody
+	}
+	if err := r.write(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) write(interaction Interaction) error {
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: encode interaction: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	name := filepath.Join(r.dir, fmt.Sprintf("%05d.json", r.seq))
+	if err := os.WriteFile(name, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (r *Recorder) scrub(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		scrubbed := make([]string, len(values))
+		for i, v := range values {
+			scrubbed[i] = r.scrub(v)
+		}
+		out[name] = scrubbed
+	}
+	for _, name := range redactedHeaders {
+		if _, ok := out[http.CanonicalHeaderKey(name)]; ok {
+			out.Set(name, Redacted)
+		}
+	}
+	return out
+}
+
+// redactURL returns the path and query of u with credential parameters
+// replaced.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	for _, name := range redactedQuery {
+		if query.Has(name) {
+			query.Set(name, Redacted)
+		}
+	}
+	return requestKeyURL(u.EscapedPath(), query)
+}
+
+// requestKeyURL renders path plus a canonically ordered query, so recorded
+// and replayed requests compare equal regardless of parameter order.
+func requestKeyURL(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// Replayer is an http.RoundTripper that answers from a recorded cassette and
+// never touches the network.
+type Replayer struct {
+	mu      sync.Mutex
+	pending map[string][]Response
+	last    map[string]Response
+}
+
+// NewReplayer loads every interaction in dir.
+func NewReplayer(dir string) (*Replayer, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("list cassette: %w", err)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("cassette %s contains no interactions", dir)
+	}
+	sort.Strings(names)
+	r := &Replayer{pending: map[string][]Response{}, last: map[string]Response{}}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		var interaction Interaction
+		if err := json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", name, err)
+		}
+		key := interaction.Request.Method + " " + interaction.Request.URL
+		r.pending[key] = append(r.pending[key], interaction.Response)
+	}
+	return r, nil
+}
+
+// RoundTrip implements http.RoundTripper. Repeated identical requests are
+// answered in recorded order; once a request's recordings run out the last
+// one is served again, so an extra retry or cache miss stays deterministic.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	key := req.Method + " " + redactURL(req.URL)
+
+	r.mu.Lock()
+	queue := r.pending[key]
+	var recorded Response
+	switch {
+	case len(queue) > 0:
+		recorded = queue[0]
+		r.pending[key] = queue[1:]
+		r.last[key] = recorded
+	default:
+		last, ok := r.last[key]
+		if !ok {
+			r.mu.Unlock()
+			return nil, fmt.Errorf("cassette: no recorded interaction for %s", key)
+		}
+		recorded = last
+	}
+	r.mu.Unlock()
+
+	body := []byte(recorded.Body)
+	if recorded.BodyBase64 != nil {
+		body = recorded.BodyBase64
+	}
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
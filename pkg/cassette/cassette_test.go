@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secret = "s3cr3t-token"
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "token "+secret)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "i_like_gitea="+secret)
+		switch r.URL.Path {
+		case "/api/v1/user":
+			_, _ = w.Write([]byte(`{"login":"alice","echo":"` + r.Header.Get("Authorization") + `"}`))
+		case "/api/v1/blob":
+			_, _ = w.Write([]byte{0xff, 0xfe, 0x00, 0x01})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir, nil, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording := &http.Client{Transport: recorder}
+	_, userBody := get(t, recording, upstream.URL+"/api/v1/user?token="+secret+"&b=2&a=1")
+	get(t, recording, upstream.URL+"/api/v1/blob")
+	get(t, recording, upstream.URL+"/api/v1/missing")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Fatalf("expected 3 interactions, got %d", len(files))
+	}
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("%s leaks the token:\n%s", f, data)
+		}
+	}
+
+	replayer, err := NewReplayer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaying := &http.Client{Transport: replayer}
+	upstream.Close()
+	before := calls
+
+	// Different host and query order must still match.
+	status, body := get(t, replaying, "http://offline.invalid/api/v1/user?a=1&b=2&token=other")
+	if status != http.StatusOK || body != strings.ReplaceAll(userBody, secret, Redacted) {
+		t.Fatalf("replayed user = %d %s", status, body)
+	}
+	if _, blob := get(t, replaying, "http://offline.invalid/api/v1/blob"); blob != string([]byte{0xff, 0xfe, 0x00, 0x01}) {
+		t.Fatalf("binary body not restored: %q", blob)
+	}
+	if status, _ := get(t, replaying, "http://offline.invalid/api/v1/missing"); status != http.StatusNotFound {
+		t.Fatalf("replayed status = %d, want 404", status)
+	}
+	// Exhausted recordings repeat the last answer.
+	if status, _ := get(t, replaying, "http://offline.invalid/api/v1/missing"); status != http.StatusNotFound {
+		t.Fatalf("repeat replay status = %d", status)
+	}
+	if calls != before {
+		t.Fatal("replay reached the network")
+	}
+
+	if _, err := replaying.Get("http://offline.invalid/api/v1/unrecorded"); err == nil || !strings.Contains(err.Error(), "no recorded interaction for GET /api/v1/unrecorded") {
+		t.Fatalf("expected unrecorded error, got %v", err)
+	}
+}
+
+func TestNewReplayer_EmptyDir(t *testing.T) {
+	if _, err := NewReplayer(t.TempDir()); err == nil {
+		t.Fatal("expected an error for an empty cassette")
+	}
+}
@@ -8,5 +8,27 @@ var (
 	Version   string
 	UserAgent string
 
+	// SudoAllowlist lists the logins an admin token may impersonate via the
+	// Sudo header. Empty disables impersonation.
+	SudoAllowlist []string
+
+	// RecordDir and ReplayDir select a cassette directory to record upstream
+	// traffic into, or to serve it back from. At most one may be set.
+	RecordDir string
+	ReplayDir string
+
+	// DetectRepo seeds the default owner/repo from the git checkout in the
+	// working directory.
+	DetectRepo bool
+
+	// ProbeWriteScopes sends the write probes when detecting token scopes.
+	// Off by default: without them a write scope is assumed from its read
+	// scope.
+	ProbeWriteScopes bool
+
+	// Listeners are extra MCP endpoints (see operation.ParseListener)
+	// served alongside the --transport selection.
+	Listeners []string
+
 	Debug bool
 )
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+// Flavor identifies which forge implementation answered /api/v1/version.
+type Flavor string
+
+const (
+	FlavorForgejo Flavor = "forgejo"
+	FlavorGitea   Flavor = "gitea"
+	FlavorUnknown Flavor = "unknown"
+)
+
+// Capability names one server-side feature whose availability depends on the
+// server version or flavor.
+type Capability string
+
+const (
+	CapabilityActionRunJobs Capability = "action_run_jobs"
+	CapabilityActionJobLogs Capability = "action_job_logs"
+)
+
+// ErrUnsupported is wrapped by RequireCapability when the detected server is
+// known not to provide a capability.
+var ErrUnsupported = errors.New("unsupported on this server")
+
+// ServerInfo is the parsed result of the server's version string.
+type ServerInfo struct {
+	Version string `json:"version"`
+	Flavor  Flavor `json:"flavor"`
+	Major   int    `json:"major"`
+	Minor   int    `json:"minor"`
+	Patch   int    `json:"patch"`
+}
+
+// CapabilityStatus reports whether one capability is enabled and why.
+type CapabilityStatus struct {
+	Name    Capability `json:"name"`
+	Enabled bool       `json:"enabled"`
+	Reason  string     `json:"reason"`
+}
+
+// capabilityRequirement is one row of the capability table. Only Forgejo
+// requirements exist today; Gitea is treated as not providing them.
+type capabilityRequirement struct {
+	name            Capability
+	minForgejoMajor int
+}
+
+// capabilityTable lists every version-gated capability in reporting order.
+var capabilityTable = []capabilityRequirement{
+	{name: CapabilityActionRunJobs, minForgejoMajor: 16},
+	{name: CapabilityActionJobLogs, minForgejoMajor: 16},
+}
+
+// ParseServerVersion classifies a /api/v1/version string. Forgejo reports
+// "<forgejo>+gitea-<gitea>" (e.g. "11.0.0+gitea-1.22.0"); releases before
+// Forgejo 7 reused Gitea's numbering with a numeric "-N" suffix
+// ("1.21.11-1"). Anything else with a 1.x major is Gitea.
+func ParseServerVersion(raw string) ServerInfo {
+	info := ServerInfo{Version: raw, Flavor: FlavorUnknown}
+	trimmed := strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if trimmed == "" {
+		return info
+	}
+
+	core, build, _ := strings.Cut(trimmed, "+")
+	core, pre, _ := strings.Cut(core, "-")
+	parts := strings.Split(core, ".")
+	numbers := make([]int, 3)
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return info
+		}
+		numbers[i] = n
+	}
+	info.Major, info.Minor, info.Patch = numbers[0], numbers[1], numbers[2]
+
+	switch {
+	case strings.HasPrefix(build, "gitea-"), info.Major >= 7:
+		info.Flavor = FlavorForgejo
+	case pre != "" && isDigits(pre):
+		info.Flavor = FlavorForgejo
+	default:
+		info.Flavor = FlavorGitea
+	}
+	return info
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Supports reports whether the server provides capability c, with a
+// human-readable reason either way.
+func (i ServerInfo) Supports(c Capability) (bool, string) {
+	for _, req := range capabilityTable {
+		if req.name != c {
+			continue
+		}
+		switch i.Flavor {
+		case FlavorForgejo:
+			if i.Major >= req.minForgejoMajor {
+				return true, fmt.Sprintf("Forgejo %d >= %d", i.Major, req.minForgejoMajor)
+			}
+			return false, fmt.Sprintf("requires Forgejo v%d+, server is v%s", req.minForgejoMajor, i.Version)
+		case FlavorGitea:
+			return false, fmt.Sprintf("requires Forgejo v%d+, server is Gitea v%s", req.minForgejoMajor, i.Version)
+		default:
+			return true, "server version unknown; assuming supported"
+		}
+	}
+	return true, "not version-gated"
+}
+
+var (
+	serverInfoMu sync.Mutex
+	// serverInfoCache is keyed on flag.URL for the same reason as
+	// settingsCache: ephemeral per-token clients all talk to one instance.
+	serverInfoCache = map[string]ServerInfo{}
+)
+
+// recordServerVersion caches the version string VerifyConnection already
+// fetched, so startup does not pay for a second /version round trip.
+func recordServerVersion(raw string) ServerInfo {
+	info := ParseServerVersion(raw)
+	serverInfoMu.Lock()
+	serverInfoCache[flag.URL] = info
+	serverInfoMu.Unlock()
+	return info
+}
+
+// DetectServer returns the cached ServerInfo for the configured instance,
+// fetching /api/v1/version on first use. ok is false when the version could
+// not be determined; callers must then treat every capability as available.
+func DetectServer(ctx context.Context) (info ServerInfo, ok bool) {
+	serverInfoMu.Lock()
+	if cached, found := serverInfoCache[flag.URL]; found {
+		serverInfoMu.Unlock()
+		return cached, cached.Flavor != FlavorUnknown
+	}
+	serverInfoMu.Unlock()
+
+	client, err := Client(ctx)
+	if err != nil {
+		return ServerInfo{Flavor: FlavorUnknown}, false
+	}
+	version, _, err := client.ServerVersion()
+	if err != nil {
+		return ServerInfo{Flavor: FlavorUnknown}, false
+	}
+	info = recordServerVersion(version)
+	return info, info.Flavor != FlavorUnknown
+}
+
+// Capabilities returns the detected server and the status of every entry in
+// the capability table.
+func Capabilities(ctx context.Context) (ServerInfo, []CapabilityStatus) {
+	info, _ := DetectServer(ctx)
+	statuses := make([]CapabilityStatus, 0, len(capabilityTable))
+	for _, req := range capabilityTable {
+		enabled, reason := info.Supports(req.name)
+		statuses = append(statuses, CapabilityStatus{Name: req.name, Enabled: enabled, Reason: reason})
+	}
+	return info, statuses
+}
+
+// RequireCapability returns an error wrapping ErrUnsupported when the server
+// is known not to provide c. An undetectable version never blocks a call.
+func RequireCapability(ctx context.Context, c Capability) error {
+	info, ok := DetectServer(ctx)
+	if !ok {
+		return nil
+	}
+	if enabled, _ := info.Supports(c); !enabled {
+		return fmt.Errorf("%s %w (v%s)", c, ErrUnsupported, info.Version)
+	}
+	return nil
+}
+
+// resetServerInfoCacheForTesting clears detected server versions; called from
+// SetClientForTesting alongside the settings cache reset.
+func resetServerInfoCacheForTesting() {
+	serverInfoMu.Lock()
+	defer serverInfoMu.Unlock()
+	serverInfoCache = map[string]ServerInfo{}
+}
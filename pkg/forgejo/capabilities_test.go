@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	cases := []struct {
+		raw    string
+		flavor Flavor
+		major  int
+		minor  int
+	}{
+		{"11.0.0+gitea-1.22.0", FlavorForgejo, 11, 0},
+		{"16.0.0-dev-123-abcdef+gitea-1.22.0", FlavorForgejo, 16, 0},
+		{"1.21.11-1", FlavorForgejo, 1, 21},
+		{"1.22.0", FlavorGitea, 1, 22},
+		{"1.23.0+dev-45-gabcdef", FlavorGitea, 1, 23},
+		{"", FlavorUnknown, 0, 0},
+		{"garbage", FlavorUnknown, 0, 0},
+	}
+	for _, tc := range cases {
+		info := ParseServerVersion(tc.raw)
+		if info.Flavor != tc.flavor || info.Major != tc.major || info.Minor != tc.minor {
+			t.Errorf("ParseServerVersion(%q) = %+v, want flavor=%s major=%d minor=%d", tc.raw, info, tc.flavor, tc.major, tc.minor)
+		}
+	}
+}
+
+func TestServerInfoSupports(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want bool
+	}{
+		{"16.0.0+gitea-1.22.0", true},
+		{"17.1.0+gitea-1.22.0", true},
+		{"15.0.2+gitea-1.22.0", false},
+		{"1.24.0", false},
+		{"", true},
+	}
+	for _, tc := range cases {
+		got, reason := ParseServerVersion(tc.raw).Supports(CapabilityActionJobLogs)
+		if got != tc.want {
+			t.Errorf("%q supports action_job_logs = %v (%s), want %v", tc.raw, got, reason, tc.want)
+		}
+		if reason == "" {
+			t.Errorf("%q: empty reason", tc.raw)
+		}
+	}
+}
+
+func newVersionServer(t *testing.T, version string) *int32 {
+	t.Helper()
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/version" {
+			atomic.AddInt32(&hits, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"` + version + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "test-token"
+	ResetClientForTesting()
+	t.Cleanup(ResetClientForTesting)
+	return &hits
+}
+
+func TestRequireCapability_Unsupported(t *testing.T) {
+	newVersionServer(t, "11.0.0+gitea-1.22.0")
+
+	err := RequireCapability(context.Background(), CapabilityActionRunJobs)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	want := "action_run_jobs unsupported on this server (v11.0.0+gitea-1.22.0)"
+	if err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestRequireCapability_SupportedAndCached(t *testing.T) {
+	hits := newVersionServer(t, "16.0.0+gitea-1.22.0")
+
+	for i := 0; i < 3; i++ {
+		if err := RequireCapability(context.Background(), CapabilityActionJobLogs); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// One probe from client construction, one from DetectServer; later calls hit the cache.
+	if got := atomic.LoadInt32(hits); got > 2 {
+		t.Fatalf("expected cached detection, /version hit %d times", got)
+	}
+}
+
+func TestVerifyConnection_RecordsServerInfo(t *testing.T) {
+	hits := newVersionServer(t, "15.0.0+gitea-1.22.0")
+
+	if err := VerifyConnection(); err != nil {
+		t.Fatalf("VerifyConnection: %v", err)
+	}
+	before := atomic.LoadInt32(hits)
+	info, ok := DetectServer(context.Background())
+	if !ok || info.Flavor != FlavorForgejo || info.Major != 15 {
+		t.Fatalf("DetectServer = %+v, %v", info, ok)
+	}
+	if atomic.LoadInt32(hits) != before {
+		t.Fatalf("DetectServer re-probed /version after VerifyConnection")
+	}
+}
@@ -29,21 +29,30 @@ func WithToken(ctx context.Context, token string) context.Context {
 }
 
 // Client returns a Forgejo client configured to connect to a Forgejo instance.
-// If a token is found in the context, a new ephemeral client is returned.
-// Otherwise, the shared singleton client is used.
+// If a token or a sudo target is found in the context, a new ephemeral client
+// is returned. Otherwise, the shared singleton client is used.
 func Client(ctx context.Context) (*forgejo.Client, error) {
-	token, ok := ctx.Value(TokenContextKey).(string)
-	if ok && token != "" {
+	token, _ := ctx.Value(TokenContextKey).(string)
+	sudo := sudoFromContext(ctx)
+	if token != "" || sudo != "" {
+		if token == "" {
+			token = flag.Token
+		}
 		// Use configured user agent or default to forgejo-mcp/<version>
 		userAgent := flag.UserAgent
 		if userAgent == "" {
 			userAgent = "forgejo-mcp/" + flag.Version
 		}
 
-		c, err := forgejo.NewClient(flag.URL,
+		options := []forgejo.ClientOption{
+			sdkHTTPClient(),
 			forgejo.SetToken(token),
 			forgejo.SetUserAgent(userAgent),
-		)
+		}
+		if sudo != "" {
+			options = append(options, forgejo.SetSudo(sudo))
+		}
+		c, err := forgejo.NewClient(flag.URL, options...)
 		if err != nil {
 			log.ErrorCtx(ctx, "Failed to create ephemeral Forgejo client",
 				log.SanitizedURLField("url", flag.URL),
@@ -68,6 +77,7 @@ func Client(ctx context.Context) (*forgejo.Client, error) {
 	}
 
 	c, err := forgejo.NewClient(flag.URL,
+		sdkHTTPClient(),
 		forgejo.SetToken(flag.Token),
 		forgejo.SetUserAgent(userAgent),
 	)
@@ -99,6 +109,7 @@ func GetBaseURL() string {
 // that the client is properly connected.
 // Uses the /version endpoint (no auth required) so that tokens scoped
 // only to repo/issue — e.g. organisation tokens — are not rejected.
+// The version is cached for capability detection (see Capabilities).
 func VerifyConnection() error {
 	start := time.Now()
 
@@ -122,10 +133,12 @@ func VerifyConnection() error {
 		return fmt.Errorf("failed to connect to Forgejo instance at %s: %w", flag.URL, err)
 	}
 
+	info := recordServerVersion(version)
 	log.Info("Connection verification successful",
 		log.SanitizedURLField("url", flag.URL),
 		log.DurationField("duration", duration),
 		log.StringField("server_version", version),
+		log.StringField("server_flavor", string(info.Flavor)),
 		log.IntField("response_status", resp.StatusCode),
 	)
 
@@ -58,8 +58,9 @@ func (e *HTTPError) Error() string {
 
 func (e *HTTPError) Unwrap() error { return e.wrapped }
 
-// rawHTTPClient is package-level so tests can swap timeouts; a single
-// shared client lets keep-alives work across tool calls.
+// rawHTTPClient is package-level so tests can swap timeouts. Its Transport
+// is left unset: doRequest fills in the shared transport, whose connection
+// pool keeps keep-alives working across tool calls.
 var rawHTTPClient = &http.Client{Timeout: 60 * time.Second}
 
 // userAgent returns the configured UA, falling back to forgejo-mcp/<version>.
@@ -113,13 +114,18 @@ func setCommonHeaders(ctx context.Context, req *http.Request) {
 	req.Header.Set("Authorization", "token "+token)
 	req.Header.Set("User-Agent", userAgent())
 	req.Header.Set("Accept", "application/json")
+	if sudo := sudoFromContext(ctx); sudo != "" {
+		req.Header.Set("Sudo", sudo)
+	}
 }
 
 // doRequest sends req, returns the response, mapping common HTTP errors to
 // the sentinels above. Caller owns response body close.
 func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 	start := time.Now()
-	resp, err := rawHTTPClient.Do(req)
+	client := *rawHTTPClient
+	client.Transport = currentTransport()
+	resp, err := client.Do(req)
 	duration := time.Since(start)
 	endpoint := req.URL.Path
 	if req.URL.RawQuery != "" {
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/log"
+)
+
+// Token scope categories as named by Forgejo's access token scopes
+// ("read:issue", "write:repository", ...).
+const (
+	ScopeRepository   = "repository"
+	ScopeIssue        = "issue"
+	ScopeUser         = "user"
+	ScopeNotification = "notification"
+	ScopeOrganization = "organization"
+)
+
+// probeOwner can never be a valid Forgejo login (names may not start with a
+// dash), so write probes addressed to it fail with 404 after the scope check
+// passes and can never mutate anything.
+const probeOwner = "-forgejo-mcp-scope-probe-"
+
+// scopeProbe is one cheap request whose answer reveals whether the token
+// carries a scope. Forgejo checks token scopes before resolving the target,
+// so a 403 naming the missing scope means "denied" and anything else —
+// including the 404 every write probe expects — means "granted". Write
+// probes are still PATCH/POST/DELETE requests, so they only run when
+// flag.ProbeWriteScopes is set.
+type scopeProbe struct {
+	category string
+	write    bool
+	method   string
+	path     string
+}
+
+var scopeProbes = []scopeProbe{
+	{ScopeRepository, false, http.MethodGet, "/repos/search?limit=1"},
+	{ScopeRepository, true, http.MethodPatch, APIPath("repos", probeOwner, probeOwner)},
+	{ScopeIssue, false, http.MethodGet, "/repos/issues/search?limit=1"},
+	{ScopeIssue, true, http.MethodPost, APIPath("repos", probeOwner, probeOwner, "issues")},
+	{ScopeUser, false, http.MethodGet, "/user"},
+	{ScopeUser, true, http.MethodDelete, APIPath("user", "following", probeOwner)},
+	{ScopeNotification, false, http.MethodGet, "/notifications?limit=1"},
+	{ScopeNotification, true, http.MethodPatch, APIPath("notifications", "threads", 0)},
+	{ScopeOrganization, false, http.MethodGet, "/orgs?limit=1"},
+	{ScopeOrganization, true, http.MethodPatch, APIPath("orgs", probeOwner)},
+}
+
+// TokenScopes is the effective scope set of one token. When Known is false
+// the scopes could not be determined and every tool must stay visible.
+type TokenScopes struct {
+	Known bool
+	// WritesProbed is false when write scopes were not probed; a write
+	// is then allowed wherever the read is, and the server has the last say.
+	WritesProbed bool
+	granted      map[string]bool
+}
+
+// Allows reports whether the token may use category at the given access
+// level. A write scope implies the matching read scope.
+func (t TokenScopes) Allows(category string, write bool) bool {
+	if !t.Known {
+		return true
+	}
+	if t.granted["write:"+category] {
+		return true
+	}
+	return (!write || !t.WritesProbed) && t.granted["read:"+category]
+}
+
+// Names returns the granted scopes in sorted "level:category" form.
+func (t TokenScopes) Names() []string {
+	names := make([]string, 0, len(t.granted))
+	for name, ok := range t.granted {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fingerprint identifies the granted set; equal fingerprints mean the same
+// tools are visible.
+func (t TokenScopes) Fingerprint() string {
+	if !t.Known {
+		return "unknown"
+	}
+	return strings.Join(t.Names(), ",")
+}
+
+// unknownScopesTTL is how long an unknown probe result is reused. A probe
+// fails on timeouts, 5xx answers or a token mid-rotation; retrying later
+// restores filtering, while the TTL keeps a persistently failing token from
+// re-probing on every call.
+const unknownScopesTTL = time.Minute
+
+type scopesCacheEntry struct {
+	scopes  TokenScopes
+	expires time.Time // zero for known scopes, which never expire
+}
+
+var (
+	scopesCacheMu sync.Mutex
+	// scopesCache is keyed on flag.URL plus a hash of the token so the raw
+	// secret never sits in a map key.
+	scopesCache = map[string]scopesCacheEntry{}
+	// scopesNow is the cache clock; tests move it forward.
+	scopesNow = time.Now
+)
+
+func scopesCacheKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return flag.URL + "|" + hex.EncodeToString(sum[:])
+}
+
+// ScopesFor returns the effective scopes of the token carried by ctx (or the
+// global flag.Token), probing the server on first use and caching the result
+// per instance and token. An unknown result is retried after
+// unknownScopesTTL.
+func ScopesFor(ctx context.Context) TokenScopes {
+	token, ok := ctx.Value(TokenContextKey).(string)
+	if !ok || token == "" {
+		token = flag.Token
+	}
+	if token == "" {
+		return TokenScopes{}
+	}
+	key := scopesCacheKey(token)
+
+	scopesCacheMu.Lock()
+	if cached, found := scopesCache[key]; found && (cached.expires.IsZero() || scopesNow().Before(cached.expires)) {
+		scopesCacheMu.Unlock()
+		return cached.scopes
+	}
+	scopesCacheMu.Unlock()
+
+	scopes := probeScopes(WithToken(ctx, token))
+
+	entry := scopesCacheEntry{scopes: scopes}
+	if !scopes.Known {
+		entry.expires = scopesNow().Add(unknownScopesTTL)
+	}
+	scopesCacheMu.Lock()
+	scopesCache[key] = entry
+	scopesCacheMu.Unlock()
+
+	if scopes.Known {
+		log.Info("Token scopes detected",
+			log.SanitizedURLField("url", flag.URL),
+			log.StringField("scopes", strings.Join(scopes.Names(), ",")),
+		)
+	}
+	return scopes
+}
+
+// probeScopes runs every probe concurrently, skipping the write probes
+// unless flag.ProbeWriteScopes is set. Any probe that cannot be classified
+// (network error, 5xx, 401 for a bad token) makes the whole result unknown
+// rather than guessing.
+func probeScopes(ctx context.Context) TokenScopes {
+	type outcome struct {
+		name    string
+		granted bool
+		known   bool
+	}
+	writes := flag.ProbeWriteScopes
+	probes := make([]scopeProbe, 0, len(scopeProbes))
+	for _, probe := range scopeProbes {
+		if !probe.write || writes {
+			probes = append(probes, probe)
+		}
+	}
+	results := make([]outcome, len(probes))
+	var wg sync.WaitGroup
+	for i, probe := range probes {
+		wg.Add(1)
+		go func(i int, probe scopeProbe) {
+			defer wg.Done()
+			level := "read"
+			var body any
+			if probe.write {
+				level = "write"
+				body = map[string]any{}
+			}
+			granted, known := classifyProbe(DoJSON(ctx, probe.method, probe.path, body, nil))
+			results[i] = outcome{name: level + ":" + probe.category, granted: granted, known: known}
+		}(i, probe)
+	}
+	wg.Wait()
+
+	scopes := TokenScopes{Known: true, WritesProbed: writes, granted: map[string]bool{}}
+	for _, r := range results {
+		if !r.known {
+			return TokenScopes{}
+		}
+		scopes.granted[r.name] = r.granted
+	}
+	return scopes
+}
+
+func classifyProbe(err error) (granted, known bool) {
+	if err == nil {
+		return true, true
+	}
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		return false, false
+	}
+	switch {
+	case httpErr.StatusCode == http.StatusUnauthorized, httpErr.StatusCode >= 500:
+		// A rejected token or a failing server says nothing about scopes.
+		return false, false
+	case httpErr.StatusCode == http.StatusForbidden:
+		if strings.Contains(strings.ToLower(httpErr.Body), "scope") {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+// resetScopesCacheForTesting clears probed token scopes; called from
+// SetClientForTesting alongside the other per-instance caches.
+func resetScopesCacheForTesting() {
+	scopesCacheMu.Lock()
+	defer scopesCacheMu.Unlock()
+	scopesCache = map[string]scopesCacheEntry{}
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+// newScopeServer answers every probe as Forgejo would for a token holding
+// read:repository, write:issue and read:user only, with write probing on.
+func newScopeServer(t *testing.T) *int32 {
+	t.Helper()
+	var probes int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		if r.Method != http.MethodGet && !flag.ProbeWriteScopes {
+			t.Errorf("write probe %s %s sent without ProbeWriteScopes", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		deny := func(scope string) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"token does not have at least one of required scope(s): [` + scope + `]"}`))
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+		switch {
+		case path == "/repos/search":
+			_, _ = w.Write([]byte(`{"data":[],"ok":true}`))
+		case path == "/repos/issues/search":
+			_, _ = w.Write([]byte(`[]`))
+		case path == "/user" && r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"login":"u"}`))
+		case strings.HasPrefix(path, "/repos/") && strings.HasSuffix(path, "/issues"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(path, "/repos/"):
+			deny("write:repository")
+		case strings.HasPrefix(path, "/user/"):
+			deny("write:user")
+		case strings.HasPrefix(path, "/notifications"):
+			deny("read:notification")
+		case strings.HasPrefix(path, "/orgs"):
+			deny("read:organization")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "scoped-token"
+	flag.ProbeWriteScopes = true
+	t.Cleanup(func() { flag.ProbeWriteScopes = false })
+	resetScopesCacheForTesting()
+	t.Cleanup(resetScopesCacheForTesting)
+	return &probes
+}
+
+func TestScopesFor_ClassifiesProbes(t *testing.T) {
+	newScopeServer(t)
+
+	scopes := ScopesFor(context.Background())
+	if !scopes.Known {
+		t.Fatal("expected scopes to be known")
+	}
+	want := []string{"read:issue", "read:repository", "read:user", "write:issue"}
+	if got := scopes.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	checks := []struct {
+		category string
+		write    bool
+		want     bool
+	}{
+		{ScopeRepository, false, true},
+		{ScopeRepository, true, false},
+		{ScopeIssue, true, true},
+		{ScopeNotification, false, false},
+		{ScopeOrganization, false, false},
+	}
+	for _, c := range checks {
+		if got := scopes.Allows(c.category, c.write); got != c.want {
+			t.Errorf("Allows(%s, write=%v) = %v, want %v", c.category, c.write, got, c.want)
+		}
+	}
+}
+
+func TestScopesFor_WritesNotProbedByDefault(t *testing.T) {
+	newScopeServer(t)
+	flag.ProbeWriteScopes = false
+
+	scopes := ScopesFor(context.Background())
+	if !scopes.Known || scopes.WritesProbed {
+		t.Fatalf("scopes = %+v, want known without write probes", scopes)
+	}
+	want := []string{"read:issue", "read:repository", "read:user"}
+	if got := scopes.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	// A write is assumed wherever the read is granted.
+	if !scopes.Allows(ScopeRepository, true) || scopes.Allows(ScopeNotification, true) {
+		t.Fatalf("Allows: repository write %v, notification write %v",
+			scopes.Allows(ScopeRepository, true), scopes.Allows(ScopeNotification, true))
+	}
+}
+
+func TestScopesFor_CachedPerToken(t *testing.T) {
+	probes := newScopeServer(t)
+
+	ScopesFor(context.Background())
+	first := atomic.LoadInt32(probes)
+	ScopesFor(context.Background())
+	if atomic.LoadInt32(probes) != first {
+		t.Fatal("second lookup for the same token re-probed")
+	}
+	ScopesFor(WithToken(context.Background(), "other-token"))
+	if atomic.LoadInt32(probes) == first {
+		t.Fatal("a different token must be probed separately")
+	}
+}
+
+func TestScopesFor_UnknownOnBadToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "bad-token"
+	resetScopesCacheForTesting()
+	t.Cleanup(resetScopesCacheForTesting)
+
+	scopes := ScopesFor(context.Background())
+	if scopes.Known {
+		t.Fatalf("expected unknown scopes for a rejected token, got %v", scopes.Names())
+	}
+	if !scopes.Allows(ScopeOrganization, true) {
+		t.Fatal("unknown scopes must allow everything")
+	}
+}
+
+func TestScopesFor_RetriesUnknownAfterTTL(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "rotating-token"
+	resetScopesCacheForTesting()
+	t.Cleanup(resetScopesCacheForTesting)
+	now := time.Now()
+	scopesNow = func() time.Time { return now }
+	t.Cleanup(func() { scopesNow = time.Now })
+
+	if ScopesFor(context.Background()).Known {
+		t.Fatal("expected unknown scopes while the server fails")
+	}
+	failing.Store(false)
+	if ScopesFor(context.Background()).Known {
+		t.Fatal("an unknown result should be reused within the TTL")
+	}
+	now = now.Add(unknownScopesTTL + time.Second)
+	if !ScopesFor(context.Background()).Known {
+		t.Fatal("an unknown result must be re-probed after the TTL")
+	}
+}
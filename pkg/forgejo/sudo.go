@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+// SudoContextKey carries the login an admin token should impersonate.
+const SudoContextKey contextKey = "forgejo-sudo"
+
+// Errors returned by CheckSudoTarget; callers match them with errors.Is.
+var (
+	ErrSudoDisabled   = errors.New("impersonation is disabled on this server")
+	ErrSudoNotAllowed = errors.New("impersonation target is not in the allowlist")
+)
+
+// WithSudo marks ctx so every Forgejo request made with it — through the SDK
+// client and the raw-HTTP helpers alike — carries the Sudo header.
+func WithSudo(ctx context.Context, login string) context.Context {
+	return context.WithValue(ctx, SudoContextKey, login)
+}
+
+func sudoFromContext(ctx context.Context) string {
+	login, _ := ctx.Value(SudoContextKey).(string)
+	return login
+}
+
+// SudoEnabled reports whether the operator configured an impersonation
+// allowlist. Impersonation is off unless they did.
+func SudoEnabled() bool {
+	return len(flag.SudoAllowlist) > 0
+}
+
+// CheckSudoTarget validates that login may be impersonated. The allowlist is
+// matched case-insensitively, as Forgejo logins are; a "*" entry admits any
+// login.
+func CheckSudoTarget(login string) error {
+	if !SudoEnabled() {
+		return ErrSudoDisabled
+	}
+	for _, allowed := range flag.SudoAllowlist {
+		if allowed == "*" || strings.EqualFold(allowed, login) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSudoNotAllowed, login)
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+func TestCheckSudoTarget(t *testing.T) {
+	t.Cleanup(func() { flag.SudoAllowlist = nil })
+
+	flag.SudoAllowlist = nil
+	if err := CheckSudoTarget("alice"); !errors.Is(err, ErrSudoDisabled) {
+		t.Fatalf("disabled: got %v", err)
+	}
+
+	flag.SudoAllowlist = []string{"Alice", "bob"}
+	if err := CheckSudoTarget("alice"); err != nil {
+		t.Fatalf("allowlisted login (case-insensitive): %v", err)
+	}
+	if err := CheckSudoTarget("mallory"); !errors.Is(err, ErrSudoNotAllowed) {
+		t.Fatalf("non-allowlisted: got %v", err)
+	}
+
+	flag.SudoAllowlist = []string{"*"}
+	if err := CheckSudoTarget("anyone"); err != nil {
+		t.Fatalf("wildcard: %v", err)
+	}
+}
+
+// TestWithSudo_SetsHeaderOnBothPaths checks the Sudo header reaches Forgejo
+// from the SDK client and from the raw-HTTP helpers, and never leaks onto
+// requests made without it.
+func TestWithSudo_SetsHeaderOnBothPaths(t *testing.T) {
+	var mu sync.Mutex
+	seen := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Get("Sudo")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/version" {
+			_, _ = w.Write([]byte(`{"version":"11.0.0+gitea-1.22.0"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"login":"alice"}`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "admin-token"
+	ResetClientForTesting()
+	t.Cleanup(ResetClientForTesting)
+
+	ctx := WithSudo(context.Background(), "alice")
+	c, err := Client(ctx)
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if _, _, err := c.GetMyUserInfo(); err != nil {
+		t.Fatalf("GetMyUserInfo: %v", err)
+	}
+	if err := DoJSON(ctx, http.MethodGet, "/users/alice", nil, nil); err != nil {
+		t.Fatalf("DoJSON: %v", err)
+	}
+	if err := DoJSON(context.Background(), http.MethodGet, "/users/bob", nil, nil); err != nil {
+		t.Fatalf("DoJSON without sudo: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if seen["/api/v1/user"] != "alice" {
+		t.Errorf("SDK request Sudo = %q, want alice", seen["/api/v1/user"])
+	}
+	if seen["/api/v1/users/alice"] != "alice" {
+		t.Errorf("raw request Sudo = %q, want alice", seen["/api/v1/users/alice"])
+	}
+	if seen["/api/v1/users/bob"] != "" {
+		t.Errorf("request without sudo carried Sudo = %q", seen["/api/v1/users/bob"])
+	}
+}
@@ -6,13 +6,15 @@ import (
 
 // SetClientForTesting overrides the singleton client for testing purposes.
 // It also resets the cached instance pagination ceiling (MaxResponseItems),
-// so a ceiling cached against one test's httptest server never leaks into
-// the next test.
+// the detected server version and probed token scopes, so nothing cached
+// against one test's httptest server leaks into the next test.
 func SetClientForTesting(c *forgejo_sdk.Client) {
 	clientMu.Lock()
 	client = c
 	clientMu.Unlock()
 	resetSettingsCacheForTesting()
+	resetServerInfoCacheForTesting()
+	resetScopesCacheForTesting()
 }
 
 // ResetClientForTesting clears the singleton so the next Client call rebuilds
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"net/http"
+	"sync"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+var (
+	transportMu sync.RWMutex
+	// transport is shared by the SDK clients and the raw-HTTP helpers so a
+	// single RoundTripper (e.g. a cassette recorder) sees every upstream call.
+	transport http.RoundTripper = http.DefaultTransport
+)
+
+// SetTransport routes every upstream Forgejo request — SDK and raw HTTP —
+// through rt. Call it before the first client is built; the singleton client
+// keeps whichever transport it was created with.
+func SetTransport(rt http.RoundTripper) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transportMu.Lock()
+	transport = rt
+	transportMu.Unlock()
+}
+
+func currentTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return transport
+}
+
+// sdkHTTPClient returns the SDK option that makes a client use the shared
+// transport.
+func sdkHTTPClient() forgejo_sdk.ClientOption {
+	return forgejo_sdk.SetHTTPClient(&http.Client{Transport: currentTransport()})
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+)
+
+type countingTransport struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.mu.Lock()
+	c.paths = append(c.paths, req.URL.Path)
+	c.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// TestSetTransport_SharedBySDKAndRawHTTP guards the property cassettes rely
+// on: SDK calls and raw-HTTP helpers both go through the one transport.
+func TestSetTransport_SharedBySDKAndRawHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/v1/version" {
+			_, _ = w.Write([]byte(`{"version":"11.0.0+gitea-1.22.0"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "t"
+
+	rt := &countingTransport{}
+	SetTransport(rt)
+	t.Cleanup(func() { SetTransport(nil) })
+	ResetClientForTesting()
+	t.Cleanup(ResetClientForTesting)
+
+	c, err := Client(context.Background())
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	if _, _, err := c.GetMyUserInfo(); err != nil {
+		t.Fatalf("GetMyUserInfo: %v", err)
+	}
+	if err := DoJSON(context.Background(), http.MethodGet, "/settings/api", nil, nil); err != nil {
+		t.Fatalf("DoJSON: %v", err)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	seen := map[string]bool{}
+	for _, p := range rt.paths {
+		seen[p] = true
+	}
+	for _, want := range []string{"/api/v1/version", "/api/v1/user", "/api/v1/settings/api"} {
+		if !seen[want] {
+			t.Errorf("%s bypassed the shared transport (saw %v)", want, rt.paths)
+		}
+	}
+}
+
+// TestSetTransport_ConcurrentWithRawHTTP swaps the transport while raw-HTTP
+// requests are in flight; go test -race flags any unsynchronized access.
+func TestSetTransport_ConcurrentWithRawHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(srv.Close)
+	flag.URL = srv.URL
+	flag.Token = "t"
+	t.Cleanup(func() { SetTransport(nil) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetTransport(&countingTransport{})
+		}()
+		go func() {
+			defer wg.Done()
+			if err := DoJSON(context.Background(), http.MethodGet, "/settings/api", nil, nil); err != nil {
+				t.Errorf("DoJSON: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
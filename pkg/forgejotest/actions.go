@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// ActionJob is one job of an action run as /actions/runs/{id}/jobs reports
+// it. Log is served by /actions/jobs/{id}/logs and is not part of the JSON.
+type ActionJob struct {
+	ID      int64    `json:"id"`
+	RunID   int64    `json:"run_id"`
+	Attempt int64    `json:"attempt"`
+	Handle  string   `json:"handle"`
+	RepoID  int64    `json:"repo_id"`
+	OwnerID int64    `json:"owner_id"`
+	Name    string   `json:"name"`
+	Needs   []string `json:"needs"`
+	RunsOn  []string `json:"runs_on"`
+	TaskID  int64    `json:"task_id"`
+	Status  string   `json:"status"`
+	Log     string   `json:"-"`
+}
+
+type runState struct {
+	run  *forgejo_sdk.ActionRun
+	jobs []*ActionJob
+}
+
+// AddActionRun seeds an action run with the given jobs and returns a copy of
+// the run. Zero IDs, run numbers and timestamps are filled in; job RunID,
+// RepoID and OwnerID always are.
+func (s *Server) AddActionRun(owner, repo string, run forgejo_sdk.ActionRun, jobs ...ActionJob) *forgejo_sdk.ActionRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: AddActionRun on unknown repository %s/%s", owner, repo))
+	}
+	stored := run
+	if stored.ID == 0 {
+		stored.ID = s.newID()
+	}
+	if stored.RunNumber == 0 {
+		stored.RunNumber = int64(len(rs.runs) + 1)
+	}
+	if stored.Created.IsZero() {
+		stored.Created = s.now()
+	}
+	if stored.Updated.IsZero() {
+		stored.Updated = stored.Created
+	}
+	if stored.Status == "" {
+		stored.Status = "success"
+	}
+	if stored.TriggerUser == nil {
+		stored.TriggerUser = s.user
+	}
+	stored.Repository = rs.repo
+	stored.HTMLURL = fmt.Sprintf("%s/actions/runs/%d", rs.repo.HTMLURL, stored.RunNumber)
+
+	state := &runState{run: &stored}
+	for _, job := range jobs {
+		job := job
+		if job.ID == 0 {
+			job.ID = s.newID()
+		}
+		if job.Attempt == 0 {
+			job.Attempt = 1
+		}
+		if job.Status == "" {
+			job.Status = stored.Status
+		}
+		job.RunID = stored.ID
+		job.RepoID = rs.repo.ID
+		job.OwnerID = rs.repo.Owner.ID
+		state.jobs = append(state.jobs, &job)
+	}
+	rs.runs = append(rs.runs, state)
+	out := stored
+	return &out
+}
+
+func (s *Server) lookupRun(w http.ResponseWriter, p params) (*runState, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, false
+	}
+	id, ok := int64Param(w, p, "id")
+	if !ok {
+		return nil, false
+	}
+	for _, state := range rs.runs {
+		if state.run.ID == id {
+			return state, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "run not found")
+	return nil, false
+}
+
+func runMatches(run *forgejo_sdk.ActionRun, r *http.Request) bool {
+	query := r.URL.Query()
+	if event := query.Get("event"); event != "" && run.Event != event {
+		return false
+	}
+	if status := query.Get("status"); status != "" && run.Status != status {
+		return false
+	}
+	if sha := query.Get("head_sha"); sha != "" && run.CommitSHA != sha {
+		return false
+	}
+	if n, err := strconv.ParseInt(query.Get("run_number"), 10, 64); err == nil && n > 0 && run.RunNumber != n {
+		return false
+	}
+	return true
+}
+
+func (s *Server) registerActionRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/actions/runs", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		found := []*forgejo_sdk.ActionRun{}
+		for i := len(rs.runs) - 1; i >= 0; i-- {
+			if runMatches(rs.runs[i].run, r) {
+				found = append(found, rs.runs[i].run)
+			}
+		}
+		writeJSON(w, http.StatusOK, forgejo_sdk.ListActionRunsResponse{
+			TotalCount:   int64(len(found)),
+			WorkflowRuns: paginate(s, w, r, found),
+		})
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/actions/runs/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if state, ok := s.lookupRun(w, p); ok {
+			writeJSON(w, http.StatusOK, state.run)
+		}
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/actions/runs/{id}/jobs", func(w http.ResponseWriter, r *http.Request, p params) {
+		if state, ok := s.lookupRun(w, p); ok {
+			jobs := state.jobs
+			if jobs == nil {
+				jobs = []*ActionJob{}
+			}
+			writeJSON(w, http.StatusOK, jobs)
+		}
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/actions/jobs/{id}/logs", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		id, ok := int64Param(w, p, "id")
+		if !ok {
+			return
+		}
+		for _, state := range rs.runs {
+			for _, job := range state.jobs {
+				if job.ID != id {
+					continue
+				}
+				// ServeContent answers Range requests with 206 and
+				// Content-Range, as Forgejo's log endpoint does.
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				http.ServeContent(w, r, "", state.run.Updated, bytes.NewReader([]byte(job.Log)))
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "job not found")
+	})
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// AddIssueAttachment seeds an attachment on an issue and returns a copy of
+// it. Its browser_download_url serves data.
+func (s *Server) AddIssueAttachment(owner, repo string, index int64, name string, data []byte) *forgejo_sdk.Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok || rs.issue(index) == nil {
+		panic(fmt.Sprintf("forgejotest: AddIssueAttachment on unknown issue %s/%s#%d", owner, repo, index))
+	}
+	att := s.newAttachment(name, data)
+	rs.assets[index] = append(rs.assets[index], att)
+	copied := *att
+	return &copied
+}
+
+// AddCommentAttachment seeds an attachment on a comment and returns a copy
+// of it.
+func (s *Server) AddCommentAttachment(owner, repo string, commentID int64, name string, data []byte) *forgejo_sdk.Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if ok {
+		for _, c := range rs.comments {
+			if c.comment.ID == commentID {
+				att := s.newAttachment(name, data)
+				c.assets = append(c.assets, att)
+				copied := *att
+				return &copied
+			}
+		}
+	}
+	panic(fmt.Sprintf("forgejotest: AddCommentAttachment on unknown comment %s/%s %d", owner, repo, commentID))
+}
+
+func (s *Server) newAttachment(name string, data []byte) *forgejo_sdk.Attachment {
+	id := s.newID()
+	uuid := fmt.Sprintf("%08x-0000-4000-8000-%012x", id, id)
+	s.files[uuid] = append([]byte(nil), data...)
+	return &forgejo_sdk.Attachment{
+		ID:          id,
+		Name:        name,
+		Size:        int64(len(data)),
+		Created:     s.now(),
+		UUID:        uuid,
+		DownloadURL: s.URL + "/attachments/" + uuid,
+	}
+}
+
+// serveAttachment answers GET /attachments/{uuid}, which lives outside the
+// API prefix as in Forgejo.
+func (s *Server) serveAttachment(w http.ResponseWriter, uuid string) {
+	s.mu.Lock()
+	data, ok := s.files[uuid]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "attachment does not exist")
+		return
+	}
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	_, _ = w.Write(data)
+}
+
+// uploadAttachment reads the multipart "attachment" part, named by the
+// optional name query parameter or else the part's filename.
+func (s *Server) uploadAttachment(w http.ResponseWriter, r *http.Request) (*forgejo_sdk.Attachment, bool) {
+	file, header, err := r.FormFile("attachment")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "attachment: "+err.Error())
+		return nil, false
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "attachment: "+err.Error())
+		return nil, false
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = header.Filename
+	}
+	return s.newAttachment(name, data), true
+}
+
+func (s *Server) registerAttachmentRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/comments/{id}/assets", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, c, ok := s.lookupComment(w, p); ok {
+			writeJSON(w, http.StatusOK, append([]*forgejo_sdk.Attachment{}, c.assets...))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/comments/{id}/assets", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, c, ok := s.lookupComment(w, p)
+		if !ok {
+			return
+		}
+		if att, ok := s.uploadAttachment(w, r); ok {
+			c.assets = append(c.assets, att)
+			writeJSON(w, http.StatusCreated, att)
+		}
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/assets", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			writeJSON(w, http.StatusOK, append([]*forgejo_sdk.Attachment{}, rs.assets[issue.Index]...))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/assets", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		if att, ok := s.uploadAttachment(w, r); ok {
+			rs.assets[issue.Index] = append(rs.assets[issue.Index], att)
+			writeJSON(w, http.StatusCreated, att)
+		}
+	})
+}
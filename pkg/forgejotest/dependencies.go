@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"net/http"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// dependencyRef names the issue another issue depends on; it may live in a
+// different repository.
+type dependencyRef struct {
+	repo  string // repoKey
+	index int64
+}
+
+// issueMeta is Forgejo's IssueMeta body for the dependency endpoints.
+type issueMeta struct {
+	Index int64  `json:"index"`
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+}
+
+// dependencyTarget resolves the IssueMeta body or answers 404.
+func (s *Server) dependencyTarget(w http.ResponseWriter, r *http.Request) (dependencyRef, bool) {
+	var meta issueMeta
+	if !decodeBody(w, r, &meta) {
+		return dependencyRef{}, false
+	}
+	ref := dependencyRef{repo: repoKey(meta.Owner, meta.Repo), index: meta.Index}
+	if rs, ok := s.repos[ref.repo]; !ok || rs.issue(ref.index) == nil {
+		writeError(w, http.StatusNotFound, "issue does not exist")
+		return dependencyRef{}, false
+	}
+	return ref, true
+}
+
+func (s *Server) registerDependencyRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/dependencies", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		found := []*forgejo_sdk.Issue{}
+		for _, ref := range rs.dependencies[issue.Index] {
+			if dep := s.repos[ref.repo].issue(ref.index); dep != nil {
+				found = append(found, dep)
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/blocks", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		self := dependencyRef{repo: repoKey(p["owner"], p["repo"]), index: issue.Index}
+		found := []*forgejo_sdk.Issue{}
+		for _, rs := range s.ordered {
+			for _, dependent := range rs.issues {
+				for _, ref := range rs.dependencies[dependent.Index] {
+					if ref == self {
+						found = append(found, dependent)
+					}
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/dependencies", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		ref, ok := s.dependencyTarget(w, r)
+		if !ok {
+			return
+		}
+		if ref.repo == repoKey(p["owner"], p["repo"]) && ref.index == issue.Index {
+			writeError(w, http.StatusUnprocessableEntity, "an issue cannot depend on itself")
+			return
+		}
+		for _, existing := range rs.dependencies[issue.Index] {
+			if existing == ref {
+				writeError(w, http.StatusUnprocessableEntity, "dependency already exists")
+				return
+			}
+		}
+		rs.dependencies[issue.Index] = append(rs.dependencies[issue.Index], ref)
+		writeJSON(w, http.StatusCreated, issue)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/dependencies", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		ref, ok := s.dependencyTarget(w, r)
+		if !ok {
+			return
+		}
+		deps := rs.dependencies[issue.Index]
+		for i, existing := range deps {
+			if existing == ref {
+				rs.dependencies[issue.Index] = append(deps[:i:i], deps[i+1:]...)
+				writeJSON(w, http.StatusCreated, issue)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "dependency does not exist")
+	})
+}
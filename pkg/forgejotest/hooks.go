@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"net/http"
+	"slices"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+func (s *Server) lookupHook(w http.ResponseWriter, p params) (*repoState, *forgejo_sdk.Hook, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := int64Param(w, p, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	for _, hook := range rs.hooks {
+		if hook.ID == id {
+			return rs, hook, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "hook does not exist")
+	return nil, nil, false
+}
+
+func (s *Server) registerHookRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/hooks", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, ok := s.lookupRepo(w, p); ok {
+			writeJSON(w, http.StatusOK, paginate(s, w, r, rs.hooks))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/hooks", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreateHookOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Type == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Type]: Required")
+			return
+		}
+		if opt.Config["url"] == "" || opt.Config["content_type"] == "" {
+			writeError(w, http.StatusUnprocessableEntity, "Missing config option: url and content_type are required")
+			return
+		}
+		events := opt.Events
+		if len(events) == 0 {
+			events = []string{"push"}
+		}
+		created := s.now()
+		hook := &forgejo_sdk.Hook{
+			ID:      s.newID(),
+			Type:    string(opt.Type),
+			Config:  opt.Config,
+			Events:  events,
+			Active:  opt.Active,
+			Created: created,
+			Updated: created,
+		}
+		rs.hooks = append(rs.hooks, hook)
+		writeJSON(w, http.StatusCreated, hook)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/hooks/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, hook, ok := s.lookupHook(w, p); ok {
+			writeJSON(w, http.StatusOK, hook)
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/hooks/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, hook, ok := s.lookupHook(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditHookOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		for key, value := range opt.Config {
+			hook.Config[key] = value
+		}
+		if opt.Events != nil {
+			hook.Events = opt.Events
+		}
+		if opt.Active != nil {
+			hook.Active = *opt.Active
+		}
+		hook.Updated = s.now()
+		writeJSON(w, http.StatusOK, hook)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/hooks/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, hook, ok := s.lookupHook(w, p)
+		if !ok {
+			return
+		}
+		rs.hooks = slices.DeleteFunc(rs.hooks, func(other *forgejo_sdk.Hook) bool { return other == hook })
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,639 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+type commentState struct {
+	issueIndex int64
+	comment    *forgejo_sdk.Comment
+	reactions  []*forgejo_sdk.Reaction
+	assets     []*forgejo_sdk.Attachment
+}
+
+// CreateIssue seeds an open issue and returns a copy of it. The repository
+// must already exist.
+func (s *Server) CreateIssue(owner, repo, title, body string) *forgejo_sdk.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: CreateIssue on unknown repository %s/%s", owner, repo))
+	}
+	issue := *s.newIssue(rs, title, body)
+	return &issue
+}
+
+// CreateLabel seeds a repository label and returns a copy of it.
+func (s *Server) CreateLabel(owner, repo, name, color string) *forgejo_sdk.Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: CreateLabel on unknown repository %s/%s", owner, repo))
+	}
+	label := *s.newLabel(rs, forgejo_sdk.CreateLabelOption{Name: name, Color: color})
+	return &label
+}
+
+// AddIssueTemplate seeds an issue template, given as the JSON object
+// GET /repos/{owner}/{repo}/issue_templates returns for it (form fields go
+// in "body"). Templates are listed in the order they were added.
+func (s *Server) AddIssueTemplate(owner, repo, template string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: AddIssueTemplate on unknown repository %s/%s", owner, repo))
+	}
+	if !json.Valid([]byte(template)) {
+		panic(fmt.Sprintf("forgejotest: AddIssueTemplate: invalid JSON %q", template))
+	}
+	rs.templates = append(rs.templates, json.RawMessage(template))
+}
+
+func (s *Server) newIssue(rs *repoState, title, body string) *forgejo_sdk.Issue {
+	rs.nextIndex++
+	created := s.now()
+	issue := &forgejo_sdk.Issue{
+		ID:         s.newID(),
+		Index:      rs.nextIndex,
+		URL:        fmt.Sprintf("%s/api/v1/repos/%s/issues/%d", s.URL, rs.repo.FullName, rs.nextIndex),
+		HTMLURL:    fmt.Sprintf("%s/issues/%d", rs.repo.HTMLURL, rs.nextIndex),
+		Poster:     s.user,
+		Title:      title,
+		Body:       body,
+		Labels:     []*forgejo_sdk.Label{},
+		Assignees:  []*forgejo_sdk.User{},
+		State:      forgejo_sdk.StateOpen,
+		Created:    created,
+		Updated:    created,
+		Repository: rs.meta(),
+	}
+	rs.issues = append(rs.issues, issue)
+	return issue
+}
+
+func (s *Server) newLabel(rs *repoState, opt forgejo_sdk.CreateLabelOption) *forgejo_sdk.Label {
+	id := s.newID()
+	label := &forgejo_sdk.Label{
+		ID:          id,
+		Name:        opt.Name,
+		Color:       strings.TrimPrefix(opt.Color, "#"),
+		Description: opt.Description,
+		URL:         fmt.Sprintf("%s/api/v1/repos/%s/labels/%d", s.URL, rs.repo.FullName, id),
+	}
+	rs.labels = append(rs.labels, label)
+	return label
+}
+
+func (rs *repoState) issue(index int64) *forgejo_sdk.Issue {
+	for _, issue := range rs.issues {
+		if issue.Index == index {
+			return issue
+		}
+	}
+	return nil
+}
+
+func (rs *repoState) label(id int64) *forgejo_sdk.Label {
+	for _, label := range rs.labels {
+		if label.ID == id {
+			return label
+		}
+	}
+	return nil
+}
+
+func (rs *repoState) labelByName(name string) *forgejo_sdk.Label {
+	for _, label := range rs.labels {
+		if strings.EqualFold(label.Name, name) {
+			return label
+		}
+	}
+	return nil
+}
+
+// lookupIssue resolves {owner}/{repo}/{index} or answers 404. Pull requests
+// are issues too and resolve here.
+func (s *Server) lookupIssue(w http.ResponseWriter, p params) (*repoState, *forgejo_sdk.Issue, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	index, ok := int64Param(w, p, "index")
+	if !ok {
+		return nil, nil, false
+	}
+	issue := rs.issue(index)
+	if issue == nil {
+		writeError(w, http.StatusNotFound, "issue does not exist")
+		return nil, nil, false
+	}
+	return rs, issue, true
+}
+
+// resolveLabels accepts label IDs or names, the way Forgejo's issue label
+// endpoints do, and answers 422 for any it cannot find.
+func (s *Server) resolveLabels(w http.ResponseWriter, rs *repoState, raw []json.RawMessage) ([]*forgejo_sdk.Label, bool) {
+	labels := make([]*forgejo_sdk.Label, 0, len(raw))
+	for _, item := range raw {
+		var label *forgejo_sdk.Label
+		var id int64
+		var name string
+		switch {
+		case json.Unmarshal(item, &id) == nil:
+			label = rs.label(id)
+		case json.Unmarshal(item, &name) == nil:
+			if n, err := strconv.ParseInt(name, 10, 64); err == nil {
+				label = rs.label(n)
+			} else {
+				label = rs.labelByName(name)
+			}
+		}
+		if label == nil {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("label %s does not exist", item))
+			return nil, false
+		}
+		if !slices.Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+	return labels, true
+}
+
+// resolveAssignees maps logins to users. The fake knows only the
+// authenticated user and repository owners; anyone else gets a synthetic
+// account, since tests rarely care.
+func (s *Server) resolveAssignees(logins []string) []*forgejo_sdk.User {
+	users := make([]*forgejo_sdk.User, 0, len(logins))
+	for _, login := range logins {
+		if strings.EqualFold(login, s.user.UserName) {
+			users = append(users, s.user)
+			continue
+		}
+		users = append(users, &forgejo_sdk.User{UserName: login, HTMLURL: s.URL + "/" + login})
+	}
+	return users
+}
+
+func (s *Server) touch(issue *forgejo_sdk.Issue) {
+	issue.Updated = s.now()
+}
+
+func (s *Server) setIssueState(issue *forgejo_sdk.Issue, state forgejo_sdk.StateType) {
+	if issue.State == state {
+		return
+	}
+	issue.State = state
+	if state == forgejo_sdk.StateClosed {
+		closed := s.now()
+		issue.Closed = &closed
+	} else {
+		issue.Closed = nil
+	}
+}
+
+// issueMatches applies the list filters Forgejo's issue list honours.
+func issueMatches(issue *forgejo_sdk.Issue, r *http.Request) bool {
+	query := r.URL.Query()
+	switch state := query.Get("state"); state {
+	case "", string(forgejo_sdk.StateOpen):
+		if issue.State != forgejo_sdk.StateOpen {
+			return false
+		}
+	case string(forgejo_sdk.StateClosed):
+		if issue.State != forgejo_sdk.StateClosed {
+			return false
+		}
+	}
+	switch query.Get("type") {
+	case "issues":
+		if issue.PullRequest != nil {
+			return false
+		}
+	case "pulls":
+		if issue.PullRequest == nil {
+			return false
+		}
+	}
+	if labels := query.Get("labels"); labels != "" {
+		for _, want := range strings.Split(labels, ",") {
+			if !slices.ContainsFunc(issue.Labels, func(l *forgejo_sdk.Label) bool {
+				return strings.EqualFold(l.Name, strings.TrimSpace(want))
+			}) {
+				return false
+			}
+		}
+	}
+	if milestones := query.Get("milestones"); milestones != "" {
+		if issue.Milestone == nil || !slices.ContainsFunc(strings.Split(milestones, ","), func(want string) bool {
+			want = strings.TrimSpace(want)
+			return strings.EqualFold(issue.Milestone.Title, want) || strconv.FormatInt(issue.Milestone.ID, 10) == want
+		}) {
+			return false
+		}
+	}
+	if since, err := time.Parse(time.RFC3339, query.Get("since")); err == nil && issue.Updated.Before(since) {
+		return false
+	}
+	if before, err := time.Parse(time.RFC3339, query.Get("before")); err == nil && !issue.Updated.Before(before) {
+		return false
+	}
+	if login := query.Get("created_by"); login != "" && (issue.Poster == nil || !strings.EqualFold(issue.Poster.UserName, login)) {
+		return false
+	}
+	if login := query.Get("assigned_by"); login != "" && !slices.ContainsFunc(issue.Assignees, func(u *forgejo_sdk.User) bool {
+		return strings.EqualFold(u.UserName, login)
+	}) {
+		return false
+	}
+	if keyword := strings.ToLower(query.Get("q")); keyword != "" {
+		if !strings.Contains(strings.ToLower(issue.Title), keyword) &&
+			!strings.Contains(strings.ToLower(issue.Body), keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) registerIssueRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		// Forgejo lists newest first.
+		found := []*forgejo_sdk.Issue{}
+		for i := len(rs.issues) - 1; i >= 0; i-- {
+			if issueMatches(rs.issues[i], r) {
+				found = append(found, rs.issues[i])
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt struct {
+			forgejo_sdk.CreateIssueOption
+			Labels []json.RawMessage `json:"labels"`
+		}
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if strings.TrimSpace(opt.Title) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Title]: Required")
+			return
+		}
+		labels, ok := s.resolveLabels(w, rs, opt.Labels)
+		if !ok {
+			return
+		}
+		var milestone *forgejo_sdk.Milestone
+		if opt.Milestone != 0 {
+			if milestone = rs.milestone(opt.Milestone); milestone == nil {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("milestone %d does not exist", opt.Milestone))
+				return
+			}
+		}
+		issue := s.newIssue(rs, opt.Title, opt.Body)
+		issue.Milestone = milestone
+		issue.Ref = opt.Ref
+		issue.Labels = labels
+		issue.Assignees = s.resolveAssignees(opt.Assignees)
+		issue.Deadline = opt.Deadline
+		if opt.Closed {
+			s.setIssueState(issue, forgejo_sdk.StateClosed)
+		}
+		writeJSON(w, http.StatusCreated, issue)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issue_templates", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		templates := rs.templates
+		if templates == nil {
+			templates = []json.RawMessage{}
+		}
+		writeJSON(w, http.StatusOK, templates)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, c, ok := s.lookupComment(w, p); ok {
+			writeJSON(w, http.StatusOK, c.comment)
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, c, ok := s.lookupComment(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditIssueCommentOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		c.comment.Body = opt.Body
+		c.comment.Updated = s.now()
+		if issue := rs.issue(c.issueIndex); issue != nil {
+			s.touch(issue)
+		}
+		writeJSON(w, http.StatusOK, c.comment)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/comments/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, c, ok := s.lookupComment(w, p)
+		if !ok {
+			return
+		}
+		rs.comments = slices.DeleteFunc(rs.comments, func(other *commentState) bool { return other == c })
+		if issue := rs.issue(c.issueIndex); issue != nil {
+			issue.Comments--
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			writeJSON(w, http.StatusOK, rs.pinnedView(issue))
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/issues/{index}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditIssueOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Title != "" {
+			issue.Title = opt.Title
+		}
+		if opt.Body != nil {
+			issue.Body = *opt.Body
+		}
+		if opt.Ref != nil {
+			issue.Ref = *opt.Ref
+		}
+		if opt.Milestone != nil {
+			if *opt.Milestone == 0 {
+				issue.Milestone = nil
+			} else if issue.Milestone = rs.milestone(*opt.Milestone); issue.Milestone == nil {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("milestone %d does not exist", *opt.Milestone))
+				return
+			}
+		}
+		if opt.Assignees != nil {
+			issue.Assignees = s.resolveAssignees(opt.Assignees)
+		}
+		if opt.Deadline != nil {
+			issue.Deadline = opt.Deadline
+		}
+		if opt.RemoveDeadline != nil && *opt.RemoveDeadline {
+			issue.Deadline = nil
+		}
+		if opt.State != nil {
+			s.setIssueState(issue, *opt.State)
+		}
+		s.touch(issue)
+		writeJSON(w, http.StatusCreated, issue)
+	})
+	s.handle(http.MethodPut, "repos/{owner}/{repo}/issues/{index}/lock", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		var opt struct {
+			Reason string `json:"lock_reason"`
+		}
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		issue.IsLocked = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/lock", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, issue, ok := s.lookupIssue(w, p); ok {
+			issue.IsLocked = false
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/comments", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		found := []*forgejo_sdk.Comment{}
+		for _, c := range rs.comments {
+			if c.issueIndex == issue.Index {
+				found = append(found, c.comment)
+			}
+		}
+		writeJSON(w, http.StatusOK, found)
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/comments", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreateIssueCommentOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if strings.TrimSpace(opt.Body) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Body]: Required")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.addComment(rs, issue, opt.Body))
+	})
+
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/labels", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, issue, ok := s.lookupIssue(w, p); ok {
+			writeJSON(w, http.StatusOK, issue.Labels)
+		}
+	})
+	addLabels := func(replace bool) handlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, p params) {
+			rs, issue, ok := s.lookupIssue(w, p)
+			if !ok {
+				return
+			}
+			var opt struct {
+				Labels []json.RawMessage `json:"labels"`
+			}
+			if !decodeBody(w, r, &opt) {
+				return
+			}
+			labels, ok := s.resolveLabels(w, rs, opt.Labels)
+			if !ok {
+				return
+			}
+			if replace {
+				issue.Labels = labels
+			} else {
+				for _, label := range labels {
+					if !slices.Contains(issue.Labels, label) {
+						issue.Labels = append(issue.Labels, label)
+					}
+				}
+			}
+			s.touch(issue)
+			writeJSON(w, http.StatusOK, issue.Labels)
+		}
+	}
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/labels", addLabels(false))
+	s.handle(http.MethodPut, "repos/{owner}/{repo}/issues/{index}/labels", addLabels(true))
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/labels", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, issue, ok := s.lookupIssue(w, p); ok {
+			issue.Labels = []*forgejo_sdk.Label{}
+			s.touch(issue)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/labels/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		id, ok := int64Param(w, p, "id")
+		if !ok {
+			return
+		}
+		before := len(issue.Labels)
+		issue.Labels = slices.DeleteFunc(issue.Labels, func(l *forgejo_sdk.Label) bool { return l.ID == id })
+		if len(issue.Labels) == before {
+			writeError(w, http.StatusNotFound, "label does not exist")
+			return
+		}
+		s.touch(issue)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, ok := s.lookupRepo(w, p); ok {
+			writeJSON(w, http.StatusOK, paginate(s, w, r, rs.labels))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/labels", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreateLabelOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Name == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Name]: Required")
+			return
+		}
+		if rs.labelByName(opt.Name) != nil {
+			writeError(w, http.StatusConflict, "label already exists")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.newLabel(rs, opt))
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/labels/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, label, ok := s.lookupLabel(w, p); ok {
+			writeJSON(w, http.StatusOK, label)
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/labels/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, label, ok := s.lookupLabel(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditLabelOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Name != nil {
+			label.Name = *opt.Name
+		}
+		if opt.Color != nil {
+			label.Color = strings.TrimPrefix(*opt.Color, "#")
+		}
+		if opt.Description != nil {
+			label.Description = *opt.Description
+		}
+		writeJSON(w, http.StatusOK, label)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/labels/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, label, ok := s.lookupLabel(w, p)
+		if !ok {
+			return
+		}
+		rs.labels = slices.DeleteFunc(rs.labels, func(l *forgejo_sdk.Label) bool { return l == label })
+		for _, issue := range rs.issues {
+			issue.Labels = slices.DeleteFunc(issue.Labels, func(l *forgejo_sdk.Label) bool { return l == label })
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func (s *Server) addComment(rs *repoState, issue *forgejo_sdk.Issue, body string) *forgejo_sdk.Comment {
+	id := s.newID()
+	created := s.now()
+	comment := &forgejo_sdk.Comment{
+		ID:       id,
+		HTMLURL:  fmt.Sprintf("%s#issuecomment-%d", issue.HTMLURL, id),
+		IssueURL: issue.HTMLURL,
+		Poster:   s.user,
+		Body:     body,
+		Created:  created,
+		Updated:  created,
+	}
+	if issue.PullRequest != nil {
+		comment.PRURL = issue.HTMLURL
+	}
+	rs.comments = append(rs.comments, &commentState{issueIndex: issue.Index, comment: comment})
+	issue.Comments++
+	issue.Updated = created
+	return comment
+}
+
+func (s *Server) lookupComment(w http.ResponseWriter, p params) (*repoState, *commentState, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := int64Param(w, p, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	for _, c := range rs.comments {
+		if c.comment.ID == id {
+			return rs, c, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "comment does not exist")
+	return nil, nil, false
+}
+
+func (s *Server) lookupLabel(w http.ResponseWriter, p params) (*repoState, *forgejo_sdk.Label, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := int64Param(w, p, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	label := rs.label(id)
+	if label == nil {
+		writeError(w, http.StatusNotFound, "label does not exist")
+		return nil, nil, false
+	}
+	return rs, label, true
+}
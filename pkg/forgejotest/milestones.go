@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// CreateMilestone seeds an open milestone and returns a copy of it.
+func (s *Server) CreateMilestone(owner, repo, title string) *forgejo_sdk.Milestone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: CreateMilestone on unknown repository %s/%s", owner, repo))
+	}
+	created := s.now()
+	milestone := &forgejo_sdk.Milestone{
+		ID:      s.newID(),
+		Title:   title,
+		State:   forgejo_sdk.StateOpen,
+		Created: created,
+	}
+	rs.milestones = append(rs.milestones, milestone)
+	m := *milestone
+	return &m
+}
+
+func (rs *repoState) milestone(id int64) *forgejo_sdk.Milestone {
+	for _, m := range rs.milestones {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// refreshMilestone recomputes the issue counters Forgejo keeps on the
+// milestone row.
+func (rs *repoState) refreshMilestone(m *forgejo_sdk.Milestone) *forgejo_sdk.Milestone {
+	m.OpenIssues, m.ClosedIssues = 0, 0
+	for _, issue := range rs.issues {
+		if issue.Milestone == nil || issue.Milestone.ID != m.ID {
+			continue
+		}
+		if issue.State == forgejo_sdk.StateOpen {
+			m.OpenIssues++
+		} else {
+			m.ClosedIssues++
+		}
+	}
+	return m
+}
+
+func (s *Server) setMilestoneState(m *forgejo_sdk.Milestone, state forgejo_sdk.StateType) {
+	if m.State == state {
+		return
+	}
+	m.State = state
+	if state == forgejo_sdk.StateClosed {
+		closed := s.now()
+		m.Closed = &closed
+	} else {
+		m.Closed = nil
+	}
+}
+
+// lookupMilestone resolves {owner}/{repo}/{id} or answers 404. Like
+// Forgejo, {id} may also be the milestone's title.
+func (s *Server) lookupMilestone(w http.ResponseWriter, p params) (*repoState, *forgejo_sdk.Milestone, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	if id, err := strconv.ParseInt(p["id"], 10, 64); err == nil {
+		if m := rs.milestone(id); m != nil {
+			return rs, m, true
+		}
+	}
+	for _, m := range rs.milestones {
+		if strings.EqualFold(m.Title, p["id"]) {
+			return rs, m, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "milestone does not exist")
+	return nil, nil, false
+}
+
+func (s *Server) registerMilestoneRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		state := r.URL.Query().Get("state")
+		found := []*forgejo_sdk.Milestone{}
+		for _, m := range rs.milestones {
+			if state == "all" || string(m.State) == state || (state == "" && m.State == forgejo_sdk.StateOpen) {
+				found = append(found, rs.refreshMilestone(m))
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/milestones", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreateMilestoneOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if strings.TrimSpace(opt.Title) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "title is required")
+			return
+		}
+		milestone := &forgejo_sdk.Milestone{
+			ID:          s.newID(),
+			Title:       opt.Title,
+			Description: opt.Description,
+			State:       forgejo_sdk.StateOpen,
+			Created:     s.now(),
+			Deadline:    opt.Deadline,
+		}
+		if opt.State == forgejo_sdk.StateClosed {
+			s.setMilestoneState(milestone, forgejo_sdk.StateClosed)
+		}
+		rs.milestones = append(rs.milestones, milestone)
+		writeJSON(w, http.StatusCreated, milestone)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/milestones/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, m, ok := s.lookupMilestone(w, p); ok {
+			writeJSON(w, http.StatusOK, rs.refreshMilestone(m))
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/milestones/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, m, ok := s.lookupMilestone(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditMilestoneOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Title != "" {
+			m.Title = opt.Title
+		}
+		if opt.Description != nil {
+			m.Description = *opt.Description
+		}
+		if opt.Deadline != nil {
+			m.Deadline = opt.Deadline
+		}
+		if opt.State != nil {
+			s.setMilestoneState(m, *opt.State)
+		}
+		updated := s.now()
+		m.Updated = &updated
+		writeJSON(w, http.StatusOK, rs.refreshMilestone(m))
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/milestones/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, m, ok := s.lookupMilestone(w, p)
+		if !ok {
+			return
+		}
+		for _, issue := range rs.issues {
+			if issue.Milestone != nil && issue.Milestone.ID == m.ID {
+				issue.Milestone = nil
+			}
+		}
+		rs.milestones = slices.DeleteFunc(rs.milestones, func(other *forgejo_sdk.Milestone) bool { return other == m })
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
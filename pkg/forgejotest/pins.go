@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"net/http"
+	"slices"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// maxPinned mirrors Forgejo's default limit on pinned issues per repository.
+const maxPinned = 3
+
+// pinnedIssue adds the pin_order field Forgejo returns but the SDK's Issue
+// lacks.
+type pinnedIssue struct {
+	*forgejo_sdk.Issue
+	PinOrder int `json:"pin_order"`
+}
+
+func (rs *repoState) pinnedView(issue *forgejo_sdk.Issue) pinnedIssue {
+	return pinnedIssue{Issue: issue, PinOrder: slices.Index(rs.pinned, issue.Index) + 1}
+}
+
+func (s *Server) registerPinRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/pinned", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		found := []pinnedIssue{}
+		for _, index := range rs.pinned {
+			if issue := rs.issue(index); issue != nil && issue.PullRequest == nil {
+				found = append(found, rs.pinnedView(issue))
+			}
+		}
+		writeJSON(w, http.StatusOK, found)
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/pin", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		if !slices.Contains(rs.pinned, issue.Index) {
+			if len(rs.pinned) >= maxPinned {
+				writeError(w, http.StatusBadRequest, "You can't pin more issues")
+				return
+			}
+			rs.pinned = append(rs.pinned, issue.Index)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/pin", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		rs.pinned = slices.DeleteFunc(rs.pinned, func(index int64) bool { return index == issue.Index })
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/issues/{index}/pin/{position}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		position, ok := int64Param(w, p, "position")
+		if !ok {
+			return
+		}
+		at := slices.Index(rs.pinned, issue.Index)
+		if at < 0 {
+			writeError(w, http.StatusUnprocessableEntity, "issue is not pinned")
+			return
+		}
+		rs.pinned = slices.Delete(rs.pinned, at, at+1)
+		dest := min(max(int(position)-1, 0), len(rs.pinned))
+		rs.pinned = slices.Insert(rs.pinned, dest, issue.Index)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
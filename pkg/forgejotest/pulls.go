@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// pullState holds what a pull request adds on top of its issue row.
+type pullState struct {
+	issue   *forgejo_sdk.Issue
+	head    string
+	base    string
+	reviews []*forgejo_sdk.PullReview
+}
+
+// fakeSHA derives a stable commit ID from a branch name so heads and bases
+// look like real commits without a git backend.
+func fakeSHA(seed string) string {
+	sum := sha1.Sum([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) renderPull(rs *repoState, ps *pullState) *forgejo_sdk.PullRequest {
+	issue := ps.issue
+	var assignee *forgejo_sdk.User
+	if len(issue.Assignees) > 0 {
+		assignee = issue.Assignees[0]
+	}
+	created, updated := issue.Created, issue.Updated
+	pr := &forgejo_sdk.PullRequest{
+		ID:        issue.ID,
+		URL:       issue.URL,
+		Index:     issue.Index,
+		Poster:    issue.Poster,
+		Title:     issue.Title,
+		Body:      issue.Body,
+		Labels:    issue.Labels,
+		Assignee:  assignee,
+		Assignees: issue.Assignees,
+		State:     issue.State,
+		IsLocked:  issue.IsLocked,
+		Comments:  issue.Comments,
+		HTMLURL:   issue.HTMLURL,
+		DiffURL:   issue.HTMLURL + ".diff",
+		PatchURL:  issue.HTMLURL + ".patch",
+		Mergeable: issue.State == forgejo_sdk.StateOpen,
+		HasMerged: issue.PullRequest.HasMerged,
+		Merged:    issue.PullRequest.Merged,
+		Base:      &forgejo_sdk.PRBranchInfo{Name: ps.base, Ref: ps.base, Sha: fakeSHA(ps.base), RepoID: rs.repo.ID, Repository: rs.repo},
+		Head:      &forgejo_sdk.PRBranchInfo{Name: ps.head, Ref: ps.head, Sha: fakeSHA(ps.head), RepoID: rs.repo.ID, Repository: rs.repo},
+		MergeBase: fakeSHA(ps.base),
+		Deadline:  issue.Deadline,
+		Created:   &created,
+		Updated:   &updated,
+		Closed:    issue.Closed,
+	}
+	return pr
+}
+
+func (s *Server) lookupPull(w http.ResponseWriter, p params) (*repoState, *pullState, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	index, ok := int64Param(w, p, "index")
+	if !ok {
+		return nil, nil, false
+	}
+	ps, found := rs.pulls[index]
+	if !found {
+		writeError(w, http.StatusNotFound, "pull request does not exist")
+		return nil, nil, false
+	}
+	return rs, ps, true
+}
+
+func (s *Server) registerPullRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/pulls", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		state := r.URL.Query().Get("state")
+		found := []*forgejo_sdk.PullRequest{}
+		for i := len(rs.issues) - 1; i >= 0; i-- {
+			issue := rs.issues[i]
+			if issue.PullRequest == nil {
+				continue
+			}
+			if state != "" && state != "all" && string(issue.State) != state {
+				continue
+			}
+			found = append(found, s.renderPull(rs, rs.pulls[issue.Index]))
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/pulls", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt struct {
+			forgejo_sdk.CreatePullRequestOption
+			Labels []json.RawMessage `json:"labels"`
+		}
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Head == "" || opt.Base == "" || strings.TrimSpace(opt.Title) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "head, base and title are required")
+			return
+		}
+		if opt.Head == opt.Base {
+			writeError(w, http.StatusUnprocessableEntity, "Invalid PullRequest: There are no changes between the head and the base")
+			return
+		}
+		for _, existing := range rs.pulls {
+			if existing.head == opt.Head && existing.base == opt.Base && existing.issue.State == forgejo_sdk.StateOpen {
+				writeError(w, http.StatusConflict, fmt.Sprintf("pull request already exists for these targets [id: %d]", existing.issue.ID))
+				return
+			}
+		}
+		labels, ok := s.resolveLabels(w, rs, opt.Labels)
+		if !ok {
+			return
+		}
+		issue := s.newIssue(rs, opt.Title, opt.Body)
+		issue.HTMLURL = fmt.Sprintf("%s/pulls/%d", rs.repo.HTMLURL, issue.Index)
+		issue.PullRequest = &forgejo_sdk.PullRequestMeta{}
+		issue.Labels = labels
+		assignees := opt.Assignees
+		if opt.Assignee != "" {
+			assignees = append([]string{opt.Assignee}, assignees...)
+		}
+		issue.Assignees = s.resolveAssignees(assignees)
+		issue.Deadline = opt.Deadline
+		ps := &pullState{issue: issue, head: opt.Head, base: opt.Base}
+		rs.pulls[issue.Index] = ps
+		writeJSON(w, http.StatusCreated, s.renderPull(rs, ps))
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/pulls/{index}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, ps, ok := s.lookupPull(w, p); ok {
+			writeJSON(w, http.StatusOK, s.renderPull(rs, ps))
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/pulls/{index}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ps, ok := s.lookupPull(w, p)
+		if !ok {
+			return
+		}
+		var opt struct {
+			forgejo_sdk.EditPullRequestOption
+			Labels []json.RawMessage `json:"labels"`
+		}
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		issue := ps.issue
+		if opt.Title != "" {
+			issue.Title = opt.Title
+		}
+		if opt.Body != nil {
+			issue.Body = *opt.Body
+		}
+		if opt.Base != "" {
+			ps.base = opt.Base
+		}
+		if opt.Assignees != nil {
+			issue.Assignees = s.resolveAssignees(opt.Assignees)
+		}
+		if opt.Labels != nil {
+			labels, ok := s.resolveLabels(w, rs, opt.Labels)
+			if !ok {
+				return
+			}
+			issue.Labels = labels
+		}
+		if opt.Deadline != nil {
+			issue.Deadline = opt.Deadline
+		}
+		if opt.RemoveDeadline != nil && *opt.RemoveDeadline {
+			issue.Deadline = nil
+		}
+		if opt.State != nil {
+			if issue.PullRequest.HasMerged {
+				writeError(w, http.StatusPreconditionFailed, "cannot change state of a merged pull request")
+				return
+			}
+			s.setIssueState(issue, *opt.State)
+		}
+		s.touch(issue)
+		writeJSON(w, http.StatusCreated, s.renderPull(rs, ps))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/pulls/{index}/merge", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, ps, ok := s.lookupPull(w, p)
+		if !ok {
+			return
+		}
+		if ps.issue.PullRequest.HasMerged {
+			writeError(w, http.StatusMethodNotAllowed, "pull request has already been merged")
+			return
+		}
+		if ps.issue.State != forgejo_sdk.StateOpen {
+			writeError(w, http.StatusMethodNotAllowed, "pull request is closed")
+			return
+		}
+		merged := s.now()
+		ps.issue.PullRequest.HasMerged = true
+		ps.issue.PullRequest.Merged = &merged
+		s.setIssueState(ps.issue, forgejo_sdk.StateClosed)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/pulls/{index}/reviews", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, ps, ok := s.lookupPull(w, p); ok {
+			writeJSON(w, http.StatusOK, paginate(s, w, r, ps.reviews))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/pulls/{index}/reviews", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, ps, ok := s.lookupPull(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreatePullReviewOptions
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		state := opt.State
+		switch state {
+		case "":
+			state = forgejo_sdk.ReviewStatePending
+		case forgejo_sdk.ReviewStateApproved, forgejo_sdk.ReviewStateRequestChanges,
+			forgejo_sdk.ReviewStateComment, forgejo_sdk.ReviewStatePending:
+		default:
+			writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("unknown review event %q", state))
+			return
+		}
+		if state == forgejo_sdk.ReviewStateRequestChanges && strings.TrimSpace(opt.Body) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "review event REQUEST_CHANGES requires a body")
+			return
+		}
+		commitID := opt.CommitID
+		if commitID == "" {
+			commitID = fakeSHA(ps.head)
+		}
+		id := s.newID()
+		review := &forgejo_sdk.PullReview{
+			ID:                id,
+			Reviewer:          s.user,
+			State:             state,
+			Body:              opt.Body,
+			CommitID:          commitID,
+			CodeCommentsCount: len(opt.Comments),
+			Submitted:         s.now(),
+			HTMLURL:           fmt.Sprintf("%s#issuecomment-%d", ps.issue.HTMLURL, id),
+			HTMLPullURL:       ps.issue.HTMLURL,
+		}
+		ps.reviews = append(ps.reviews, review)
+		s.touch(ps.issue)
+		writeJSON(w, http.StatusOK, review)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/pulls/{index}/reviews/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, ps, ok := s.lookupPull(w, p)
+		if !ok {
+			return
+		}
+		id, ok := int64Param(w, p, "id")
+		if !ok {
+			return
+		}
+		for _, review := range ps.reviews {
+			if review.ID == id {
+				writeJSON(w, http.StatusOK, review)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "review does not exist")
+	})
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"net/http"
+	"slices"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// allowedReactions mirrors Forgejo's default ui.REACTIONS setting.
+var allowedReactions = []string{"+1", "-1", "laugh", "hooray", "confused", "heart", "rocket", "eyes"}
+
+type reactionOption struct {
+	Content string `json:"content"`
+}
+
+// react adds the authenticated user's reaction to list and returns the new
+// list. Like Forgejo, a repeated reaction answers 200 with the existing one
+// instead of 201.
+func (s *Server) react(w http.ResponseWriter, r *http.Request, list []*forgejo_sdk.Reaction) []*forgejo_sdk.Reaction {
+	var opt reactionOption
+	if !decodeBody(w, r, &opt) {
+		return list
+	}
+	if !slices.Contains(allowedReactions, opt.Content) {
+		writeError(w, http.StatusForbidden, "'"+opt.Content+"' is not an allowed reaction")
+		return list
+	}
+	for _, existing := range list {
+		if existing.Reaction == opt.Content && existing.User.ID == s.user.ID {
+			writeJSON(w, http.StatusOK, existing)
+			return list
+		}
+	}
+	reaction := &forgejo_sdk.Reaction{User: s.user, Reaction: opt.Content, Created: s.now()}
+	writeJSON(w, http.StatusCreated, reaction)
+	return append(list, reaction)
+}
+
+// unreact removes the authenticated user's reaction from list; removing one
+// that was never given succeeds, as in Forgejo.
+func (s *Server) unreact(w http.ResponseWriter, r *http.Request, list []*forgejo_sdk.Reaction) []*forgejo_sdk.Reaction {
+	var opt reactionOption
+	if !decodeBody(w, r, &opt) {
+		return list
+	}
+	w.WriteHeader(http.StatusOK)
+	return slices.DeleteFunc(list, func(existing *forgejo_sdk.Reaction) bool {
+		return existing.Reaction == opt.Content && existing.User.ID == s.user.ID
+	})
+}
+
+func (s *Server) registerReactionRoutes() {
+	// Comment routes first, or "comments" would match {index} below.
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/comments/{id}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, c, ok := s.lookupComment(w, p); ok {
+			writeJSON(w, http.StatusOK, append([]*forgejo_sdk.Reaction{}, c.reactions...))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/comments/{id}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, c, ok := s.lookupComment(w, p); ok {
+			c.reactions = s.react(w, r, c.reactions)
+		}
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/comments/{id}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, c, ok := s.lookupComment(w, p); ok {
+			c.reactions = s.unreact(w, r, c.reactions)
+		}
+	})
+
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			writeJSON(w, http.StatusOK, paginate(s, w, r, append([]*forgejo_sdk.Reaction{}, rs.reactions[issue.Index]...)))
+		}
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/issues/{index}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			rs.reactions[issue.Index] = s.react(w, r, rs.reactions[issue.Index])
+		}
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/reactions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			rs.reactions[issue.Index] = s.unreact(w, r, rs.reactions[issue.Index])
+		}
+	})
+}
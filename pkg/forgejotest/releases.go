@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+func (s *Server) lookupRelease(w http.ResponseWriter, p params) (*repoState, *forgejo_sdk.Release, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	id, ok := int64Param(w, p, "id")
+	if !ok {
+		return nil, nil, false
+	}
+	for _, release := range rs.releases {
+		if release.ID == id {
+			return rs, release, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "release does not exist")
+	return nil, nil, false
+}
+
+func (rs *repoState) releaseByTag(tag string) *forgejo_sdk.Release {
+	for _, release := range rs.releases {
+		if release.TagName == tag {
+			return release
+		}
+	}
+	return nil
+}
+
+func (s *Server) registerReleaseRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		found := []*forgejo_sdk.Release{}
+		for i := len(rs.releases) - 1; i >= 0; i-- {
+			found = append(found, rs.releases[i])
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/releases", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.CreateReleaseOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.TagName == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[TagName]: Required")
+			return
+		}
+		if rs.releaseByTag(opt.TagName) != nil {
+			writeError(w, http.StatusConflict, "Release is has no Tag")
+			return
+		}
+		target := opt.Target
+		if target == "" {
+			target = rs.repo.DefaultBranch
+		}
+		created := s.now()
+		id := s.newID()
+		release := &forgejo_sdk.Release{
+			ID:           id,
+			TagName:      opt.TagName,
+			Target:       target,
+			Title:        opt.Title,
+			Note:         opt.Note,
+			URL:          fmt.Sprintf("%s/api/v1/repos/%s/releases/%d", s.URL, rs.repo.FullName, id),
+			HTMLURL:      fmt.Sprintf("%s/releases/tag/%s", rs.repo.HTMLURL, opt.TagName),
+			TarURL:       fmt.Sprintf("%s/archive/%s.tar.gz", rs.repo.HTMLURL, opt.TagName),
+			ZipURL:       fmt.Sprintf("%s/archive/%s.zip", rs.repo.HTMLURL, opt.TagName),
+			IsDraft:      opt.IsDraft,
+			IsPrerelease: opt.IsPrerelease,
+			CreatedAt:    created,
+			PublishedAt:  created,
+			Publisher:    s.user,
+			Attachments:  []*forgejo_sdk.Attachment{},
+		}
+		rs.releases = append(rs.releases, release)
+		writeJSON(w, http.StatusCreated, release)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/releases/latest", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		for i := len(rs.releases) - 1; i >= 0; i-- {
+			if release := rs.releases[i]; !release.IsDraft && !release.IsPrerelease {
+				writeJSON(w, http.StatusOK, release)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "release does not exist")
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		if release := rs.releaseByTag(p["tag"]); release != nil {
+			writeJSON(w, http.StatusOK, release)
+			return
+		}
+		writeError(w, http.StatusNotFound, "release does not exist")
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/releases/tags/{tag}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		release := rs.releaseByTag(p["tag"])
+		if release == nil {
+			writeError(w, http.StatusNotFound, "release does not exist")
+			return
+		}
+		rs.releases = slices.DeleteFunc(rs.releases, func(other *forgejo_sdk.Release) bool { return other == release })
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/releases/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if _, release, ok := s.lookupRelease(w, p); ok {
+			writeJSON(w, http.StatusOK, release)
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/releases/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, release, ok := s.lookupRelease(w, p)
+		if !ok {
+			return
+		}
+		var opt forgejo_sdk.EditReleaseOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.TagName != "" {
+			release.TagName = opt.TagName
+		}
+		if opt.Target != "" {
+			release.Target = opt.Target
+		}
+		if opt.Title != "" {
+			release.Title = opt.Title
+		}
+		if opt.Note != "" {
+			release.Note = opt.Note
+		}
+		if opt.IsDraft != nil {
+			release.IsDraft = *opt.IsDraft
+		}
+		if opt.IsPrerelease != nil {
+			release.IsPrerelease = *opt.IsPrerelease
+		}
+		writeJSON(w, http.StatusOK, release)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/releases/{id}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, release, ok := s.lookupRelease(w, p)
+		if !ok {
+			return
+		}
+		rs.releases = slices.DeleteFunc(rs.releases, func(other *forgejo_sdk.Release) bool { return other == release })
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// repoState is everything the fake stores for one repository. Issues and
+// pull requests share the index counter, as they do in Forgejo.
+type repoState struct {
+	repo         *forgejo_sdk.Repository
+	nextIndex    int64
+	issues       []*forgejo_sdk.Issue
+	pulls        map[int64]*pullState
+	comments     []*commentState
+	labels       []*forgejo_sdk.Label
+	milestones   []*forgejo_sdk.Milestone
+	releases     []*forgejo_sdk.Release
+	wiki         []*wikiState
+	hooks        []*forgejo_sdk.Hook
+	runs         []*runState
+	templates    []json.RawMessage
+	reactions    map[int64][]*forgejo_sdk.Reaction   // by issue index
+	pinned       []int64                             // issue indexes in pin order
+	watchers     map[int64][]*forgejo_sdk.User       // issue subscribers by index
+	events       map[int64][]timelineEvent           // seeded timeline events by issue index
+	assets       map[int64][]*forgejo_sdk.Attachment // issue attachments by index
+	dependencies map[int64][]dependencyRef           // what each issue depends on, by index
+}
+
+func repoKey(owner, name string) string {
+	return strings.ToLower(owner) + "/" + strings.ToLower(name)
+}
+
+// CreateRepo seeds a repository owned by owner and returns a copy of it.
+// Creating an existing repository returns the stored one unchanged.
+func (s *Server) CreateRepo(owner, name string) *forgejo_sdk.Repository {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs := s.createRepo(owner, forgejo_sdk.CreateRepoOption{Name: name})
+	repo := *rs.repo
+	return &repo
+}
+
+func (s *Server) createRepo(owner string, opt forgejo_sdk.CreateRepoOption) *repoState {
+	if rs, ok := s.repos[repoKey(owner, opt.Name)]; ok {
+		return rs
+	}
+	branch := opt.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+	ownerUser := s.user
+	if !strings.EqualFold(owner, s.user.UserName) {
+		ownerUser = &forgejo_sdk.User{ID: s.newID(), UserName: owner, HTMLURL: s.URL + "/" + owner}
+	}
+	created := s.now()
+	rs := &repoState{
+		repo: &forgejo_sdk.Repository{
+			ID:              s.newID(),
+			Owner:           ownerUser,
+			Name:            opt.Name,
+			FullName:        owner + "/" + opt.Name,
+			Description:     opt.Description,
+			Private:         opt.Private,
+			Template:        opt.Template,
+			Empty:           !opt.AutoInit,
+			HTMLURL:         s.URL + "/" + owner + "/" + opt.Name,
+			CloneURL:        s.URL + "/" + owner + "/" + opt.Name + ".git",
+			DefaultBranch:   branch,
+			Created:         created,
+			Updated:         created,
+			HasIssues:       true,
+			HasWiki:         true,
+			HasPullRequests: true,
+			HasReleases:     true,
+			HasActions:      true,
+			Permissions:     &forgejo_sdk.Permission{Admin: true, Push: true, Pull: true},
+		},
+		pulls:        map[int64]*pullState{},
+		reactions:    map[int64][]*forgejo_sdk.Reaction{},
+		watchers:     map[int64][]*forgejo_sdk.User{},
+		events:       map[int64][]timelineEvent{},
+		assets:       map[int64][]*forgejo_sdk.Attachment{},
+		dependencies: map[int64][]dependencyRef{},
+	}
+	s.repos[repoKey(owner, opt.Name)] = rs
+	s.ordered = append(s.ordered, rs)
+	return rs
+}
+
+// lookupRepo resolves {owner}/{repo} or answers 404.
+func (s *Server) lookupRepo(w http.ResponseWriter, p params) (*repoState, bool) {
+	rs, ok := s.repos[repoKey(p["owner"], p["repo"])]
+	if !ok {
+		writeError(w, http.StatusNotFound, "repository does not exist")
+		return nil, false
+	}
+	return rs, true
+}
+
+// refreshCounters recomputes the denormalised counts Forgejo keeps on the
+// repository row.
+func (rs *repoState) refreshCounters() {
+	openIssues, openPulls := 0, 0
+	for _, issue := range rs.issues {
+		if issue.State != forgejo_sdk.StateOpen {
+			continue
+		}
+		if issue.PullRequest != nil {
+			openPulls++
+		} else {
+			openIssues++
+		}
+	}
+	rs.repo.OpenIssues = openIssues
+	rs.repo.OpenPulls = openPulls
+	rs.repo.Releases = len(rs.releases)
+}
+
+func (rs *repoState) meta() *forgejo_sdk.RepositoryMeta {
+	return &forgejo_sdk.RepositoryMeta{
+		ID:       rs.repo.ID,
+		Name:     rs.repo.Name,
+		Owner:    rs.repo.Owner.UserName,
+		FullName: rs.repo.FullName,
+	}
+}
+
+func (s *Server) registerRepoRoutes() {
+	s.handle(http.MethodGet, "user/repos", func(w http.ResponseWriter, r *http.Request, p params) {
+		var mine []*forgejo_sdk.Repository
+		for _, rs := range s.ordered {
+			if strings.EqualFold(rs.repo.Owner.UserName, s.user.UserName) {
+				mine = append(mine, rs.repo)
+			}
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, mine))
+	})
+	s.handle(http.MethodPost, "user/repos", func(w http.ResponseWriter, r *http.Request, p params) {
+		var opt forgejo_sdk.CreateRepoOption
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if opt.Name == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Name]: Required")
+			return
+		}
+		if _, exists := s.repos[repoKey(s.user.UserName, opt.Name)]; exists {
+			writeError(w, http.StatusConflict, "The repository with the same name already exists.")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.createRepo(s.user.UserName, opt).repo)
+	})
+	s.handle(http.MethodGet, "repos/search", func(w http.ResponseWriter, r *http.Request, p params) {
+		keyword := strings.ToLower(r.URL.Query().Get("q"))
+		found := []*forgejo_sdk.Repository{}
+		for _, rs := range s.ordered {
+			if keyword == "" || strings.Contains(strings.ToLower(rs.repo.FullName), keyword) {
+				found = append(found, rs.repo)
+			}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "data": paginate(s, w, r, found)})
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		rs.refreshCounters()
+		writeJSON(w, http.StatusOK, rs.repo)
+	})
+}
@@ -0,0 +1,384 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package forgejotest runs an in-memory, stateful fake of the Forgejo REST
+// API for end-to-end tool tests that need no real instance.
+//
+// A Server keeps repositories, issues, comments, attachments, reactions,
+// pins, subscriptions, timelines, dependencies, labels, milestones, pull
+// requests, reviews, releases, wiki pages, webhooks and action runs in memory
+// and answers the same paths the forgejo-mcp tools call, with Forgejo's
+// pagination headers (X-Total-Count and Link) and its JSON error shape.
+// Writes are visible to later reads, so a test can create an issue with one
+// tool and read it back with another:
+//
+//	srv := forgejotest.NewServer(t)
+//	srv.Use(t) // point flag.URL/flag.Token and the forgejo client at it
+//	srv.CreateRepo("alice", "demo")
+//	// ... call tool handlers ...
+//
+// Coverage is deliberately the subset the tools in this module use; an
+// unimplemented route answers 404 like an unknown Forgejo endpoint would.
+package forgejotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+const (
+	// DefaultVersion is what /api/v1/version reports unless Version is changed.
+	DefaultVersion = "11.0.0+gitea-1.22.0"
+	// DefaultToken is the token Use configures and the server accepts.
+	DefaultToken = "forgejotest-token"
+
+	defaultPageSize  = 30
+	defaultMaxItems  = 50
+	defaultUserLogin = "forgejotest"
+)
+
+// Server is a running fake Forgejo instance. All exported fields may be
+// changed between requests; they are read under the server lock.
+type Server struct {
+	// URL is the base URL, without /api/v1.
+	URL string
+	// Token is the only token accepted; empty accepts any request, even an
+	// anonymous one.
+	Token string
+	// Version is reported by /api/v1/version.
+	Version string
+	// MaxResponseItems is the page-size ceiling reported by /settings/api and
+	// enforced on every list endpoint.
+	MaxResponseItems int
+
+	httpServer *httptest.Server
+	routes     []route
+
+	mu      sync.Mutex
+	clock   time.Time
+	nextID  int64
+	user    *forgejo_sdk.User
+	repos   map[string]*repoState
+	ordered []*repoState
+	files   map[string][]byte // attachment contents by UUID
+}
+
+// NewServer starts a fake Forgejo server that is closed when t finishes.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		Token:            DefaultToken,
+		Version:          DefaultVersion,
+		MaxResponseItems: defaultMaxItems,
+		clock:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		repos:            map[string]*repoState{},
+		files:            map[string][]byte{},
+	}
+	s.registerRoutes()
+	s.httpServer = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.httpServer.URL
+	s.user = &forgejo_sdk.User{
+		ID:       s.newID(),
+		UserName: defaultUserLogin,
+		FullName: "Forgejo Test",
+		Email:    defaultUserLogin + "@example.com",
+		HTMLURL:  s.URL + "/" + defaultUserLogin,
+		IsActive: true,
+	}
+	t.Cleanup(s.httpServer.Close)
+	return s
+}
+
+// Use points the forgejo-mcp globals (flag.URL, flag.Token) and the shared
+// forgejo client at this server. When t finishes it restores the globals'
+// previous values and a fresh client.
+func (s *Server) Use(t testing.TB) {
+	t.Helper()
+	url, token, userAgent := flag.URL, flag.Token, flag.UserAgent
+	flag.URL = s.URL
+	flag.Token = s.Token
+	if flag.UserAgent == "" {
+		flag.UserAgent = "forgejo-mcp-test"
+	}
+	forgejo.ResetClientForTesting()
+	t.Cleanup(func() {
+		flag.URL, flag.Token, flag.UserAgent = url, token, userAgent
+		forgejo.ResetClientForTesting()
+	})
+}
+
+// User returns the authenticated user every request acts as.
+func (s *Server) User() *forgejo_sdk.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := *s.user
+	return &u
+}
+
+// newID hands out instance-wide unique IDs, as Forgejo's tables do.
+// Callers hold s.mu (or run before the server is shared).
+func (s *Server) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// now returns a deterministic, strictly increasing timestamp so ordering by
+// created/updated time is stable across runs.
+func (s *Server) now() time.Time {
+	s.clock = s.clock.Add(time.Minute)
+	return s.clock
+}
+
+// --- routing ----------------------------------------------------------------
+
+type params map[string]string
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request, p params)
+
+// route matches a method and a /-separated pattern whose {name} segments
+// capture one path segment and whose trailing {name...} captures the rest.
+// Routes are tried in registration order, so a literal route registered
+// before a wildcard one wins.
+type route struct {
+	method  string
+	parts   []string
+	handler handlerFunc
+}
+
+func (s *Server) handle(method, pattern string, h handlerFunc) {
+	s.routes = append(s.routes, route{
+		method:  method,
+		parts:   strings.Split(strings.Trim(pattern, "/"), "/"),
+		handler: h,
+	})
+}
+
+func (rt route) match(segments []string) (params, bool) {
+	p := params{}
+	for i, part := range rt.parts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "...}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			p[strings.TrimSuffix(part[1:], "...}")] = strings.Join(segments[i:], "/")
+			return p, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			p[part[1:len(part)-1]] = segments[i]
+			continue
+		}
+		if part != segments[i] {
+			return nil, false
+		}
+	}
+	return p, len(rt.parts) == len(segments)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/api/v1/"
+	escaped := r.URL.EscapedPath()
+	if uuid, ok := strings.CutPrefix(escaped, "/attachments/"); ok {
+		s.serveAttachment(w, uuid)
+		return
+	}
+	if !strings.HasPrefix(escaped, prefix) {
+		writeError(w, http.StatusNotFound, "The target couldn't be found.")
+		return
+	}
+	// Split the escaped path so an encoded "/" (%2F) inside a wiki page name
+	// stays part of its segment.
+	raw := strings.Split(strings.Trim(strings.TrimPrefix(escaped, prefix), "/"), "/")
+	segments := make([]string, len(raw))
+	for i, seg := range raw {
+		decoded, err := url.PathUnescape(seg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid path segment")
+			return
+		}
+		segments[i] = decoded
+	}
+
+	if len(segments) != 1 || segments[0] != "version" {
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "token is required")
+			return
+		}
+	}
+
+	methodMatched := false
+	for _, rt := range s.routes {
+		p, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			methodMatched = true
+			continue
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		rt.handler(w, r, p)
+		return
+	}
+	if methodMatched {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "The target couldn't be found.")
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	want := s.Token
+	s.mu.Unlock()
+	if want == "" {
+		return true
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok {
+		return false
+	}
+	scheme = strings.ToLower(scheme)
+	return (scheme == "token" || scheme == "bearer") && token == want
+}
+
+// --- responses --------------------------------------------------------------
+
+// apiError mirrors Forgejo's APIError body.
+type apiError struct {
+	Message string `json:"message"`
+	URL     string `json:"url"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Message: message, URL: "/api/swagger"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// decodeBody decodes the JSON request body into v, answering 422 on
+// malformed input as Forgejo's binding layer does.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func int64Param(w http.ResponseWriter, p params, name string) (int64, bool) {
+	n, err := strconv.ParseInt(p[name], 10, 64)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("invalid %s", name))
+		return 0, false
+	}
+	return n, true
+}
+
+// paginate slices items by the page/limit query and sets X-Total-Count and
+// a Link header with first/prev/next/last relations, as Forgejo does.
+func paginate[T any](s *Server, w http.ResponseWriter, r *http.Request, items []T) []T {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 {
+		limit = defaultPageSize
+	}
+	if s.MaxResponseItems > 0 && limit > s.MaxResponseItems {
+		limit = s.MaxResponseItems
+	}
+
+	total := len(items)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	lastPage := (total + limit - 1) / limit
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	link := func(n int, rel string) string {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(n))
+		q.Set("limit", strconv.Itoa(limit))
+		return fmt.Sprintf(`<%s%s?%s>; rel="%s"`, s.URL, r.URL.Path, q.Encode(), rel)
+	}
+	var links []string
+	if page < lastPage {
+		links = append(links, link(page+1, "next"), link(lastPage, "last"))
+	}
+	if page > 1 {
+		links = append(links, link(1, "first"), link(page-1, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ","))
+	}
+
+	start := (page - 1) * limit
+	if start >= total {
+		return []T{}
+	}
+	return items[start:min(start+limit, total)]
+}
+
+// --- instance-level routes --------------------------------------------------
+
+func (s *Server) registerRoutes() {
+	s.handle(http.MethodGet, "version", func(w http.ResponseWriter, r *http.Request, p params) {
+		writeJSON(w, http.StatusOK, map[string]string{"version": s.Version})
+	})
+	s.handle(http.MethodGet, "settings/api", func(w http.ResponseWriter, r *http.Request, p params) {
+		writeJSON(w, http.StatusOK, forgejo_sdk.GlobalAPISettings{
+			MaxResponseItems:       s.MaxResponseItems,
+			DefaultPagingNum:       defaultPageSize,
+			DefaultGitTreesPerPage: 1000,
+			DefaultMaxBlobSize:     10485760,
+		})
+	})
+	s.handle(http.MethodGet, "settings/attachment", func(w http.ResponseWriter, r *http.Request, p params) {
+		writeJSON(w, http.StatusOK, forgejo_sdk.GlobalAttachmentSettings{
+			Enabled:      true,
+			AllowedTypes: ".zip,.txt,image/*",
+			MaxSize:      2 << 20,
+			MaxFiles:     5,
+		})
+	})
+	s.handle(http.MethodGet, "user", func(w http.ResponseWriter, r *http.Request, p params) {
+		writeJSON(w, http.StatusOK, s.user)
+	})
+	s.registerRepoRoutes()
+	s.registerPinRoutes() // before issues: "pinned" would match {index}
+	s.registerAttachmentRoutes()
+	s.registerIssueRoutes()
+	s.registerReactionRoutes()
+	s.registerSubscriptionRoutes()
+	s.registerTimelineRoutes()
+	s.registerDependencyRoutes()
+	s.registerMilestoneRoutes()
+	s.registerPullRoutes()
+	s.registerReleaseRoutes()
+	s.registerWikiRoutes()
+	s.registerHookRoutes()
+	s.registerActionRoutes()
+}
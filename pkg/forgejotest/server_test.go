@@ -0,0 +1,356 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/actions"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/operation/issue"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/flag"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejotest"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func callTool(t *testing.T, fn func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error), args map[string]any) string {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := fn(context.Background(), req)
+	if err != nil {
+		t.Fatalf("tool error: %v", err)
+	}
+	var text strings.Builder
+	for _, content := range result.Content {
+		if tc, ok := content.(mcp.TextContent); ok {
+			text.WriteString(tc.Text)
+		}
+	}
+	return text.String()
+}
+
+func TestServer_IssueToolsShareState(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+
+	created := callTool(t, issue.CreateIssueFn, map[string]any{
+		"owner": "alice", "repo": "demo", "title": "Crash on start", "body": "stack trace",
+	})
+	if !strings.Contains(created, `"number":1`) {
+		t.Fatalf("create_issue result missing number 1: %s", created)
+	}
+	callTool(t, issue.CreateIssueCommentFn, map[string]any{
+		"owner": "alice", "repo": "demo", "index": float64(1), "body": "reproduced",
+	})
+
+	got := callTool(t, issue.GetIssueByIndexFn, map[string]any{"owner": "alice", "repo": "demo", "index": float64(1)})
+	if !strings.Contains(got, "Crash on start") || !strings.Contains(got, `"comments":1`) {
+		t.Fatalf("get_issue_by_index did not see the earlier writes: %s", got)
+	}
+}
+
+func TestServer_UseRestoresGlobals(t *testing.T) {
+	flag.URL, flag.Token, flag.UserAgent = "https://forgejo.example", "outer", ""
+	t.Cleanup(func() { flag.URL, flag.Token, flag.UserAgent = "", "", "" })
+
+	srv := forgejotest.NewServer(t)
+	t.Run("use", func(t *testing.T) {
+		srv.Use(t)
+		if flag.URL != srv.URL || flag.Token != srv.Token || flag.UserAgent == "" {
+			t.Fatalf("globals not pointed at the server: %q %q %q", flag.URL, flag.Token, flag.UserAgent)
+		}
+	})
+	if flag.URL != "https://forgejo.example" || flag.Token != "outer" || flag.UserAgent != "" {
+		t.Fatalf("globals not restored: %q %q %q", flag.URL, flag.Token, flag.UserAgent)
+	}
+}
+
+func TestServer_IssueLifecycleThroughSDK(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	bug := srv.CreateLabel("alice", "demo", "bug", "#ee0701")
+
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, _, err := client.CreateIssue("alice", "demo", forgejo_sdk.CreateIssueOption{Title: "first", Labels: []int64{bug.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Labels) != 1 || created.Labels[0].Name != "bug" {
+		t.Fatalf("labels = %+v, want [bug]", created.Labels)
+	}
+	srv.CreateIssue("alice", "demo", "second", "")
+
+	closed := forgejo_sdk.StateClosed
+	if _, _, err := client.EditIssue("alice", "demo", created.Index, forgejo_sdk.EditIssueOption{State: &closed}); err != nil {
+		t.Fatal(err)
+	}
+
+	open, _, err := client.ListRepoIssues("alice", "demo", forgejo_sdk.ListIssueOption{State: forgejo_sdk.StateOpen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].Title != "second" {
+		t.Fatalf("open issues = %+v, want only 'second'", open)
+	}
+	all, _, err := client.ListRepoIssues("alice", "demo", forgejo_sdk.ListIssueOption{State: forgejo_sdk.StateAll, Labels: []string{"bug"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Closed == nil {
+		t.Fatalf("bug-labelled issues = %+v, want the closed one", all)
+	}
+}
+
+func TestServer_PaginationHeaders(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	for i := 0; i < 5; i++ {
+		srv.CreateIssue("alice", "demo", "issue", "")
+	}
+
+	var page []forgejo_sdk.Issue
+	header, err := forgejo.DoJSONListWithHeader(context.Background(), http.MethodGet,
+		forgejo.APIPath("repos", "alice", "demo", "issues")+"?page=2&limit=2", &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("X-Total-Count"); got != "5" {
+		t.Errorf("X-Total-Count = %q, want 5", got)
+	}
+	link := header.Get("Link")
+	for _, rel := range []string{`rel="next"`, `rel="last"`, `rel="first"`, `rel="prev"`} {
+		if !strings.Contains(link, rel) {
+			t.Errorf("Link header missing %s: %s", rel, link)
+		}
+	}
+	if len(page) != 2 || page[0].Index != 3 {
+		t.Fatalf("page 2 = %+v, want issues 3 and 2 (newest first)", page)
+	}
+
+	srv.MaxResponseItems = 3
+	header, err = forgejo.DoJSONListWithHeader(context.Background(), http.MethodGet,
+		forgejo.APIPath("repos", "alice", "demo", "issues")+"?limit=50", &page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 3 || !strings.Contains(header.Get("Link"), `rel="next"`) {
+		t.Fatalf("limit was not capped at MaxResponseItems: %d items, Link %q", len(page), header.Get("Link"))
+	}
+}
+
+func TestServer_ErrorResponses(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{"unknown repo", http.MethodGet, forgejo.APIPath("repos", "alice", "missing"), nil, http.StatusNotFound},
+		{"unknown issue", http.MethodGet, forgejo.APIPath("repos", "alice", "demo", "issues", 9), nil, http.StatusNotFound},
+		{"empty title", http.MethodPost, forgejo.APIPath("repos", "alice", "demo", "issues"), map[string]any{"title": " "}, http.StatusUnprocessableEntity},
+		{"unknown label", http.MethodPost, forgejo.APIPath("repos", "alice", "demo", "issues"), map[string]any{"title": "x", "labels": []int{42}}, http.StatusUnprocessableEntity},
+		{"duplicate label", http.MethodPost, forgejo.APIPath("repos", "alice", "demo", "labels"), map[string]any{"name": "bug", "color": "ff0000"}, http.StatusConflict},
+		{"wrong method", http.MethodPut, forgejo.APIPath("repos", "alice", "demo"), map[string]any{}, http.StatusMethodNotAllowed},
+	}
+	srv.CreateLabel("alice", "demo", "bug", "ff0000")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := forgejo.DoJSON(ctx, tt.method, tt.path, tt.body, nil)
+			var httpErr *forgejo.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("err = %v, want *forgejo.HTTPError", err)
+			}
+			if httpErr.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", httpErr.StatusCode, tt.status)
+			}
+			if !strings.Contains(httpErr.Body, `"message"`) {
+				t.Errorf("body is not a Forgejo API error: %s", httpErr.Body)
+			}
+		})
+	}
+
+	srv.Token = "rotated"
+	err := forgejo.DoJSON(ctx, http.MethodGet, "/user", nil, nil)
+	if !errors.Is(err, forgejo.ErrUnauthorized) {
+		t.Fatalf("stale token: err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestServer_PullsAndReviews(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	srv.CreateIssue("alice", "demo", "an issue first", "")
+
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, _, err := client.CreatePullRequest("alice", "demo", forgejo_sdk.CreatePullRequestOption{Head: "feature", Base: "main", Title: "Add feature"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Index != 2 {
+		t.Fatalf("pull index = %d, want 2 (shared with issues)", pr.Index)
+	}
+	if _, _, err := client.CreatePullRequest("alice", "demo", forgejo_sdk.CreatePullRequestOption{Head: "feature", Base: "main", Title: "again"}); err == nil {
+		t.Fatal("duplicate pull request was accepted")
+	}
+	if _, _, err := client.CreatePullReview("alice", "demo", pr.Index, forgejo_sdk.CreatePullReviewOptions{State: forgejo_sdk.ReviewStateApproved, Body: "lgtm"}); err != nil {
+		t.Fatal(err)
+	}
+	reviews, _, err := client.ListPullReviews("alice", "demo", pr.Index, forgejo_sdk.ListPullReviewsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 1 || reviews[0].State != forgejo_sdk.ReviewStateApproved {
+		t.Fatalf("reviews = %+v", reviews)
+	}
+	issues, _, err := client.ListRepoIssues("alice", "demo", forgejo_sdk.ListIssueOption{Type: forgejo_sdk.IssueTypeIssue})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("type=issues returned %d items, want 1", len(issues))
+	}
+}
+
+func TestServer_ReleasesAndHooks(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.CreateRelease("alice", "demo", forgejo_sdk.CreateReleaseOption{TagName: "v1.0.0", Title: "One"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.CreateRelease("alice", "demo", forgejo_sdk.CreateReleaseOption{TagName: "v2.0.0-rc1", Title: "Two RC", IsPrerelease: true}); err != nil {
+		t.Fatal(err)
+	}
+	latest, _, err := client.GetLatestRelease("alice", "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.TagName != "v1.0.0" {
+		t.Fatalf("latest = %s, want v1.0.0 (prereleases excluded)", latest.TagName)
+	}
+	if _, err := client.DeleteReleaseByTag("alice", "demo", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.GetReleaseByTag("alice", "demo", "v1.0.0"); err == nil {
+		t.Fatal("deleted release still readable")
+	}
+
+	hook, _, err := client.CreateRepoHook("alice", "demo", forgejo_sdk.CreateHookOption{
+		Type:   forgejo_sdk.HookTypeForgejo,
+		Config: map[string]string{"url": "https://example.com/hook", "content_type": "json"},
+		Active: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.EditRepoHook("alice", "demo", hook.ID, forgejo_sdk.EditHookOption{Events: []string{"issues"}}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := client.GetRepoHook("alice", "demo", hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 1 || got.Events[0] != "issues" {
+		t.Fatalf("events = %v, want [issues]", got.Events)
+	}
+}
+
+func TestServer_WikiRoundTrip(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	ctx := context.Background()
+
+	content := base64.StdEncoding.EncodeToString([]byte("# Hello"))
+	if _, err := forgejo.CreateWikiPage(ctx, "alice", "demo", "Getting Started", content, ""); err != nil {
+		t.Fatal(err)
+	}
+	edited := base64.StdEncoding.EncodeToString([]byte("# Hello again"))
+	if _, err := forgejo.EditWikiPage(ctx, "alice", "demo", "Getting-Started", "Getting Started", edited, "second"); err != nil {
+		t.Fatal(err)
+	}
+	page, err := forgejo.GetWikiPage(ctx, "alice", "demo", "Getting Started")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.ContentBase64 != edited || page.CommitCount != 2 || page.LastCommit.Message != "second" {
+		t.Fatalf("page = %+v", page)
+	}
+	revisions, err := forgejo.GetWikiPageRevisions(ctx, "alice", "demo", "Getting-Started", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revisions.Count != 2 || len(revisions.Commits) != 1 {
+		t.Fatalf("revisions = %+v, want count 2 with one commit on the page", revisions)
+	}
+}
+
+func TestServer_ActionRunsAndJobLogs(t *testing.T) {
+	srv := forgejotest.NewServer(t)
+	srv.Version = "16.0.0+gitea-1.22.0"
+	srv.Use(t)
+	srv.CreateRepo("alice", "demo")
+	run := srv.AddActionRun("alice", "demo", forgejo_sdk.ActionRun{Title: "CI", Event: "push", Status: "failure"},
+		forgejotest.ActionJob{Name: "build", Log: "line one\nline two\n"},
+	)
+	srv.AddActionRun("alice", "demo", forgejo_sdk.ActionRun{Title: "CI", Event: "schedule"})
+
+	client, err := forgejo.Client(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, _, err := client.ListRepoActionRuns("alice", "demo", forgejo_sdk.ListActionRunsOption{Event: "push"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs.TotalCount != 1 || runs.WorkflowRuns[0].ID != run.ID {
+		t.Fatalf("runs = %+v, want only the push run", runs)
+	}
+
+	jobs := callTool(t, actions.ListActionRunJobsFn, map[string]any{"owner": "alice", "repo": "demo", "run_id": float64(run.ID)})
+	if !strings.Contains(jobs, `"name":"build"`) {
+		t.Fatalf("list_action_run_jobs = %s", jobs)
+	}
+	var jobID float64
+	{
+		var listed []forgejotest.ActionJob
+		if err := forgejo.DoJSON(context.Background(), http.MethodGet,
+			forgejo.APIPath("repos", "alice", "demo", "actions", "runs", run.ID, "jobs"), nil, &listed); err != nil {
+			t.Fatal(err)
+		}
+		jobID = float64(listed[0].ID)
+	}
+	logs := callTool(t, actions.GetActionJobLogsFn, map[string]any{"owner": "alice", "repo": "demo", "job_id": jobID, "max_bytes": float64(9)})
+	if !strings.Contains(logs, `line two`) || !strings.Contains(logs, `"truncated_before":true`) {
+		t.Fatalf("get_action_job_logs = %s", logs)
+	}
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	forgejo_sdk "codeberg.org/mvdkleijn/forgejo-sdk/forgejo/v3"
+)
+
+// watcherIndex returns the position of login among an issue's subscribers,
+// or -1.
+func watcherIndex(watchers []*forgejo_sdk.User, login string) int {
+	return slices.IndexFunc(watchers, func(u *forgejo_sdk.User) bool { return strings.EqualFold(u.UserName, login) })
+}
+
+func (s *Server) registerSubscriptionRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/subscriptions", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, issue, ok := s.lookupIssue(w, p); ok {
+			writeJSON(w, http.StatusOK, paginate(s, w, r, append([]*forgejo_sdk.User{}, rs.watchers[issue.Index]...)))
+		}
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/subscriptions/check", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, forgejo_sdk.WatchInfo{
+			Subscribed:    watcherIndex(rs.watchers[issue.Index], s.user.UserName) >= 0,
+			URL:           fmt.Sprintf("%s/api/v1/repos/%s/issues/%d", s.URL, rs.repo.FullName, issue.Index),
+			RepositoryURL: rs.repo.HTMLURL,
+		})
+	})
+	// Like Forgejo, both writes answer 201 when they change something and
+	// 200 when the user was already (un)subscribed.
+	s.handle(http.MethodPut, "repos/{owner}/{repo}/issues/{index}/subscriptions/{user}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		if watcherIndex(rs.watchers[issue.Index], p["user"]) >= 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		rs.watchers[issue.Index] = append(rs.watchers[issue.Index], s.resolveAssignees([]string{p["user"]})...)
+		w.WriteHeader(http.StatusCreated)
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/issues/{index}/subscriptions/{user}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		at := watcherIndex(rs.watchers[issue.Index], p["user"])
+		if at < 0 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		rs.watchers[issue.Index] = slices.Delete(rs.watchers[issue.Index], at, at+1)
+		w.WriteHeader(http.StatusCreated)
+	})
+}
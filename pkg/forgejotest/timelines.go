@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// timelineEvent is a seeded, non-comment timeline entry; comments are
+// merged in from the issue's comment list when the timeline is read.
+type timelineEvent struct {
+	created time.Time
+	fields  map[string]any
+}
+
+// AddTimelineEvent seeds a timeline entry on an issue, given as the JSON
+// object GET /repos/{owner}/{repo}/issues/{index}/timeline returns for it
+// (e.g. {"type":"label","body":"1","label":{"name":"bug"}}). id, user and
+// created_at are filled in when missing.
+func (s *Server) AddTimelineEvent(owner, repo string, index int64, event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rs, ok := s.repos[repoKey(owner, repo)]
+	if !ok {
+		panic(fmt.Sprintf("forgejotest: AddTimelineEvent on unknown repository %s/%s", owner, repo))
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(event), &fields); err != nil {
+		panic(fmt.Sprintf("forgejotest: AddTimelineEvent: invalid JSON %q: %v", event, err))
+	}
+	if _, ok := fields["id"]; !ok {
+		fields["id"] = s.newID()
+	}
+	if _, ok := fields["user"]; !ok {
+		fields["user"] = s.user
+	}
+	created := s.now()
+	if raw, ok := fields["created_at"].(string); ok {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			panic(fmt.Sprintf("forgejotest: AddTimelineEvent: invalid created_at %q", raw))
+		}
+		created = parsed
+	}
+	fields["created_at"] = created
+	rs.events[index] = append(rs.events[index], timelineEvent{created: created, fields: fields})
+}
+
+func (s *Server) registerTimelineRoutes() {
+	// Like Forgejo, since and before filter on the entry's time and the
+	// result is oldest first.
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/issues/{index}/timeline", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, issue, ok := s.lookupIssue(w, p)
+		if !ok {
+			return
+		}
+		entries := append([]timelineEvent{}, rs.events[issue.Index]...)
+		for _, c := range rs.comments {
+			if c.issueIndex != issue.Index {
+				continue
+			}
+			entries = append(entries, timelineEvent{created: c.comment.Created, fields: map[string]any{
+				"id":         c.comment.ID,
+				"type":       "comment",
+				"html_url":   c.comment.HTMLURL,
+				"issue_url":  c.comment.IssueURL,
+				"user":       c.comment.Poster,
+				"body":       c.comment.Body,
+				"created_at": c.comment.Created,
+				"updated_at": c.comment.Updated,
+			}})
+		}
+		slices.SortStableFunc(entries, func(a, b timelineEvent) int { return a.created.Compare(b.created) })
+
+		query := r.URL.Query()
+		var since, before time.Time
+		for key, dst := range map[string]*time.Time{"since": &since, "before": &before} {
+			if raw := query.Get(key); raw != "" {
+				parsed, err := time.Parse(time.RFC3339, raw)
+				if err != nil {
+					writeError(w, http.StatusUnprocessableEntity, key+": "+err.Error())
+					return
+				}
+				*dst = parsed
+			}
+		}
+		found := []map[string]any{}
+		for _, e := range entries {
+			if (!since.IsZero() && e.created.Before(since)) || (!before.IsZero() && !e.created.Before(before)) {
+				continue
+			}
+			found = append(found, e.fields)
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, found))
+	})
+}
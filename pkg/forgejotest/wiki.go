@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forgejotest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"git.b4mad.industries/agentic-forges/forgejo-mcp/v2/pkg/forgejo"
+)
+
+// wikiState is one page; commits are newest first, like git log.
+type wikiState struct {
+	title   string
+	content string
+	commits []forgejo.WikiCommit
+}
+
+// wikiSubURL is the page name as Forgejo puts it in sub_url and routes:
+// spaces become dashes.
+func wikiSubURL(title string) string {
+	return strings.ReplaceAll(title, " ", "-")
+}
+
+func (rs *repoState) wikiPage(name string) *wikiState {
+	for _, page := range rs.wiki {
+		if page.title == name || wikiSubURL(page.title) == wikiSubURL(name) {
+			return page
+		}
+	}
+	return nil
+}
+
+func (s *Server) wikiCommit(message string) forgejo.WikiCommit {
+	return forgejo.WikiCommit{
+		SHA:     fakeSHA(fmt.Sprintf("wiki-%d", s.newID())),
+		Author:  forgejo.WikiPerson{Name: s.user.UserName, Email: s.user.Email, Date: s.now().Format("2006-01-02T15:04:05Z07:00")},
+		Message: message,
+	}
+}
+
+func (s *Server) renderWikiMeta(rs *repoState, page *wikiState) forgejo.WikiPageMeta {
+	sub := wikiSubURL(page.title)
+	return forgejo.WikiPageMeta{
+		Title:      page.title,
+		HTMLURL:    rs.repo.HTMLURL + "/wiki/" + url.PathEscape(sub),
+		SubURL:     sub,
+		LastCommit: page.commits[0],
+	}
+}
+
+func (s *Server) renderWikiPage(rs *repoState, page *wikiState) forgejo.WikiPage {
+	return forgejo.WikiPage{
+		WikiPageMeta:  s.renderWikiMeta(rs, page),
+		ContentBase64: base64.StdEncoding.EncodeToString([]byte(page.content)),
+		CommitCount:   len(page.commits),
+	}
+}
+
+// wikiWrite is the request body of the create and edit endpoints.
+type wikiWrite struct {
+	Title         string `json:"title"`
+	ContentBase64 string `json:"content_base64"`
+	Message       string `json:"message"`
+}
+
+func decodeWikiContent(w http.ResponseWriter, opt wikiWrite) (string, bool) {
+	content, err := base64.StdEncoding.DecodeString(opt.ContentBase64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "content_base64 is not valid base64")
+		return "", false
+	}
+	return string(content), true
+}
+
+func (s *Server) lookupWikiPage(w http.ResponseWriter, p params) (*repoState, *wikiState, bool) {
+	rs, ok := s.lookupRepo(w, p)
+	if !ok {
+		return nil, nil, false
+	}
+	page := rs.wikiPage(p["name"])
+	if page == nil {
+		writeError(w, http.StatusNotFound, "wiki page does not exist")
+		return nil, nil, false
+	}
+	return rs, page, true
+}
+
+func (s *Server) registerWikiRoutes() {
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/wiki/pages", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		pages := make([]forgejo.WikiPageMeta, 0, len(rs.wiki))
+		for _, page := range rs.wiki {
+			pages = append(pages, s.renderWikiMeta(rs, page))
+		}
+		writeJSON(w, http.StatusOK, paginate(s, w, r, pages))
+	})
+	s.handle(http.MethodPost, "repos/{owner}/{repo}/wiki/new", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, ok := s.lookupRepo(w, p)
+		if !ok {
+			return
+		}
+		var opt wikiWrite
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		if strings.TrimSpace(opt.Title) == "" {
+			writeError(w, http.StatusUnprocessableEntity, "[Title]: Required")
+			return
+		}
+		if rs.wikiPage(opt.Title) != nil {
+			writeError(w, http.StatusConflict, "wiki page already exists")
+			return
+		}
+		content, ok := decodeWikiContent(w, opt)
+		if !ok {
+			return
+		}
+		message := opt.Message
+		if message == "" {
+			message = "Add " + opt.Title
+		}
+		page := &wikiState{title: opt.Title, content: content, commits: []forgejo.WikiCommit{s.wikiCommit(message)}}
+		rs.wiki = append(rs.wiki, page)
+		writeJSON(w, http.StatusCreated, s.renderWikiPage(rs, page))
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/wiki/page/{name}", func(w http.ResponseWriter, r *http.Request, p params) {
+		if rs, page, ok := s.lookupWikiPage(w, p); ok {
+			writeJSON(w, http.StatusOK, s.renderWikiPage(rs, page))
+		}
+	})
+	s.handle(http.MethodPatch, "repos/{owner}/{repo}/wiki/page/{name}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, page, ok := s.lookupWikiPage(w, p)
+		if !ok {
+			return
+		}
+		var opt wikiWrite
+		if !decodeBody(w, r, &opt) {
+			return
+		}
+		content, ok := decodeWikiContent(w, opt)
+		if !ok {
+			return
+		}
+		if opt.Title != "" {
+			page.title = opt.Title
+		}
+		page.content = content
+		message := opt.Message
+		if message == "" {
+			message = "Update " + page.title
+		}
+		page.commits = append([]forgejo.WikiCommit{s.wikiCommit(message)}, page.commits...)
+		writeJSON(w, http.StatusOK, s.renderWikiPage(rs, page))
+	})
+	s.handle(http.MethodDelete, "repos/{owner}/{repo}/wiki/page/{name}", func(w http.ResponseWriter, r *http.Request, p params) {
+		rs, page, ok := s.lookupWikiPage(w, p)
+		if !ok {
+			return
+		}
+		rs.wiki = slices.DeleteFunc(rs.wiki, func(other *wikiState) bool { return other == page })
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.handle(http.MethodGet, "repos/{owner}/{repo}/wiki/revisions/{name}", func(w http.ResponseWriter, r *http.Request, p params) {
+		_, page, ok := s.lookupWikiPage(w, p)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, forgejo.WikiRevisions{
+			Commits: paginate(s, w, r, page.commits),
+			Count:   len(page.commits),
+		})
+	})
+}
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package gitremote finds the Forgejo repository a local git checkout points
+// at by reading the remotes in its .git/config. It never runs git.
+package gitremote
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned when no git checkout or no matching remote exists.
+var ErrNotFound = errors.New("no matching git remote")
+
+// Repo is a repository resolved from a remote URL.
+type Repo struct {
+	Owner  string `json:"owner"`
+	Name   string `json:"repo"`
+	Remote string `json:"remote"`
+}
+
+// preferredRemotes are tried first, in order; any other remote follows in
+// name order.
+var preferredRemotes = []string{"origin", "upstream"}
+
+// Detect walks up from dir to the nearest git checkout and returns the first
+// remote whose URL points at forgeURL's host (and path prefix, for instances
+// served below a sub-path).
+func Detect(dir, forgeURL string) (Repo, error) {
+	forge, err := url.Parse(forgeURL)
+	if err != nil || forge.Host == "" {
+		return Repo{}, fmt.Errorf("invalid forge URL %q", forgeURL)
+	}
+	configPath, err := findConfig(dir)
+	if err != nil {
+		return Repo{}, err
+	}
+	f, err := os.Open(configPath)
+	if err != nil {
+		return Repo{}, fmt.Errorf("read git config: %w", err)
+	}
+	defer f.Close()
+	remotes, err := parseRemotes(f)
+	if err != nil {
+		return Repo{}, fmt.Errorf("parse %s: %w", configPath, err)
+	}
+
+	for _, name := range orderRemotes(remotes) {
+		for _, raw := range remotes[name] {
+			if owner, repo, ok := matchRemote(raw, forge); ok {
+				return Repo{Owner: owner, Name: repo, Remote: name}, nil
+			}
+		}
+	}
+	return Repo{}, fmt.Errorf("%w for %s in %s", ErrNotFound, forge.Host, configPath)
+}
+
+// findConfig locates the config file of the checkout containing dir. A .git
+// file (worktrees, submodules) is followed to the directory it names; for a
+// linked worktree the shared config lives in the common dir.
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		info, err := os.Stat(gitPath)
+		if err == nil {
+			gitDir := gitPath
+			if !info.IsDir() {
+				if gitDir, err = readGitFile(gitPath); err != nil {
+					return "", err
+				}
+			}
+			if common, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+				commonDir := strings.TrimSpace(string(common))
+				if !filepath.IsAbs(commonDir) {
+					commonDir = filepath.Join(gitDir, commonDir)
+				}
+				gitDir = commonDir
+			}
+			return filepath.Join(gitDir, "config"), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%w: not inside a git checkout", ErrNotFound)
+		}
+		dir = parent
+	}
+}
+
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s is not a gitdir pointer", path)
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return target, nil
+}
+
+// parseRemotes extracts remote.<name>.url values from a git config file. It
+// handles the subset of the format git itself writes for remotes.
+func parseRemotes(f *os.File) (map[string][]string, error) {
+	remotes := map[string][]string{}
+	current := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			current = ""
+			section := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			kind, name, ok := strings.Cut(section, " ")
+			if ok && strings.EqualFold(kind, "remote") {
+				current = strings.Trim(strings.TrimSpace(name), `"`)
+			}
+			continue
+		}
+		if current == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "url") {
+			continue
+		}
+		remotes[current] = append(remotes[current], strings.Trim(strings.TrimSpace(value), `"`))
+	}
+	return remotes, scanner.Err()
+}
+
+func orderRemotes(remotes map[string][]string) []string {
+	var ordered []string
+	for _, name := range preferredRemotes {
+		if _, ok := remotes[name]; ok {
+			ordered = append(ordered, name)
+		}
+	}
+	var rest []string
+	for name := range remotes {
+		if name != "origin" && name != "upstream" {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(ordered, rest...)
+}
+
+// matchRemote parses the URL forms git accepts (https://, ssh://, and the
+// scp-like user@host:path) and reports owner and repo when the remote is on
+// the forge. Ports are ignored: SSH and HTTPS share a host but not a port.
+func matchRemote(raw string, forge *url.URL) (owner, repo string, ok bool) {
+	var host, path string
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, found := strings.Cut(raw, ":"); found && !strings.Contains(at, "/") {
+		host = at
+		if i := strings.LastIndex(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+		path = rest
+	} else {
+		return "", "", false
+	}
+	if !strings.EqualFold(host, forge.Hostname()) {
+		return "", "", false
+	}
+
+	path = strings.Trim(path, "/")
+	// An instance served below a sub-path keeps that prefix in HTTPS remotes
+	// but not in SSH ones, so strip it only when present.
+	if prefix := strings.Trim(forge.Path, "/"); prefix != "" {
+		path = strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+	}
+	parts := strings.Split(strings.TrimSuffix(path, ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
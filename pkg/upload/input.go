@@ -35,6 +35,28 @@ func filePathUploadsEnabled() bool {
 	return false
 }
 
+// FilePathSettings reports whether file_path uploads are enabled and the
+// resolved UploadRootEnv directory, empty when unset. err explains a root
+// that cannot confine anything because it does not resolve to a directory.
+func FilePathSettings() (enabled bool, root string, err error) {
+	enabled = filePathUploadsEnabled()
+	root = strings.TrimSpace(os.Getenv(UploadRootEnv))
+	if root == "" {
+		return enabled, "", nil
+	}
+	absoluteRoot, err := filepath.Abs(root)
+	if err == nil {
+		root, err = filepath.EvalSymlinks(absoluteRoot)
+	}
+	if err != nil {
+		return enabled, root, fmt.Errorf("resolve %s: %w", UploadRootEnv, err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return enabled, root, fmt.Errorf("%s %s is not a directory", UploadRootEnv, root)
+	}
+	return enabled, root, nil
+}
+
 // confineToRoot rejects a resolved path that escapes UploadRootEnv. Both sides
 // are symlink-resolved first, so a symlink inside the root cannot point out of it.
 func confineToRoot(resolvedPath string) error {
@@ -195,3 +195,31 @@ func TestOpenRejectsInvalidSources(t *testing.T) {
 		})
 	}
 }
+
+func TestFilePathSettings(t *testing.T) {
+	t.Setenv(AllowFilePathEnv, "")
+	t.Setenv(UploadRootEnv, "")
+	if enabled, root, err := FilePathSettings(); enabled || root != "" || err != nil {
+		t.Fatalf("defaults = %v, %q, %v", enabled, root, err)
+	}
+
+	dir := t.TempDir()
+	allowFilePathUploads(t)
+	t.Setenv(UploadRootEnv, dir)
+	enabled, root, err := FilePathSettings()
+	want, _ := filepath.EvalSymlinks(dir)
+	if !enabled || root != want || err != nil {
+		t.Fatalf("with root = %v, %q, %v; want %q", enabled, root, err, want)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{file, filepath.Join(dir, "missing")} {
+		t.Setenv(UploadRootEnv, bad)
+		if _, _, err := FilePathSettings(); err == nil {
+			t.Errorf("root %s accepted", bad)
+		}
+	}
+}